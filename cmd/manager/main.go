@@ -19,9 +19,11 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 
 	"github.com/Ridecell/ridecell-operator/pkg/apis"
 	"github.com/Ridecell/ridecell-operator/pkg/controller"
+	"github.com/Ridecell/ridecell-operator/pkg/webhook"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -29,6 +31,10 @@ import (
 )
 
 func main() {
+	metricsAddr := flag.String("metrics-addr", ":8080", "The address the Prometheus metrics endpoint binds to, or 0 to disable it.")
+	enableWebhooks := flag.Bool("enable-webhooks", false, "Serve the admission webhooks, requires POD_NAMESPACE to be set.")
+	webhookPort := flag.Int("webhook-port", 9876, "The port the admission webhook server listens on.")
+	webhookCertDir := flag.String("webhook-cert-dir", "/tmp/cert", "The directory to store the webhook serving certificate in.")
 	flag.Parse()
 
 	// Get a config to talk to the apiserver
@@ -38,7 +44,7 @@ func main() {
 	}
 
 	// Create a new Cmd to provide shared dependencies and start components
-	mgr, err := manager.New(cfg, manager.Options{LeaderElection: true})
+	mgr, err := manager.New(cfg, manager.Options{LeaderElection: true, MetricsBindAddress: *metricsAddr})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -55,6 +61,18 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Setup the admission webhooks
+	if *enableWebhooks {
+		namespace := os.Getenv("POD_NAMESPACE")
+		if namespace == "" {
+			log.Fatal("POD_NAMESPACE must be set to run the admission webhooks")
+		}
+		err := webhook.AddToManager(mgr, webhook.Options{Port: int32(*webhookPort), CertDir: *webhookCertDir, Namespace: namespace})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Printf("Starting the Cmd.")
 
 	// Start the Cmd
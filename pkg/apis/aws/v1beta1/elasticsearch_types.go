@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // ElasticSearchSpec defines the desired state of ElasticSearch
@@ -45,9 +47,10 @@ type ElasticSearchSpec struct {
 
 // ElasticSearchStatus defines the observed state of ElasticSearch
 type ElasticSearchStatus struct {
-	Status         string `json:"status"`
-	Message        string `json:"message"`
-	DomainEndpoint string `json:"domainEndpoint"`
+	Status         string                 `json:"status"`
+	Message        string                 `json:"message"`
+	DomainEndpoint string                 `json:"domainEndpoint"`
+	Conditions     []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
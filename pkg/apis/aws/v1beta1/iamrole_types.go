@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // IAMRoleSpec defines the desired state of IAMRole
@@ -26,13 +28,49 @@ type IAMRoleSpec struct {
 	InlinePolicies           map[string]string `json:"inlinePolicies,omitempty"`
 	AssumeRolePolicyDocument string            `json:"assumeRolePolicyDocument,omitempty"`
 	PermissionsBoundaryArn   string            `json:"permissionsBoundaryArn,omitempty"`
+	// ARNs of AWS-managed or customer-managed policies to attach to the role. Supports {{ .Region }} templating.
+	ManagedPolicyArns []string `json:"managedPolicyArns,omitempty"`
+	// Kubernetes ServiceAccounts allowed to assume the role through IAM Roles for Service Accounts. A web
+	// identity statement is added to the trust policy and the ServiceAccounts are annotated with the role ARN.
+	// Requires OIDCProviderArn.
+	// +optional
+	ServiceAccounts []IAMRoleServiceAccountRef `json:"serviceAccounts,omitempty"`
+	// ARN of the cluster's IAM OIDC identity provider, like
+	// arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE.
+	// +optional
+	OIDCProviderArn string `json:"oidcProviderArn,omitempty"`
+}
+
+// IAMRoleServiceAccountRef refers to a Kubernetes ServiceAccount.
+type IAMRoleServiceAccountRef struct {
+	Name string `json:"name"`
+	// Defaults to the namespace of the IAMRole.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // IAMRoleStatus defines the observed state of IAMRole
 type IAMRoleStatus struct {
-	Status   string `json:"status"`
-	Message  string `json:"message"`
-	RoleName string `json:"roleName"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	RoleName   string                 `json:"roleName"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
+	// ARN of the role, as used in the eks.amazonaws.com/role-arn annotation.
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+	// Inline policy names and managed policy ARNs attached to the role.
+	// +optional
+	EffectivePolicies []string `json:"effectivePolicies,omitempty"`
+	// When policies changed outside of the operator were last put back to match the spec.
+	// Formatted as time.UnixDate.
+	// +optional
+	LastDriftCorrection string `json:"lastDriftCorrection,omitempty"`
+	// What was changed by the last drift correction.
+	// +optional
+	LastDriftCorrectionMessage string `json:"lastDriftCorrectionMessage,omitempty"`
+	// ServiceAccounts annotated with the role ARN, as namespace/name.
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // IAMUserSpec defines the desired state of IAMUser
@@ -25,12 +27,45 @@ type IAMUserSpec struct {
 	UserName               string            `json:"username,omitempty"`
 	InlinePolicies         map[string]string `json:"inlinePolicies,omitempty"`
 	PermissionsBoundaryArn string            `json:"permissionsBoundaryArn"`
+	// ARNs of AWS-managed or customer-managed policies to attach to the user. Supports {{ .Region }} templating.
+	ManagedPolicyArns []string `json:"managedPolicyArns,omitempty"`
+	// How long an access key is used before a new one is created, like 2160h for 90 days. Leave empty to never
+	// rotate keys.
+	// +optional
+	MaxKeyAge metav1.Duration `json:"maxKeyAge,omitempty"`
+	// How long the old access key keeps working after a rotation so pods can roll onto the new one. Defaults to 1h.
+	// +optional
+	RotationGracePeriod metav1.Duration `json:"rotationGracePeriod,omitempty"`
 }
 
 // IAMUserStatus defines the observed state of IAMUser
 type IAMUserStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
+	// Inline policy names and managed policy ARNs attached to the user.
+	// +optional
+	EffectivePolicies []string `json:"effectivePolicies,omitempty"`
+	// When policies changed outside of the operator were last put back to match the spec.
+	// Formatted as time.UnixDate.
+	// +optional
+	LastDriftCorrection string `json:"lastDriftCorrection,omitempty"`
+	// What was changed by the last drift correction.
+	// +optional
+	LastDriftCorrectionMessage string `json:"lastDriftCorrectionMessage,omitempty"`
+	// ID of the access key in the credentials secret.
+	// +optional
+	AccessKeyID string `json:"accessKeyId,omitempty"`
+	// Age of the current access key, rounded to the hour.
+	// +optional
+	AccessKeyAge string `json:"accessKeyAge,omitempty"`
+	// When the access key was last rotated.
+	// Formatted as time.UnixDate.
+	// +optional
+	LastRotated string `json:"lastRotated,omitempty"`
+	// ID of the replaced access key, kept active until the rotation grace period is over.
+	// +optional
+	PreviousAccessKeyID string `json:"previousAccessKeyId,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // S3BucketSpec defines the desired state of S3Bucket
@@ -25,12 +27,67 @@ type S3BucketSpec struct {
 	BucketName   string `json:"bucketName,omitempty"`
 	BucketPolicy string `json:"bucketPolicy,omitempty"`
 	Region       string `json:"region,omitempty"`
+	// The settings below are left alone on the bucket when unset.
+	// Enable object versioning. Setting it to false on a versioned bucket suspends versioning.
+	Versioning *bool `json:"versioning,omitempty"`
+	// Default server-side encryption for new objects.
+	Encryption *S3BucketEncryptionSpec `json:"encryption,omitempty"`
+	// Lifecycle rules for the bucket, replacing any existing ones.
+	LifecycleRules []S3BucketLifecycleRule `json:"lifecycleRules,omitempty"`
+	// CORS rules for the bucket, replacing any existing ones.
+	CorsRules []S3BucketCorsRule `json:"corsRules,omitempty"`
+	// Public access block settings.
+	PublicAccessBlock *S3BucketPublicAccessBlock `json:"publicAccessBlock,omitempty"`
+}
+
+// S3BucketEncryptionSpec defines the default encryption of a bucket.
+type S3BucketEncryptionSpec struct {
+	// Server-side encryption algorithm, either aws:kms or AES256. Defaults to aws:kms.
+	Algorithm string `json:"algorithm,omitempty"`
+	// KMS key ID or ARN to use with aws:kms. Uses the AWS managed aws/s3 key if unset.
+	KMSKeyID string `json:"kmsKeyId,omitempty"`
+}
+
+// S3BucketLifecycleRule defines an expiration or transition rule for objects in a bucket.
+type S3BucketLifecycleRule struct {
+	ID string `json:"id"`
+	// Only apply the rule to keys with this prefix. Applies to the whole bucket if unset.
+	Prefix string `json:"prefix,omitempty"`
+	// Days after creation to expire current object versions.
+	ExpirationDays int64 `json:"expirationDays,omitempty"`
+	// Days after becoming noncurrent to permanently delete old object versions.
+	NoncurrentVersionExpirationDays int64                         `json:"noncurrentVersionExpirationDays,omitempty"`
+	Transitions                     []S3BucketLifecycleTransition `json:"transitions,omitempty"`
+}
+
+// S3BucketLifecycleTransition moves objects to another storage class after some time.
+type S3BucketLifecycleTransition struct {
+	Days         int64  `json:"days"`
+	StorageClass string `json:"storageClass"`
+}
+
+// S3BucketCorsRule defines a single CORS rule for a bucket.
+type S3BucketCorsRule struct {
+	AllowedOrigins []string `json:"allowedOrigins"`
+	AllowedMethods []string `json:"allowedMethods"`
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	ExposeHeaders  []string `json:"exposeHeaders,omitempty"`
+	MaxAgeSeconds  int64    `json:"maxAgeSeconds,omitempty"`
+}
+
+// S3BucketPublicAccessBlock defines the public access block settings for a bucket.
+type S3BucketPublicAccessBlock struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls,omitempty"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls,omitempty"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy,omitempty"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets,omitempty"`
 }
 
 // S3BucketStatus defines the observed state of S3Bucket
 type S3BucketStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -33,6 +34,14 @@ func (sb *S3Bucket) SetErrorStatus(errorMsg string) {
 	sb.Status.Message = errorMsg
 }
 
+func (sb *S3Bucket) GetConditions() []conditions.Condition {
+	return sb.Status.Conditions
+}
+
+func (sb *S3Bucket) SetConditions(conds []conditions.Condition) {
+	sb.Status.Conditions = conds
+}
+
 func (iu *IAMUser) GetStatus() components.Status {
 	return iu.Status
 }
@@ -46,6 +55,14 @@ func (iu *IAMUser) SetErrorStatus(errorMsg string) {
 	iu.Status.Message = errorMsg
 }
 
+func (iu *IAMUser) GetConditions() []conditions.Condition {
+	return iu.Status.Conditions
+}
+
+func (iu *IAMUser) SetConditions(conds []conditions.Condition) {
+	iu.Status.Conditions = conds
+}
+
 func (ir *IAMRole) GetStatus() components.Status {
 	return ir.Status
 }
@@ -59,6 +76,14 @@ func (ir *IAMRole) SetErrorStatus(errorMsg string) {
 	ir.Status.Message = errorMsg
 }
 
+func (ir *IAMRole) GetConditions() []conditions.Condition {
+	return ir.Status.Conditions
+}
+
+func (ir *IAMRole) SetConditions(conds []conditions.Condition) {
+	ir.Status.Conditions = conds
+}
+
 func (es *ElasticSearch) GetStatus() components.Status {
 	return es.Status
 }
@@ -71,3 +96,11 @@ func (es *ElasticSearch) SetErrorStatus(errorMsg string) {
 	es.Status.Status = StatusError
 	es.Status.Message = errorMsg
 }
+
+func (es *ElasticSearch) GetConditions() []conditions.Condition {
+	return es.Status.Conditions
+}
+
+func (es *ElasticSearch) SetConditions(conds []conditions.Condition) {
+	es.Status.Conditions = conds
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition types managed by the component reconciler for every object.
+const (
+	// The object has converged, every component-level condition is True or, for objects without
+	// component-level conditions, the .Status.Status is Ready.
+	TypeReady = "Ready"
+	// The reconciler is waiting on one or more components to finish.
+	TypeProgressing = "Progressing"
+	// The last reconcile failed.
+	TypeDegraded = "Degraded"
+)
+
+// Reasons set by the component reconciler.
+const (
+	ReasonReconcileError    = "ReconcileError"
+	ReasonReconcileSuccess  = "ReconcileSuccess"
+	ReasonComponentsReady   = "ComponentsReady"
+	ReasonComponentsPending = "ComponentsPending"
+	ReasonStatusNotReady    = "StatusNotReady"
+)
+
+// Condition is a single observation of the state of an object, modeled after the upstream Kubernetes
+// condition convention so `kubectl wait --for=condition=Ready` works against our objects.
+type Condition struct {
+	// Type of the condition, such as Ready or DatabaseReady.
+	Type string `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status ConditionStatus `json:"status"`
+	// The metadata.generation of the object this condition was computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Last time the condition changed from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// A CamelCase reason for the last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// A human readable message with details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// HasComponentConditions returns true if any condition other than the core ones is present.
+func HasComponentConditions(conditions []Condition) bool {
+	for _, cond := range conditions {
+		if !IsCore(cond.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCore returns true for the condition types owned by the component reconciler itself.
+func IsCore(conditionType string) bool {
+	return conditionType == TypeReady || conditionType == TypeProgressing || conditionType == TypeDegraded
+}
+
+// Find returns the condition of the given type, or nil if it isn't present.
+func Find(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsTrue checks if the condition of the given type is present and True.
+func IsTrue(conditions []Condition, conditionType string) bool {
+	cond := Find(conditions, conditionType)
+	return cond != nil && cond.Status == ConditionTrue
+}
+
+// Set adds or updates a condition and returns the new slice. The transition time is only bumped
+// when the status actually changes so repeated reconciles don't generate status writes.
+func Set(conditions []Condition, newCond Condition) []Condition {
+	existing := Find(conditions, newCond.Type)
+	if existing == nil {
+		if newCond.LastTransitionTime.IsZero() {
+			newCond.LastTransitionTime = metav1.Now()
+		}
+		return append(conditions, newCond)
+	}
+	if existing.Status != newCond.Status {
+		existing.Status = newCond.Status
+		if newCond.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		} else {
+			existing.LastTransitionTime = newCond.LastTransitionTime
+		}
+	}
+	existing.ObservedGeneration = newCond.ObservedGeneration
+	existing.Reason = newCond.Reason
+	existing.Message = newCond.Message
+	return conditions
+}
+
+// Remove deletes the condition of the given type, if present, and returns the new slice.
+func Remove(conditions []Condition, conditionType string) []Condition {
+	var out []Condition
+	for _, cond := range conditions {
+		if cond.Type == conditionType {
+			continue
+		}
+		out = append(out, cond)
+	}
+	return out
+}
+
+// Pending returns the types of all non-core conditions which are not True, in a stable order.
+func Pending(conditions []Condition) []string {
+	pending := []string{}
+	for _, cond := range conditions {
+		if IsCore(cond.Type) || cond.Status == ConditionTrue {
+			continue
+		}
+		pending = append(pending, cond.Type)
+	}
+	return pending
+}
+
+// PendingMessage formats a list of pending condition types for use in a Ready message.
+func PendingMessage(pending []string) string {
+	return "waiting on " + strings.Join(pending, ", ")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+)
+
+var _ = Describe("Conditions", func() {
+	It("adds a new condition with a transition time", func() {
+		conds := conditions.Set(nil, conditions.Condition{Type: "DatabaseReady", Status: conditions.ConditionTrue})
+		Expect(conds).To(HaveLen(1))
+		Expect(conds[0].LastTransitionTime.IsZero()).To(BeFalse())
+		Expect(conditions.IsTrue(conds, "DatabaseReady")).To(BeTrue())
+	})
+
+	It("keeps the transition time if the status doesn't change", func() {
+		past := metav1.NewTime(time.Now().Add(-time.Hour))
+		conds := []conditions.Condition{{Type: "DatabaseReady", Status: conditions.ConditionFalse, LastTransitionTime: past}}
+		conds = conditions.Set(conds, conditions.Condition{Type: "DatabaseReady", Status: conditions.ConditionFalse, Reason: "Provisioning", ObservedGeneration: 2})
+		Expect(conds).To(HaveLen(1))
+		Expect(conds[0].LastTransitionTime).To(Equal(past))
+		Expect(conds[0].Reason).To(Equal("Provisioning"))
+		Expect(conds[0].ObservedGeneration).To(Equal(int64(2)))
+	})
+
+	It("bumps the transition time when the status changes", func() {
+		past := metav1.NewTime(time.Now().Add(-time.Hour))
+		conds := []conditions.Condition{{Type: "DatabaseReady", Status: conditions.ConditionFalse, LastTransitionTime: past}}
+		conds = conditions.Set(conds, conditions.Condition{Type: "DatabaseReady", Status: conditions.ConditionTrue})
+		Expect(conds[0].Status).To(Equal(conditions.ConditionTrue))
+		Expect(conds[0].LastTransitionTime.After(past.Time)).To(BeTrue())
+	})
+
+	It("lists pending component conditions but not core ones", func() {
+		conds := []conditions.Condition{
+			{Type: conditions.TypeReady, Status: conditions.ConditionFalse},
+			{Type: conditions.TypeDegraded, Status: conditions.ConditionFalse},
+			{Type: "DatabaseReady", Status: conditions.ConditionTrue},
+			{Type: "MigrationsComplete", Status: conditions.ConditionFalse},
+			{Type: "BackupReady", Status: conditions.ConditionUnknown},
+		}
+		Expect(conditions.Pending(conds)).To(Equal([]string{"MigrationsComplete", "BackupReady"}))
+	})
+
+	It("removes a condition", func() {
+		conds := []conditions.Condition{{Type: "A"}, {Type: "B"}}
+		conds = conditions.Remove(conds, "A")
+		Expect(conds).To(HaveLen(1))
+		Expect(conditions.Find(conds, "A")).To(BeNil())
+		Expect(conditions.Find(conds, "B")).ToNot(BeNil())
+	})
+
+	It("only counts non-core conditions as component conditions", func() {
+		conds := []conditions.Condition{
+			{Type: conditions.TypeReady, Status: conditions.ConditionTrue},
+			{Type: conditions.TypeDegraded, Status: conditions.ConditionFalse},
+		}
+		Expect(conditions.HasComponentConditions(conds)).To(BeFalse())
+		conds = append(conds, conditions.Condition{Type: "DatabaseReady", Status: conditions.ConditionTrue})
+		Expect(conditions.HasComponentConditions(conds)).To(BeTrue())
+	})
+})
@@ -20,6 +20,8 @@ import (
 	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // A copy of postgresv1.PostgresParam, see below for why. This time it's the
@@ -83,6 +85,8 @@ type PostgresDbConfigStatus struct {
 	Status      string             `json:"status"`
 	Connection  PostgresConnection `json:"connection"`
 	SharedUsers SharedUsersStatus  `json:"sharedUsers"`
+	// Connections to read replicas of the database server, if any.
+	ReplicaConnections []PostgresConnection `json:"replicaConnections,omitempty"`
 }
 
 // DbConfigStatus defines the observed state of DbConfig
@@ -91,6 +95,7 @@ type DbConfigStatus struct {
 	Message       string                 `json:"message"`
 	Postgres      PostgresDbConfigStatus `json:"postgres"`
 	RDSInstanceID string                 `json:"rdsInstanceId,omitempty"`
+	Conditions    []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
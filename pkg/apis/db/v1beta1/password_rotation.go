@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation to rotate a password on demand. Set it to any new value, like the current date, to trigger a rotation.
+const PasswordRotationAnnotation = "ridecell.io/rotate-password"
+
+// PasswordRotationSpec defines how often a generated password is replaced.
+// Neither Postgres nor RabbitMQ allow two passwords for one user, so there is no overlap between the old and
+// new passwords. Open connections are not affected, and app pods pick up the new password from the updated secret.
+type PasswordRotationSpec struct {
+	// How long a password is used before a new one is generated, like 2160h for 90 days. Leave empty to only
+	// rotate on demand using the ridecell.io/rotate-password annotation.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// PasswordRotationStatus records when a password was last rotated.
+type PasswordRotationStatus struct {
+	// When the current password was generated. Starts from when rotation was first seen for existing passwords.
+	// Formatted as time.UnixDate.
+	// +optional
+	LastRotated string `json:"lastRotated,omitempty"`
+	// The value of the ridecell.io/rotate-password annotation which was last acted on.
+	// +optional
+	LastRequest string `json:"lastRequest,omitempty"`
+}
+
+// Due returns true if the password should be rotated, either because the interval passed or a new rotation was requested.
+func (s PasswordRotationStatus) Due(spec PasswordRotationSpec, request string, now time.Time) bool {
+	if request != "" && request != s.LastRequest {
+		return true
+	}
+	next, ok := s.NextRotation(spec)
+	return ok && !now.Before(next)
+}
+
+// NextRotation returns when the interval passes. Returns false if there is no interval or it is unknown
+// when the password was last rotated.
+func (s PasswordRotationStatus) NextRotation(spec PasswordRotationSpec) (time.Time, bool) {
+	if spec.Interval.Duration == 0 || s.LastRotated == "" {
+		return time.Time{}, false
+	}
+	lastRotated, err := time.Parse(time.UnixDate, s.LastRotated)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return lastRotated.Add(spec.Interval.Duration), true
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018-2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+)
+
+// PostgresBackupSpec defines the desired state of PostgresBackup
+type PostgresBackupSpec struct {
+	// Name of the PostgresDatabase to back up, in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Database string `json:"database"`
+	// Name of the S3 bucket to upload the dump to. Defaults to the POSTGRES_BACKUP_BUCKET of the operator.
+	// +optional
+	BucketName string `json:"bucketName,omitempty"`
+	// AWS region of the bucket. Defaults to the AWS_REGION of the operator.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Object key for the dump. Defaults to postgres-backup/<namespace>/<name>.dump.
+	// +optional
+	Key string `json:"key,omitempty"`
+	// TTL is the time until the object and the dump in S3 clean themselves up
+	// +optional
+	TTL metav1.Duration `json:"ttl,omitempty"`
+}
+
+// PostgresBackupStatus defines the observed state of PostgresBackup
+type PostgresBackupStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// S3 URL of the finished dump.
+	// +optional
+	Location string `json:"location,omitempty"`
+	// When the dump finished.
+	// Formatted as time.UnixDate.
+	// +optional
+	CompletedAt string                 `json:"completedAt,omitempty"`
+	Conditions  []conditions.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresBackup is the Schema for the PostgresBackups API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type PostgresBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PostgresBackupSpec   `json:"spec,omitempty"`
+	Status PostgresBackupStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresBackupList contains a list of PostgresBackup
+type PostgresBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PostgresBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PostgresBackup{}, &PostgresBackupList{})
+}
@@ -18,7 +18,10 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // PostgresDatabaseSpec defines the desired state of PostgresDatabase
@@ -41,20 +44,166 @@ type PostgresDatabaseSpec struct {
 	// Migration override settings.
 	// +optional
 	MigrationOverrides MigrationOverridesSpec `json:"migrationOverrides,omitempty"`
+	// Password rotation settings for the owner user.
+	// +optional
+	PasswordRotation PasswordRotationSpec `json:"passwordRotation,omitempty"`
+	// What happens to the database when this object is deleted. Retain leaves the database and owner user in place,
+	// Archive dumps the database to S3 and then drops it, Delete drops it straight away. Defaults to Retain.
+	// +kubebuilder:validation:Enum=,Retain,Archive,Delete
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// Where to store the dump when DeletionPolicy is Archive.
+	// +optional
+	Archive PostgresDatabaseArchiveSpec `json:"archive,omitempty"`
+	// Copy the contents of another database into this one when it is first created. Has no effect on a database
+	// which already exists.
+	// +optional
+	CloneFrom *PostgresDatabaseCloneSpec `json:"cloneFrom,omitempty"`
+	// Scheduled logical backups, independent of any RDS snapshots.
+	// +optional
+	Backups PostgresDatabaseBackupsSpec `json:"backups,omitempty"`
+	// Run a PgBouncer connection pooler in front of the database. When set, Status.Connection points at the
+	// pooler instead of the database server.
+	// +optional
+	Pooler *PostgresDatabasePoolerSpec `json:"pooler,omitempty"`
+	// Size limit for the database. Going over it doesn't block anything but raises a Warning event and shows
+	// in Status.Health.
+	// +optional
+	SizeQuota *resource.Quantity `json:"sizeQuota,omitempty"`
+}
+
+// PostgresDatabasePoolerSpec defines a PgBouncer deployment for a database.
+type PostgresDatabasePoolerSpec struct {
+	// When server connections are returned to the pool. Transaction pooling is not compatible with session
+	// state like prepared statements or server-side cursors, so it has to be asked for. Defaults to session.
+	// +kubebuilder:validation:Enum=,session,transaction,statement
+	// +optional
+	PoolMode string `json:"poolMode,omitempty"`
+	// Server connections per PgBouncer pod. Defaults to 20.
+	// +optional
+	PoolSize int `json:"poolSize,omitempty"`
+	// Client connections accepted per PgBouncer pod. Defaults to 1000.
+	// +optional
+	MaxClientConnections int `json:"maxClientConnections,omitempty"`
+	// Number of PgBouncer pods. Defaults to 2.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// PostgresDatabaseBackupsSpec defines a schedule of PostgresBackups for a database.
+type PostgresDatabaseBackupsSpec struct {
+	// How often to take a backup. Scheduled backups are off when unset.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// How long to keep each backup before it and its dump are deleted. Backups are kept forever when unset.
+	// +optional
+	TTL metav1.Duration `json:"ttl,omitempty"`
+	// Name of the S3 bucket to upload dumps to. Defaults to the POSTGRES_BACKUP_BUCKET of the operator.
+	// +optional
+	BucketName string `json:"bucketName,omitempty"`
+}
+
+// PostgresDatabaseArchiveSpec defines where a database is dumped to before being dropped.
+type PostgresDatabaseArchiveSpec struct {
+	// Name of the S3 bucket to upload the dump to. The bucket must already exist.
+	BucketName string `json:"bucketName,omitempty"`
+	// AWS region of the bucket. Defaults to the AWS_REGION of the operator.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// Prefix for the object key, the dump is stored as <prefix>/<namespace>/<name>/<timestamp>.dump. Defaults to postgres-archive.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// PostgresDatabaseCloneSpec defines where to copy the initial contents of a new database from. Set one of
+// PostgresDatabaseRef or RDSSnapshotRef.
+type PostgresDatabaseCloneSpec struct {
+	// Another PostgresDatabase to copy. Namespace defaults to the namespace of this object.
+	// +optional
+	PostgresDatabaseRef corev1.ObjectReference `json:"postgresDatabaseRef,omitempty"`
+	// An RDSSnapshot to copy. The snapshot is restored to a temporary RDS instance which is deleted once the copy is done.
+	// Namespace defaults to the namespace of this object.
+	// +optional
+	RDSSnapshotRef corev1.ObjectReference `json:"rdsSnapshotRef,omitempty"`
+	// Name of the database inside the snapshot to copy. Required with RDSSnapshotRef.
+	// +optional
+	DatabaseName string `json:"databaseName,omitempty"`
+	// A SQL script in a ConfigMap in the same namespace, run against the copy before it is marked ready. Use this
+	// to anonymize PII. The script runs in a single transaction.
+	// +optional
+	ScrubScriptRef *corev1.ConfigMapKeySelector `json:"scrubScriptRef,omitempty"`
+}
+
+// PostgresDatabaseCloneStatus defines the observed state of a clone.
+type PostgresDatabaseCloneStatus struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ID of the temporary RDS instance restored from the snapshot.
+	// +optional
+	RestoreInstanceID string `json:"restoreInstanceId,omitempty"`
+	// When the copy finished.
+	// Formatted as time.UnixDate.
+	// +optional
+	CompletedAt string `json:"completedAt,omitempty"`
+}
+
+// PostgresDatabaseBackupStatus records the last scheduled backup.
+type PostgresDatabaseBackupStatus struct {
+	// Name of the last PostgresBackup created by the schedule.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// When the last PostgresBackup was created.
+	// Formatted as time.UnixDate.
+	// +optional
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// PostgresDatabaseHealthStatus is a periodic snapshot of the database's resource usage.
+type PostgresDatabaseHealthStatus struct {
+	// Size of the database on disk.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// Open connections to this database.
+	Connections int `json:"connections,omitempty"`
+	// max_connections of the whole database server, shared with every other database on it.
+	MaxConnections int `json:"maxConnections,omitempty"`
+	// Transactions open for more than five minutes.
+	LongTransactions int `json:"longTransactions,omitempty"`
+	// Age of the oldest open transaction in seconds.
+	OldestTransactionSeconds int64 `json:"oldestTransactionSeconds,omitempty"`
+	// Largest replay lag of any replica in seconds, only collected for local clusters.
+	ReplicationLagSeconds int64 `json:"replicationLagSeconds,omitempty"`
+	// Installed extensions and their versions.
+	Extensions map[string]string `json:"extensions,omitempty"`
+	// True if the database is bigger than Spec.SizeQuota.
+	QuotaExceeded bool `json:"quotaExceeded,omitempty"`
+	// When these values were collected.
+	// Formatted as time.UnixDate.
+	CollectedAt string `json:"collectedAt,omitempty"`
 }
 
 // PostgresDatabaseStatus defines the observed state of PostgresDatabase
 type PostgresDatabaseStatus struct {
-	Status                string             `json:"status"`
-	Message               string             `json:"message"`
-	DatabaseClusterStatus string             `json:"databaseClusterStatus"`
-	DatabaseStatus        string             `json:"databaseStatus"`
-	ExtensionStatus       map[string]string  `json:"extensionStatus,omitempty"`
-	UserStatus            string             `json:"userStatus"`
-	Connection            PostgresConnection `json:"connection"`
-	AdminConnection       PostgresConnection `json:"adminConnection"`
-	SharedUsers           SharedUsersStatus  `json:"sharedUsers"`
-	RDSInstanceID         string             `json:"rdsInstanceId,omitempty"`
+	Status                string                       `json:"status"`
+	Message               string                       `json:"message"`
+	DatabaseClusterStatus string                       `json:"databaseClusterStatus"`
+	DatabaseStatus        string                       `json:"databaseStatus"`
+	ExtensionStatus       map[string]string            `json:"extensionStatus,omitempty"`
+	UserStatus            string                       `json:"userStatus"`
+	Connection            PostgresConnection           `json:"connection"`
+	AdminConnection       PostgresConnection           `json:"adminConnection"`
+	SharedUsers           SharedUsersStatus            `json:"sharedUsers"`
+	RDSInstanceID         string                       `json:"rdsInstanceId,omitempty"`
+	Clone                 PostgresDatabaseCloneStatus  `json:"clone,omitempty"`
+	LastBackup            PostgresDatabaseBackupStatus `json:"lastBackup,omitempty"`
+	Conditions            []conditions.Condition       `json:"conditions,omitempty"`
+	// Admin connections to read replicas of the database server.
+	AdminReplicaConnections []PostgresConnection `json:"adminReplicaConnections,omitempty"`
+	// Connections to this database on the read replicas, as the owner user.
+	ReplicaConnections []PostgresConnection `json:"replicaConnections,omitempty"`
+	// Resource usage of the database, collected every few minutes once it is ready.
+	Health PostgresDatabaseHealthStatus `json:"health,omitempty"`
+	// Host of the PgBouncer pooler once it has been available, Connection keeps pointing at it from then on.
+	PoolerHost string `json:"poolerHost,omitempty"`
 }
 
 // +genclient
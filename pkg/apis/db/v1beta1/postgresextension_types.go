@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // PostgresExtensionSpec defines the desired state of PostgresExtension
@@ -29,8 +31,9 @@ type PostgresExtensionSpec struct {
 
 // PostgresExtensionStatus defines the observed state of PostgresExtension
 type PostgresExtensionStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
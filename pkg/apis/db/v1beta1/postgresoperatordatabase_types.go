@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 type PostgresDBRef struct {
@@ -32,8 +34,9 @@ type PostgresOperatorDatabaseSpec struct {
 
 // PostgresOperatorDatabaseStatus defines the observed state of PostgresOperatorDatabase
 type PostgresOperatorDatabaseStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
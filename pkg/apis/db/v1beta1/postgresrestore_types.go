@@ -0,0 +1,75 @@
+/*
+Copyright 2018-2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+)
+
+// PostgresRestoreSpec defines the desired state of PostgresRestore
+type PostgresRestoreSpec struct {
+	// Name of the PostgresDatabase to restore into, in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Database string `json:"database"`
+	// Name of the PostgresBackup to restore, in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Backup string `json:"backup"`
+	// Drop existing objects in the database before recreating them from the backup. Without this the restore fails
+	// if the database already has tables.
+	// +optional
+	Clean bool `json:"clean,omitempty"`
+}
+
+// PostgresRestoreStatus defines the observed state of PostgresRestore
+type PostgresRestoreStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// When the restore finished.
+	// Formatted as time.UnixDate.
+	// +optional
+	CompletedAt string                 `json:"completedAt,omitempty"`
+	Conditions  []conditions.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresRestore is the Schema for the PostgresRestores API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type PostgresRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PostgresRestoreSpec   `json:"spec,omitempty"`
+	Status PostgresRestoreStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresRestoreList contains a list of PostgresRestore
+type PostgresRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PostgresRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PostgresRestore{}, &PostgresRestoreList{})
+}
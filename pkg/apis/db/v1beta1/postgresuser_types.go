@@ -18,19 +18,77 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // PostgresUserSpec defines the desired state of PostgresUser
 type PostgresUserSpec struct {
 	Connection PostgresConnection `json:"connection"`
 	Username   string             `json:"username,omitempty"`
+	// Privileges to grant the user. Privileges previously granted by the controller which are no longer
+	// listed are revoked.
+	// +optional
+	Grants []PostgresGrant `json:"grants,omitempty"`
+	// Roles the user is a member of. Memberships previously granted by the controller which are no longer
+	// listed are revoked.
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+	// Password rotation settings.
+	// +optional
+	Rotation PasswordRotationSpec `json:"rotation,omitempty"`
+}
+
+// PostgresGrant defines a set of privileges on the tables and sequences of one schema.
+type PostgresGrant struct {
+	// Database the grant applies to. Defaults to the connection database.
+	// +optional
+	Database string `json:"database,omitempty"`
+	// Schema the grant applies to. Defaults to public.
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// Named set of privileges. readonly can SELECT, readwrite can also INSERT, UPDATE, and DELETE, and owner
+	// gets all privileges including CREATE on the schema.
+	// +optional
+	// +kubebuilder:validation:Enum=,readonly,readwrite,owner
+	Profile string `json:"profile,omitempty"`
+	// Table privileges granted in addition to the profile, like SELECT or INSERT.
+	// +optional
+	Privileges []string `json:"privileges,omitempty"`
+	// Roles whose future tables and sequences in the schema get the same privileges, using ALTER DEFAULT PRIVILEGES.
+	// Usually the owner of the application database.
+	// +optional
+	DefaultPrivilegesFor []string `json:"defaultPrivilegesFor,omitempty"`
+}
+
+// PostgresPrivilege is a single privilege granted to a PostgresUser.
+type PostgresPrivilege struct {
+	Database string `json:"database"`
+	// Schema of the object, empty for DATABASE privileges.
+	// +optional
+	Schema string `json:"schema,omitempty"`
+	// One of DATABASE, SCHEMA, TABLES, or SEQUENCES.
+	ObjectType string `json:"objectType"`
+	// Role whose future objects this applies to, for default privileges.
+	// +optional
+	ForRole   string `json:"forRole,omitempty"`
+	Privilege string `json:"privilege"`
 }
 
 // PostgresUserStatus defines the observed state of PostgresUser
 type PostgresUserStatus struct {
-	Status     string             `json:"status"`
-	Message    string             `json:"message"`
-	Connection PostgresConnection `json:"connection"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Connection PostgresConnection     `json:"connection"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
+	// Privileges currently granted by the controller.
+	// +optional
+	Privileges []PostgresPrivilege `json:"privileges,omitempty"`
+	// Role memberships currently granted by the controller.
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+	// +optional
+	Rotation PasswordRotationStatus `json:"rotation,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // RabbitmqPermission defines a single user permissions entry.
@@ -39,6 +41,9 @@ type RabbitmqUserSpec struct {
 	Permissions []RabbitmqPermission `json:"permissions,omitempty"`
 	// TODO TopicPermissions
 	Connection RabbitmqConnection `json:"connection,omitempty"`
+	// Password rotation settings.
+	// +optional
+	Rotation PasswordRotationSpec `json:"rotation,omitempty"`
 }
 
 // RabbitmqUserStatus defines the observed state of RabbitmqUser
@@ -46,6 +51,9 @@ type RabbitmqUserStatus struct {
 	Status     string                   `json:"status"`
 	Message    string                   `json:"message"`
 	Connection RabbitmqStatusConnection `json:"connection,omitempty"`
+	Conditions []conditions.Condition   `json:"conditions,omitempty"`
+	// +optional
+	Rotation PasswordRotationStatus `json:"rotation,omitempty"`
 }
 
 // +genclient
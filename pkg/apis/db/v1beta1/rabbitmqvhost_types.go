@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 type RabbitmqPolicy struct {
@@ -39,6 +41,9 @@ type RabbitmqVhostSpec struct {
 	SkipUser   bool                      `json:"skipUser,omitempty"`
 	Policies   map[string]RabbitmqPolicy `json:"policies,omitempty"`
 	Connection RabbitmqConnection        `json:"connection,omitempty"`
+	// Password rotation settings for the vhost user.
+	// +optional
+	PasswordRotation PasswordRotationSpec `json:"passwordRotation,omitempty"`
 }
 
 // RabbitmqVhostStatus defines the observed state of RabbitmqVhost
@@ -46,6 +51,7 @@ type RabbitmqVhostStatus struct {
 	Status     string                   `json:"status"`
 	Message    string                   `json:"message"`
 	Connection RabbitmqStatusConnection `json:"connection,omitempty"`
+	Conditions []conditions.Condition   `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // RDSInstanceSpec defines the desired state of RDS
@@ -34,15 +36,49 @@ type RDSInstanceSpec struct {
 	Username          string            `json:"username,omitempty"`
 	SubnetGroupName   string            `json:"subnetGroupName,omitempty"`
 	VPCID             string            `json:"vpcID,omitempty"`
+	// Storage type, one of standard, gp2, gp3 or io1. Defaults to gp2.
+	StorageType string `json:"storageType,omitempty"`
+	// Provisioned IOPS, only used with io1 and gp3 storage.
+	IOPS int64 `json:"iops,omitempty"`
+	// Number of days to keep automated backups. Defaults to 7.
+	BackupRetentionPeriod *int64 `json:"backupRetentionPeriod,omitempty"`
+	// Block deletes of the RDS instance, including from the operator's finalizer.
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+	// Defaults to true.
+	PubliclyAccessible *bool `json:"publiclyAccessible,omitempty"`
+	// Apply disruptive changes (instance class, engine version, Multi-AZ and
+	// storage type) right away instead of waiting for the MaintenanceWindow.
+	ApplyImmediately bool `json:"applyImmediately,omitempty"`
+	// Read replicas of this instance.
+	ReadReplicas []RDSReadReplicaSpec `json:"readReplicas,omitempty"`
+}
+
+// RDSReadReplicaSpec defines a read replica of an RDSInstance.
+type RDSReadReplicaSpec struct {
+	// Suffix for the replica, the instance ID is <instanceID>-<name>.
+	Name string `json:"name"`
+	// Defaults to the instance class of the primary.
+	InstanceClass string `json:"instanceClass,omitempty"`
+	// Availability zone for the replica, picked by RDS if not set.
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
 }
 
 // RDSInstanceStatus defines the observed state of RDSInstance
 type RDSInstanceStatus struct {
-	Status          string             `json:"status"`
-	Message         string             `json:"message"`
-	Connection      PostgresConnection `json:"rdsConnection"`
-	InstanceID      string             `json:"instanceID"`
-	SecurityGroupID string             `json:"securityGroupID"`
+	Status          string                 `json:"status"`
+	Message         string                 `json:"message"`
+	Connection      PostgresConnection     `json:"rdsConnection"`
+	InstanceID      string                 `json:"instanceID"`
+	SecurityGroupID string                 `json:"securityGroupID"`
+	Conditions      []conditions.Condition `json:"conditions,omitempty"`
+	// Parameter group in use, this changes once an engine upgrade to a new family has been applied.
+	ParameterGroupName string `json:"parameterGroupName,omitempty"`
+	// Parameter group for the new family while an engine upgrade is waiting to be applied.
+	PendingParameterGroupName string `json:"pendingParameterGroupName,omitempty"`
+	// Changes waiting for the maintenance window or still being applied by RDS, keyed by field.
+	PendingModifications map[string]string `json:"pendingModifications,omitempty"`
+	// Connections for the read replicas which are available.
+	ReplicaConnections []PostgresConnection `json:"replicaConnections,omitempty"`
 }
 
 // +genclient
@@ -0,0 +1,92 @@
+/*
+Copyright 2018-2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+)
+
+// RDSRestoreSpec defines the desired state of RDSRestore
+type RDSRestoreSpec struct {
+	// Name of an RDSInstance in the same namespace. The restored instance uses its parameter group, security group,
+	// subnet group, instance class and master password.
+	// +kubebuilder:validation:MinLength=1
+	RDSInstanceRef string `json:"rdsInstanceRef"`
+	// RDS instance to restore to a point in time. Defaults to the instance of RDSInstanceRef.
+	// +optional
+	SourceInstanceID string `json:"sourceInstanceID,omitempty"`
+	// RDS snapshot to restore from. When set the restore is from the snapshot instead of to a point in time.
+	// +optional
+	SnapshotID string `json:"snapshotID,omitempty"`
+	// Point in time to restore to, in RFC 3339 format. Defaults to the latest restorable time. Cannot be used with SnapshotID.
+	// +optional
+	RestoreTime string `json:"restoreTime,omitempty"`
+	// ID of the new RDS instance. Defaults to the name of this object.
+	// +optional
+	TargetInstanceID string `json:"targetInstanceID,omitempty"`
+	// A DbConfig to repoint at the new instance once it is available, by setting its migration overrides. Only has an
+	// effect on Shared mode DbConfigs.
+	// +optional
+	DbConfigRef *corev1.LocalObjectReference `json:"dbConfigRef,omitempty"`
+}
+
+// RDSRestoreStatus defines the observed state of RDSRestore
+type RDSRestoreStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// ID of the restored RDS instance.
+	// +optional
+	InstanceID string `json:"instanceID,omitempty"`
+	// Hostname of the restored RDS instance.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// When the restored instance became available.
+	// Formatted as time.UnixDate.
+	// +optional
+	CompletedAt string                 `json:"completedAt,omitempty"`
+	Conditions  []conditions.Condition `json:"conditions,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RDSRestore is the Schema for the RDSRestores API. Deleting it leaves the restored instance in place.
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type RDSRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RDSRestoreSpec   `json:"spec,omitempty"`
+	Status RDSRestoreStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RDSRestoreList contains a list of RDSRestore
+type RDSRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RDSRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RDSRestore{}, &RDSRestoreList{})
+}
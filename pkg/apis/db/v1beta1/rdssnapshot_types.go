@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // RDSSnapshotSpec defines the desired state of RDSSnapshot
@@ -33,9 +35,10 @@ type RDSSnapshotSpec struct {
 
 // RDSSnapshotStatus defines the observed state of RDSSnapshot
 type RDSSnapshotStatus struct {
-	Status     string `json:"status"`
-	Message    string `json:"message"`
-	SnapshotID string `json:"snapshotId"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	SnapshotID string                 `json:"snapshotId"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
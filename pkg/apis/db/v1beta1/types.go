@@ -40,6 +40,27 @@ type PostgresConnection struct {
 	SSLMode           string            `json:"sslmode,omitempty"`
 }
 
+// Named privilege profiles for PostgresUser grants.
+const (
+	PostgresProfileReadOnly  = "readonly"
+	PostgresProfileReadWrite = "readwrite"
+	PostgresProfileOwner     = "owner"
+)
+
+// Deletion policies for PostgresDatabase.
+const (
+	DeletionPolicyRetain  = "Retain"
+	DeletionPolicyArchive = "Archive"
+	DeletionPolicyDelete  = "Delete"
+)
+
+// Clone statuses for PostgresDatabase, a finished clone is StatusReady.
+const (
+	CloneStatusPending   = "Pending"
+	CloneStatusRestoring = "Restoring"
+	CloneStatusCopying   = "Copying"
+)
+
 // Currently unused but could hold future per-object connection details.
 type RabbitmqConnection struct{}
 
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // GCPProjectSpec defines the desired state of GCPProject
@@ -39,10 +41,11 @@ type ProjectParent struct {
 
 // GCPProjectStatus defines the observed state of GCPProject
 type GCPProjectStatus struct {
-	Status                string `json:"status"`
-	Message               string `json:"message"`
-	ProjectOperationName  string `json:"projectOperationName,omitempty"`
-	FirebaseOperationName string `json:"firebaseOperationName,omitempty"`
+	Status                string                 `json:"status"`
+	Message               string                 `json:"message"`
+	ProjectOperationName  string                 `json:"projectOperationName,omitempty"`
+	FirebaseOperationName string                 `json:"firebaseOperationName,omitempty"`
+	Conditions            []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -18,6 +18,8 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // ServiceAccountSpec defines the desired state of ServiceAccount
@@ -29,9 +31,10 @@ type GCPServiceAccountSpec struct {
 
 // GCPServiceAccountStatus defines the observed state of GCPServiceAccount
 type GCPServiceAccountStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Email   string `json:"email"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Email      string                 `json:"email"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -32,3 +33,11 @@ func (pe *RidecellIngress) SetErrorStatus(errorMsg string) {
 	pe.Status.Status = "Error"
 	pe.Status.Message = errorMsg
 }
+
+func (pe *RidecellIngress) GetConditions() []conditions.Condition {
+	return pe.Status.Conditions
+}
+
+func (pe *RidecellIngress) SetConditions(conds []conditions.Condition) {
+	pe.Status.Conditions = conds
+}
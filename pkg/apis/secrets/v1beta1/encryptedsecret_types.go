@@ -18,15 +18,60 @@ package v1beta1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 // KMS doesn't allow encrypting an empty string so use a magic constant to represent it.
 const EncryptedSecretEmptyKey = "___empty_string___"
 
+// EncryptedSecretKeyProvider selects the key used to decrypt the values. Only one provider may be set, AWS
+// KMS is used if none are.
+type EncryptedSecretKeyProvider struct {
+	// +optional
+	AWSKMS *AWSKMSKeyProvider `json:"awsKms,omitempty"`
+	// +optional
+	GCPKMS *GCPKMSKeyProvider `json:"gcpKms,omitempty"`
+	// +optional
+	Local *LocalKeyProvider `json:"local,omitempty"`
+}
+
+// AWSKMSKeyProvider decrypts values with AWS KMS.
+type AWSKMSKeyProvider struct {
+	// Region of the KMS key, defaults to us-west-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+	// If set, values must have been encrypted with this key. Key ID or key ARN, aliases are not supported.
+	// +optional
+	KeyID string `json:"keyId,omitempty"`
+	// Encryption context the values were encrypted with, defaults to RidecellOperator=true.
+	// +optional
+	EncryptionContext map[string]string `json:"encryptionContext,omitempty"`
+}
+
+// GCPKMSKeyProvider decrypts values with Google Cloud KMS.
+type GCPKMSKeyProvider struct {
+	// Resource name of the key, projects/*/locations/*/keyRings/*/cryptoKeys/*.
+	KeyName string `json:"keyName"`
+	// Additional authenticated data the values were encrypted with.
+	// +optional
+	AdditionalAuthenticatedData string `json:"additionalAuthenticatedData,omitempty"`
+}
+
+// LocalKeyProvider decrypts values with a NaCl secretbox key stored in a Secret in the same namespace. Meant for
+// development clusters and tests without cloud access. Values are the 24 byte nonce followed by the sealed box.
+type LocalKeyProvider struct {
+	SecretName string `json:"secretName"`
+	// Key in the Secret holding the raw 32 byte key, defaults to "key".
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
 // EncryptedSecretStatus defines the observed state of EncryptedSecret
 type EncryptedSecretStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -41,6 +86,8 @@ type EncryptedSecret struct {
 
 	Status EncryptedSecretStatus `json:"status,omitempty"`
 	Data   map[string]string     `json:"data,omitempty"`
+	// +optional
+	KeyProvider *EncryptedSecretKeyProvider `json:"keyProvider,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
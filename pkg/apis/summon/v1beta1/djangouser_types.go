@@ -19,6 +19,7 @@ package v1beta1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 )
 
@@ -38,8 +39,9 @@ type DjangoUserSpec struct {
 
 // DjangoUserStatus defines the observed state of DjangoUser
 type DjangoUserStatus struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string                 `json:"status"`
+	Message    string                 `json:"message"`
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
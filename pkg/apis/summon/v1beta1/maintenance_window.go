@@ -0,0 +1,150 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const week = 7 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// A window as offsets from the start of the week (Sunday 00:00). End can be past one week when the window wraps.
+type weekRange struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// Until returns how long until the window next opens, or 0 if t is inside the window.
+func (w MaintenanceWindow) Until(t time.Time) (time.Duration, error) {
+	ranges, loc, err := w.parse()
+	if err != nil {
+		return 0, err
+	}
+
+	local := t.In(loc)
+	hour, minute, sec := local.Clock()
+	offset := time.Duration(local.Weekday())*24*time.Hour + time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(sec)*time.Second
+
+	wait := week
+	for _, r := range ranges {
+		since := (offset - r.start + week) % week
+		if since < r.end-r.start {
+			return 0, nil
+		}
+		if until := week - since; until < wait {
+			wait = until
+		}
+	}
+	return wait, nil
+}
+
+// Validate checks the window and timezone can be parsed.
+func (w MaintenanceWindow) Validate() error {
+	_, _, err := w.parse()
+	return err
+}
+
+func (w MaintenanceWindow) parse() ([]weekRange, *time.Location, error) {
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unknown timezone %s", w.Timezone)
+		}
+	}
+
+	parts := strings.Split(w.Window, "-")
+	if len(parts) != 2 {
+		return nil, nil, errors.Errorf("window %q must be in the format ddd:hh24:mi-ddd:hh24:mi or hh24:mi-hh24:mi", w.Window)
+	}
+	startDay, start, err := parseWindowTime(parts[0])
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid window start in %q", w.Window)
+	}
+	endDay, end, err := parseWindowTime(parts[1])
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid window end in %q", w.Window)
+	}
+	if (startDay == nil) != (endDay == nil) {
+		return nil, nil, errors.Errorf("window %q must set a day on both ends or neither", w.Window)
+	}
+
+	if startDay == nil {
+		// Daily window, repeat it for every day of the week.
+		if end <= start {
+			end += 24 * time.Hour
+		}
+		ranges := make([]weekRange, 7)
+		for day := range ranges {
+			dayOffset := time.Duration(day) * 24 * time.Hour
+			ranges[day] = weekRange{start: dayOffset + start, end: dayOffset + end}
+		}
+		return ranges, loc, nil
+	}
+
+	r := weekRange{
+		start: time.Duration(*startDay)*24*time.Hour + start,
+		end:   time.Duration(*endDay)*24*time.Hour + end,
+	}
+	if r.end <= r.start {
+		r.end += week
+	}
+	return []weekRange{r}, loc, nil
+}
+
+// Parses "ddd:hh24:mi" or "hh24:mi". The day is nil if not given.
+func parseWindowTime(value string) (*time.Weekday, time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	var day *time.Weekday
+	switch len(parts) {
+	case 2:
+	case 3:
+		weekday, ok := weekdays[strings.ToLower(parts[0])]
+		if !ok {
+			return nil, 0, errors.Errorf("unknown day %s", parts[0])
+		}
+		day = &weekday
+		parts = parts[1:]
+	default:
+		return nil, 0, errors.Errorf("unable to parse %q", value)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return nil, 0, errors.Errorf("invalid hour %s", parts[0])
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return nil, 0, errors.Errorf("invalid minute %s", parts[1])
+	}
+	return day, time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
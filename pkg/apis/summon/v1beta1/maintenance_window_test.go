@@ -0,0 +1,62 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+var _ = Describe("MaintenanceWindow", func() {
+	// A Monday.
+	now := time.Date(2019, 6, 3, 10, 30, 0, 0, time.UTC)
+
+	until := func(window, timezone string) time.Duration {
+		d, err := summonv1beta1.MaintenanceWindow{Window: window, Timezone: timezone}.Until(now)
+		Expect(err).NotTo(HaveOccurred())
+		return d
+	}
+
+	It("is open inside a weekly window", func() {
+		Expect(until("Mon:10:00-Mon:11:00", "")).To(Equal(time.Duration(0)))
+	})
+
+	It("waits for a weekly window later in the week", func() {
+		Expect(until("Mon:11:00-Mon:12:00", "")).To(Equal(30 * time.Minute))
+		Expect(until("Sun:23:00-Mon:01:00", "")).To(Equal(156*time.Hour + 30*time.Minute))
+	})
+
+	It("handles daily windows which wrap past midnight", func() {
+		Expect(until("22:00-02:00", "")).To(Equal(11*time.Hour + 30*time.Minute))
+		Expect(until("09:00-10:00", "")).To(Equal(22*time.Hour + 30*time.Minute))
+	})
+
+	It("uses the timezone", func() {
+		Expect(until("03:00-04:00", "America/Los_Angeles")).To(Equal(time.Duration(0)))
+	})
+
+	It("rejects malformed windows", func() {
+		Expect(summonv1beta1.MaintenanceWindow{Window: "Fri:10:00"}.Validate()).To(HaveOccurred())
+		Expect(summonv1beta1.MaintenanceWindow{Window: "Foo:10:00-Foo:11:00"}.Validate()).To(HaveOccurred())
+		Expect(summonv1beta1.MaintenanceWindow{Window: "Mon:10:00-11:00"}.Validate()).To(HaveOccurred())
+		Expect(summonv1beta1.MaintenanceWindow{Window: "10:00-11:00", Timezone: "Mars/Base"}.Validate()).To(HaveOccurred())
+	})
+})
@@ -19,6 +19,8 @@ package v1beta1
 import (
 	//corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 )
 
 type MockCarServerTenantSpec struct {
@@ -30,9 +32,10 @@ type MockCarServerTenantSpec struct {
 }
 
 type MockCarServerTenantStatus struct {
-	Status        string `json:"status,omitempty"`
-	Message       string `json:"message,omitempty"`
-	KeysSecretRef string `json:"keyssecretref,omitempty"`
+	Status        string                 `json:"status,omitempty"`
+	Message       string                 `json:"message,omitempty"`
+	KeysSecretRef string                 `json:"keyssecretref,omitempty"`
+	Conditions    []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
 
@@ -33,6 +34,14 @@ func (s *SummonPlatform) SetErrorStatus(errorMsg string) {
 	s.Status.Message = errorMsg
 }
 
+func (s *SummonPlatform) GetConditions() []conditions.Condition {
+	return s.Status.Conditions
+}
+
+func (s *SummonPlatform) SetConditions(conds []conditions.Condition) {
+	s.Status.Conditions = conds
+}
+
 func (s *DjangoUser) GetStatus() components.Status {
 	return s.Status
 }
@@ -46,6 +55,14 @@ func (s *DjangoUser) SetErrorStatus(errorMsg string) {
 	s.Status.Message = errorMsg
 }
 
+func (s *DjangoUser) GetConditions() []conditions.Condition {
+	return s.Status.Conditions
+}
+
+func (s *DjangoUser) SetConditions(conds []conditions.Condition) {
+	s.Status.Conditions = conds
+}
+
 func (s *MockCarServerTenant) GetStatus() components.Status {
 	return s.Status
 }
@@ -58,3 +75,11 @@ func (s *MockCarServerTenant) SetErrorStatus(errorMsg string) {
 	s.Status.Status = StatusError
 	s.Status.Message = errorMsg
 }
+
+func (s *MockCarServerTenant) GetConditions() []conditions.Condition {
+	return s.Status.Conditions
+}
+
+func (s *MockCarServerTenant) SetConditions(conds []conditions.Condition) {
+	s.Status.Conditions = conds
+}
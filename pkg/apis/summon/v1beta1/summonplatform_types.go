@@ -20,6 +20,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 )
 
@@ -54,6 +55,12 @@ type DatabaseSpec struct {
 	// An optional ref to a DbConfig object to use for configuration. Defaults to the name of the namespace.
 	// +optional
 	DbConfigRef corev1.ObjectReference `json:"dbConfigRef,omitempty"`
+	// Copy another tenant's database or an RDS snapshot into the database when it is first created.
+	// +optional
+	CloneFrom *dbv1beta1.PostgresDatabaseCloneSpec `json:"cloneFrom,omitempty"`
+	// Connect the app to the database through a PgBouncer pooler.
+	// +optional
+	Pooler *dbv1beta1.PostgresDatabasePoolerSpec `json:"pooler,omitempty"`
 }
 
 // CelerySpec defines configuration and settings for Celery.
@@ -88,6 +95,10 @@ type BackupSpec struct {
 	TTL metav1.Duration `json:"ttl,omitempty"`
 	// whether or not the backup process waits on the snapshot to finish
 	WaitUntilReady *bool `json:"waitUntilReady,omitempty"`
+	// S3 bucket for a logical pg_dump backup, used instead of an RDS snapshot when the database is not on its own
+	// RDS instance. Defaults to the POSTGRES_BACKUP_BUCKET of the operator.
+	// +optional
+	BucketName string `json:"bucketName,omitempty"`
 }
 
 // WaitSpec defines the configuration of post migration delays.
@@ -95,6 +106,74 @@ type WaitSpec struct {
 	PostMigrate metav1.Duration `json:"postMigrate,omitempty"`
 }
 
+// RollbackSpec defines how failed deploys are detected and recovered from.
+type RollbackSpec struct {
+	// Roll the web, celeryd, and daphne Deployments back to the last known good version if a new version
+	// doesn't become ready within the progress deadline. Migrations are not re-run. To retry, deploy a different version.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// How long a new version has to become ready before the deploy is considered failed. Defaults to 15 minutes.
+	// +optional
+	ProgressDeadline metav1.Duration `json:"progressDeadline,omitempty"`
+}
+
+// RolloutSpec defines how a new version is rolled out to the web Deployment.
+type RolloutSpec struct {
+	// Rollout strategy. Leave empty to update everything at once, or set to Canary to first send a share of
+	// web traffic to a canary Deployment of the new version and only promote it once it stays healthy.
+	// +optional
+	// +kubebuilder:validation:Enum=,Canary
+	Strategy string `json:"strategy,omitempty"`
+	// Canary rollout settings, only used with the Canary strategy.
+	// +optional
+	Canary CanarySpec `json:"canary,omitempty"`
+}
+
+// CanarySpec defines the canary web Deployment and the health gate it has to pass before promotion.
+type CanarySpec struct {
+	// Percentage of web traffic sent to the canary. Defaults to 10.
+	// +optional
+	TrafficPercent int `json:"trafficPercent,omitempty"`
+	// Number of canary web pods. Defaults to 1.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// How long the canary has to stay ready and healthy before it is promoted. Defaults to 10 minutes.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// A Prometheus query checked while the canary is running, like the alert expressions used for monitoring.
+	// If it returns any results the canary is aborted. Requires PROMETHEUS_URL to be set on the operator.
+	// +optional
+	HealthQuery string `json:"healthQuery,omitempty"`
+}
+
+// MigrationsSpec defines how database migrations are run for a new version.
+type MigrationsSpec struct {
+	// Run a plan job (manage.py migrate --plan) for each new version before migrating, and record the
+	// pending migrations in the status.
+	// +optional
+	Plan bool `json:"plan,omitempty"`
+	// Hold planned migrations until the ridecell.io/approve-migrations annotation is set to the new version.
+	// Only used with Plan. Defaults to true for prod.
+	// +optional
+	RequireApproval *bool `json:"requireApproval,omitempty"`
+	// How many times a failed migration job is retried before giving up. Defaults to 2.
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+	// How long to wait before retrying a failed migration job, doubled after each retry. Defaults to 2 minutes.
+	// +optional
+	RetryBackoff metav1.Duration `json:"retryBackoff,omitempty"`
+}
+
+// MaintenanceWindow defines a recurring time range in which version changes are allowed to start.
+type MaintenanceWindow struct {
+	// Time range in the same format as the RDS maintenance window, ddd:hh24:mi-ddd:hh24:mi, like "Sun:07:00-Sun:09:00".
+	// The days can be left off for a daily window, like "22:00-02:00".
+	Window string `json:"window"`
+	// IANA time zone the window is in, like "America/Los_Angeles". Defaults to UTC.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+}
+
 // MigrationOverridesSpec defines value overrides used when migrating Ansible-based Summon instances into Kubernetes/ridecell-operator.
 type MigrationOverridesSpec struct {
 	RDSInstanceID     string `json:"rdsInstanceId,omitempty"`
@@ -247,6 +326,10 @@ type SummonPlatformSpec struct {
 	// Database-related settings.
 	// +optional
 	Database DatabaseSpec `json:"database,omitempty"`
+	// Password rotation settings for the Postgres and RabbitMQ users. The app secrets are updated and the
+	// Deployments restarted after each rotation.
+	// +optional
+	PasswordRotation dbv1beta1.PasswordRotationSpec `json:"passwordRotation,omitempty"`
 	// The flavor of data to be imported upon creation
 	// +optional
 	Flavor string `json:"flavor,omitempty"`
@@ -265,6 +348,20 @@ type SummonPlatformSpec struct {
 	// Deployment wait settings
 	// +optional
 	Waits WaitSpec `json:"waits,omitempty"`
+	// Failed deploy detection and rollback settings.
+	// +optional
+	Rollback RollbackSpec `json:"rollback,omitempty"`
+	// Staged rollout settings.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+	// Migration settings.
+	// +optional
+	Migrations MigrationsSpec `json:"migrations,omitempty"`
+	// Times when version changes are allowed to start. A new version set outside of all windows is held in
+	// WaitingForWindow until the next one opens, unless the ridecell.io/skip-maintenance-window annotation is set
+	// to that version. New instances are never held. Leave empty to allow changes at any time.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 	// Migration override settings.
 	// +optional
 	MigrationOverrides MigrationOverridesSpec `json:"migrationOverrides,omitempty"`
@@ -311,6 +408,10 @@ type SummonPlatformSpec struct {
 	// Settings for comp-customer-portal.
 	// +optional
 	CustomerPortal CompCustomerPortalSpec `json:"customerPortal,omitempty"`
+	// Rotate the AWS access key of the instance's IAMUser once it is this old. Rotation restarts the app, so
+	// it is off unless set.
+	// +optional
+	AWSAccessKeyMaxAge metav1.Duration `json:"awsAccessKeyMaxAge,omitempty"`
 	// Feature flag to disable the CORE-1540 fixup in case it goes AWOL.
 	// To be removed when support for the 1540 fixup is removed in summon.
 	// +optional
@@ -360,12 +461,93 @@ type MIVStatus struct {
 // WaitStatus is the output information for deployment Waits.
 type WaitStatus struct {
 	// The time that deployments should wait for after migrations to continue.
-	// Real type = time.Time
-	// workaround because metav1.Time is broked
+	// Timestamps in status are time.UnixDate strings rather than metav1.Time. A zero metav1.Time
+	// serializes as null even with omitempty, which the generated CRD validation rejects for a string
+	// field, so optional timestamps can't use it.
 	// +optional
 	Until string `json:"until,omitempty"`
 }
 
+// DeployStatus tracks the progress of the current deploy and the last known good version.
+type DeployStatus struct {
+	// The last version which became fully ready.
+	// +optional
+	LastGoodVersion string `json:"lastGoodVersion,omitempty"`
+	// The version currently being rolled out.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// When the current version started rolling out.
+	// Formatted as time.UnixDate.
+	// +optional
+	StartedAt string `json:"startedAt,omitempty"`
+	// A version which failed to become ready within the progress deadline.
+	// +optional
+	FailedVersion string `json:"failedVersion,omitempty"`
+}
+
+// MigrationPlanStatus is the output of the migration plan job for a version.
+type MigrationPlanStatus struct {
+	// The version which was planned.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Migrations which will be applied, like app.0002_name.
+	// +optional
+	Pending []string `json:"pending,omitempty"`
+	// True if any pending migration removes or renames existing data.
+	// +optional
+	Destructive bool `json:"destructive,omitempty"`
+	// The operations which made the plan destructive.
+	// +optional
+	DestructiveOperations []string `json:"destructiveOperations,omitempty"`
+	// True if the plan output was cut off. Approval is always required as the plan could not be fully checked.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// MigrationFailureStatus describes the last failed migration job for a version.
+type MigrationFailureStatus struct {
+	// The version whose migrations failed.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// How many times the migration job has been retried for this version.
+	// +optional
+	Retries int32 `json:"retries,omitempty"`
+	// Why the migration container stopped, such as Error or OOMKilled.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Exit code of the migration container.
+	// +optional
+	ExitCode int32 `json:"exitCode,omitempty"`
+	// The end of the migration logs, truncated.
+	// +optional
+	LogTail string `json:"logTail,omitempty"`
+	// When the failure was seen, cleared when the job is retried.
+	// Formatted as time.UnixDate.
+	// +optional
+	FailedAt string `json:"failedAt,omitempty"`
+}
+
+// CanaryStatus tracks the canary for the version currently being rolled out.
+type CanaryStatus struct {
+	// The version running in the canary.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// One of Progressing, Promoted, or Aborted.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// When the canary was started.
+	// Formatted as time.UnixDate.
+	// +optional
+	StartedAt string `json:"startedAt,omitempty"`
+	// When the canary became ready and the health gate started.
+	// Formatted as time.UnixDate.
+	// +optional
+	HealthySince string `json:"healthySince,omitempty"`
+	// Why the canary was aborted.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // SummonPlatformStatus defines the observed state of SummonPlatform
 type SummonPlatformStatus struct {
 	// Overall object status
@@ -380,6 +562,8 @@ type SummonPlatformStatus struct {
 	// Current PostgresDatabase status if one exists.
 	PostgresStatus     string                       `json:"postgresStatus,omitempty"`
 	PostgresConnection dbv1beta1.PostgresConnection `json:"postgresConnection,omitempty"`
+	// Connections to read replicas of the database, if any.
+	PostgresReplicaConnections []dbv1beta1.PostgresConnection `json:"postgresReplicaConnections,omitempty"`
 
 	// Current RabbitMQ status if one exists.
 	RabbitMQStatus     string                             `json:"rabbitmqStatus,omitempty"`
@@ -388,6 +572,12 @@ type SummonPlatformStatus struct {
 	// Previous version for which migrations ran successfully.
 	// +optional
 	MigrateVersion string `json:"migrateVersion,omitempty"`
+	// Migration plan for the current version, if planning is enabled.
+	// +optional
+	MigrationPlan MigrationPlanStatus `json:"migrationPlan,omitempty"`
+	// Details of the last failed migration job.
+	// +optional
+	MigrationFailure MigrationFailureStatus `json:"migrationFailure,omitempty"`
 	// Previous version for which a backup was made.
 	// +optional
 	BackupVersion string `json:"backupVersion,omitempty"`
@@ -400,6 +590,16 @@ type SummonPlatformStatus struct {
 	// Status for deployment Waits
 	// +optional
 	Wait WaitStatus `json:"wait,omitempty"`
+	// Status of the current deploy, used for failed deploy detection and rollback.
+	// +optional
+	Deploy DeployStatus `json:"deploy,omitempty"`
+	// Status of the canary when using the Canary rollout strategy.
+	// +optional
+	Canary CanaryStatus `json:"canary,omitempty"`
+
+	// Standard status conditions.
+	// +optional
+	Conditions []conditions.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Hardware types supported by the mock car server.
+var validMockTenantHardwareTypes = []string{"OTAKEYS", "MENSA"}
+
+// Default fills in the spec fields which only depend on the object's own name and namespace. These are
+// safe to persist at admission time, everything else is defaulted in-memory by the defaults component
+// on every reconcile so that changes to the defaults roll out to existing instances.
+func (s *SummonPlatform) Default() {
+	if s.Spec.Environment == "" {
+		s.Spec.Environment = strings.TrimPrefix(s.Namespace, "summon-")
+	}
+	if s.Spec.Hostname == "" {
+		baseHostname := ".ridecell.us"
+		if s.Spec.Environment == "uat" || s.Spec.Environment == "prod" {
+			baseHostname = ".ridecell.com"
+		}
+		s.Spec.Hostname = s.Name + baseHostname
+	}
+	if s.Spec.PullSecret == "" {
+		s.Spec.PullSecret = "pull-secret"
+	}
+	if s.Spec.MockTenantHardwareType == "" {
+		s.Spec.MockTenantHardwareType = "OTAKEYS"
+	}
+}
+
+// ValidateCreate checks a new SummonPlatform spec for errors which would otherwise only show up during reconcile.
+func (s *SummonPlatform) ValidateCreate() field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	if s.Spec.Version == "" && s.Spec.AutoDeploy == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("version"), "one of version or autoDeploy must be set"))
+	}
+	if s.Spec.Version != "" && s.Spec.AutoDeploy != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("autoDeploy"), "version and autoDeploy are both set, only one may be specified"))
+	}
+
+	celeryBeat := s.Spec.Replicas.CeleryBeat
+	if celeryBeat != nil && *celeryBeat != 0 && *celeryBeat != 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("replicas", "celeryBeat"), *celeryBeat, "must be exactly 0 or 1"))
+	}
+
+	// If the persistentVolumeClaim for redis changes this limit should as well.
+	if s.Spec.Redis.RAM > 10*1024 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("redis", "ram"), s.Spec.Redis.RAM, "redis memory limit cannot surpass available disk space"))
+	}
+
+	if s.Spec.MockTenantHardwareType != "" {
+		valid := false
+		for _, hwType := range validMockTenantHardwareTypes {
+			if s.Spec.MockTenantHardwareType == hwType {
+				valid = true
+			}
+		}
+		if !valid {
+			allErrs = append(allErrs, field.NotSupported(specPath.Child("mockTenantHardwareType"), s.Spec.MockTenantHardwareType, validMockTenantHardwareTypes))
+		}
+	}
+
+	rollout := s.Spec.Rollout
+	if rollout.Strategy != "" && rollout.Strategy != RolloutStrategyCanary {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("rollout", "strategy"), rollout.Strategy, []string{RolloutStrategyCanary}))
+	}
+	if rollout.Canary.TrafficPercent < 0 || rollout.Canary.TrafficPercent > 100 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("rollout", "canary", "trafficPercent"), rollout.Canary.TrafficPercent, "must be between 0 and 100"))
+	}
+
+	for i, window := range s.Spec.MaintenanceWindows {
+		if err := window.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("maintenanceWindows").Index(i), window.Window, err.Error()))
+		}
+	}
+
+	if s.Spec.Hostname != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(s.Spec.Hostname) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("hostname"), s.Spec.Hostname, msg))
+		}
+	}
+	seen := map[string]bool{s.Spec.Hostname: true}
+	for i, alias := range s.Spec.Aliases {
+		aliasPath := specPath.Child("aliases").Index(i)
+		for _, msg := range validation.IsDNS1123Subdomain(alias) {
+			allErrs = append(allErrs, field.Invalid(aliasPath, alias, msg))
+		}
+		if seen[alias] {
+			allErrs = append(allErrs, field.Duplicate(aliasPath, alias))
+		}
+		seen[alias] = true
+	}
+
+	return allErrs
+}
+
+// ValidateUpdate runs the same checks as ValidateCreate, and also rejects changes to immutable fields.
+func (s *SummonPlatform) ValidateUpdate(old *SummonPlatform) field.ErrorList {
+	allErrs := s.ValidateCreate()
+	specPath := field.NewPath("spec")
+
+	// Objects created before defaulting at admission might not have these set, so only compare if the old value is there.
+	if old.Spec.Environment != "" && s.Spec.Environment != old.Spec.Environment {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("environment"), "field is immutable"))
+	}
+	oldRef := old.Spec.Database.DbConfigRef
+	newRef := s.Spec.Database.DbConfigRef
+	if oldRef.Name != "" && (newRef.Name != oldRef.Name || newRef.Namespace != oldRef.Namespace) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("database", "dbConfigRef"), "field is immutable"))
+	}
+
+	return allErrs
+}
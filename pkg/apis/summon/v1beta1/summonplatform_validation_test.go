@@ -0,0 +1,119 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+var _ = Describe("SummonPlatform validation", func() {
+	var instance *summonv1beta1.SummonPlatform
+
+	BeforeEach(func() {
+		instance = &summonv1beta1.SummonPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
+			Spec: summonv1beta1.SummonPlatformSpec{
+				Version: "1.2.3",
+			},
+		}
+	})
+
+	It("fills in the persistent defaults", func() {
+		instance.Default()
+		Expect(instance.Spec.Environment).To(Equal("dev"))
+		Expect(instance.Spec.Hostname).To(Equal("foo-dev.ridecell.us"))
+		Expect(instance.Spec.PullSecret).To(Equal("pull-secret"))
+		Expect(instance.Spec.MockTenantHardwareType).To(Equal("OTAKEYS"))
+	})
+
+	It("accepts a valid spec", func() {
+		instance.Default()
+		Expect(instance.ValidateCreate()).To(BeEmpty())
+	})
+
+	It("requires exactly one of version or autoDeploy", func() {
+		instance.Spec.Version = ""
+		Expect(instance.ValidateCreate().ToAggregate().Error()).To(ContainSubstring("spec.version"))
+		instance.Spec.Version = "1.2.3"
+		instance.Spec.AutoDeploy = "master"
+		Expect(instance.ValidateCreate().ToAggregate().Error()).To(ContainSubstring("spec.autoDeploy"))
+	})
+
+	It("rejects invalid celerybeat replicas", func() {
+		replicas := int32(2)
+		instance.Spec.Replicas.CeleryBeat = &replicas
+		Expect(instance.ValidateCreate().ToAggregate().Error()).To(ContainSubstring("spec.replicas.celeryBeat"))
+	})
+
+	It("rejects an unknown mock tenant hardware type", func() {
+		instance.Spec.MockTenantHardwareType = "FOO"
+		Expect(instance.ValidateCreate().ToAggregate().Error()).To(ContainSubstring("spec.mockTenantHardwareType"))
+	})
+
+	It("rejects an unknown rollout strategy and bad canary traffic", func() {
+		instance.Spec.Rollout.Strategy = "BlueGreen"
+		instance.Spec.Rollout.Canary.TrafficPercent = 150
+		errs := instance.ValidateCreate()
+		Expect(errs).To(HaveLen(2))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.rollout.strategy"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.rollout.canary.trafficPercent"))
+	})
+
+	It("rejects invalid maintenance windows", func() {
+		instance.Spec.MaintenanceWindows = []summonv1beta1.MaintenanceWindow{
+			{Window: "Sun:07:00-Sun:09:00", Timezone: "America/Los_Angeles"},
+			{Window: "25:00-26:00"},
+		}
+		errs := instance.ValidateCreate()
+		Expect(errs).To(HaveLen(1))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.maintenanceWindows[1]"))
+	})
+
+	It("rejects bad hostnames and duplicate aliases", func() {
+		instance.Spec.Hostname = "Foo_Bar.ridecell.us"
+		instance.Spec.Aliases = []string{"foo.ridecell.com", "foo.ridecell.com"}
+		errs := instance.ValidateCreate()
+		Expect(errs).To(HaveLen(2))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.hostname"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.aliases[1]"))
+	})
+
+	It("rejects changes to immutable fields", func() {
+		instance.Default()
+		instance.Spec.Database.DbConfigRef = corev1.ObjectReference{Name: "summon-dev"}
+		old := instance.DeepCopy()
+		Expect(instance.ValidateUpdate(old)).To(BeEmpty())
+
+		instance.Spec.Environment = "prod"
+		instance.Spec.Database.DbConfigRef.Name = "other"
+		errs := instance.ValidateUpdate(old)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.environment"))
+		Expect(errs.ToAggregate().Error()).To(ContainSubstring("spec.database.dbConfigRef"))
+	})
+
+	It("allows setting an environment on objects created before defaulting", func() {
+		old := instance.DeepCopy()
+		instance.Spec.Environment = "dev"
+		Expect(instance.ValidateUpdate(old)).To(BeEmpty())
+	})
+})
@@ -17,11 +17,32 @@ limitations under the License.
 package v1beta1
 
 const (
-	StatusInitializing    = "Initializing"
-	StatusMigrating       = "Migrating"
-	StatusCreatingBackup  = "CreatingBackup"
-	StatusDeploying       = "Deploying"
-	StatusReady           = "Ready"
-	StatusError           = "Error"
-	StatusPostMigrateWait = "PostMigrateWait"
+	StatusInitializing     = "Initializing"
+	StatusMigrating        = "Migrating"
+	StatusCreatingBackup   = "CreatingBackup"
+	StatusDeploying        = "Deploying"
+	StatusReady            = "Ready"
+	StatusError            = "Error"
+	StatusPostMigrateWait  = "PostMigrateWait"
+	StatusAwaitingApproval = "AwaitingApproval"
+	StatusWaitingForWindow = "WaitingForWindow"
+)
+
+// Rollout strategies and canary phases.
+const (
+	RolloutStrategyCanary = "Canary"
+
+	CanaryProgressing = "Progressing"
+	CanaryPromoted    = "Promoted"
+	CanaryAborted     = "Aborted"
+)
+
+// Component-level condition types reported on SummonPlatform, in addition to the standard Ready, Progressing, and Degraded.
+const (
+	ConditionDatabaseReady      = "DatabaseReady"
+	ConditionBackupReady        = "BackupReady"
+	ConditionMigrationsComplete = "MigrationsComplete"
+	ConditionDeploymentsReady   = "DeploymentsReady"
+	// False while a version change is held for the next maintenance window.
+	ConditionMaintenanceWindowOpen = "MaintenanceWindowOpen"
 )
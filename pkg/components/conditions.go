@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+)
+
+// SetCondition sets a condition on a top-level object, if it supports them. This is intended to be
+// called from inside a StatusModifier so the change is replayed correctly on write collisions.
+func SetCondition(obj runtime.Object, conditionType string, status conditions.ConditionStatus, reason, message string) {
+	top, ok := obj.(ConditionStatuser)
+	if !ok {
+		return
+	}
+	var generation int64
+	if meta, ok := obj.(metav1.Object); ok {
+		generation = meta.GetGeneration()
+	}
+	top.SetConditions(conditions.Set(top.GetConditions(), conditions.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	}))
+}
+
+// Returns a StatusModifier which recomputes the core Ready/Progressing/Degraded conditions from
+// the result of a reconcile and the component-level conditions already on the object.
+func coreConditionsModifier(reconcileErr error, blocked []string) StatusModifier {
+	return func(obj runtime.Object) error {
+		top, ok := obj.(ConditionStatuser)
+		if !ok {
+			return nil
+		}
+		if reconcileErr != nil {
+			msg := reconcileErr.Error()
+			degradedMsg := msg
+			if len(blocked) > 0 {
+				degradedMsg = fmt.Sprintf("%s (blocked: %s)", msg, strings.Join(blocked, ", "))
+			}
+			SetCondition(obj, conditions.TypeDegraded, conditions.ConditionTrue, conditions.ReasonReconcileError, degradedMsg)
+			SetCondition(obj, conditions.TypeProgressing, conditions.ConditionFalse, conditions.ReasonReconcileError, msg)
+			SetCondition(obj, conditions.TypeReady, conditions.ConditionFalse, conditions.ReasonReconcileError, msg)
+			return nil
+		}
+
+		SetCondition(obj, conditions.TypeDegraded, conditions.ConditionFalse, conditions.ReasonReconcileSuccess, "")
+		// Most objects don't set component-level conditions, for those a clean pass only means the
+		// cloud side was poked so go by the .Status.Status their components maintain instead.
+		status, hasStatus := StatusValue(obj)
+		if hasStatus && status != "" && !isReadyStatus(status) && !conditions.HasComponentConditions(top.GetConditions()) {
+			msg := fmt.Sprintf("status is %s", status)
+			SetCondition(obj, conditions.TypeProgressing, conditions.ConditionTrue, conditions.ReasonStatusNotReady, msg)
+			SetCondition(obj, conditions.TypeReady, conditions.ConditionFalse, conditions.ReasonStatusNotReady, msg)
+			return nil
+		}
+		pending := conditions.Pending(top.GetConditions())
+		if len(pending) == 0 {
+			SetCondition(obj, conditions.TypeProgressing, conditions.ConditionFalse, conditions.ReasonComponentsReady, "")
+			SetCondition(obj, conditions.TypeReady, conditions.ConditionTrue, conditions.ReasonComponentsReady, "")
+		} else {
+			msg := conditions.PendingMessage(pending)
+			SetCondition(obj, conditions.TypeProgressing, conditions.ConditionTrue, conditions.ReasonComponentsPending, msg)
+			SetCondition(obj, conditions.TypeReady, conditions.ConditionFalse, conditions.ReasonComponentsPending, msg)
+		}
+		return nil
+	}
+}
+
+// The .Status.Status values controllers use once an object has converged.
+func isReadyStatus(status string) bool {
+	return status == "Ready" || status == "Success"
+}
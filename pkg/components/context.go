@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -68,17 +69,40 @@ func (ctx *ComponentContext) CreateOrUpdate(path string, extraData map[string]in
 		return Result{Requeue: true}, op, err
 	}
 
+	switch op {
+	case controllerutil.OperationResultCreated:
+		ctx.objectEvent("Created", target)
+	case controllerutil.OperationResultUpdated:
+		ctx.objectEvent("Updated", target)
+	}
+
 	return Result{}, op, nil
 }
 
 // Make a copy of a context with new templates. Used mostly for shared components.
 func (ctx *ComponentContext) WithTemplates(templates http.FileSystem) *ComponentContext {
 	return &ComponentContext{
-		Client:    ctx.Client,
-		templates: templates,
-		Context:   ctx.Context,
-		Top:       ctx.Top,
-		Scheme:    ctx.Scheme,
+		Client:       ctx.Client,
+		templates:    templates,
+		Context:      ctx.Context,
+		Top:          ctx.Top,
+		Scheme:       ctx.Scheme,
+		Recorder:     ctx.Recorder,
+		eventLimiter: ctx.eventLimiter,
+	}
+}
+
+// Make a copy of a context with a different top object, used to render templates from a modified copy
+// of the real object. The copy should keep the same identity so owner references still line up.
+func (ctx *ComponentContext) WithTop(top runtime.Object) *ComponentContext {
+	return &ComponentContext{
+		Client:       ctx.Client,
+		templates:    ctx.templates,
+		Context:      ctx.Context,
+		Top:          top,
+		Scheme:       ctx.Scheme,
+		Recorder:     ctx.Recorder,
+		eventLimiter: ctx.eventLimiter,
 	}
 }
 
@@ -86,10 +110,12 @@ func (ctx *ComponentContext) WithTemplates(templates http.FileSystem) *Component
 func NewTestContext(top runtime.Object, templates http.FileSystem) *ComponentContext {
 	// This method is ugly and I don't like it. I should rebuild this whole subsytem around interfaces and have an explicit fake for it.
 	return &ComponentContext{
-		Top:       top,
-		Client:    fake.NewFakeClient(top),
-		Scheme:    scheme.Scheme,
-		templates: templates,
+		Top:          top,
+		Client:       fake.NewFakeClient(top),
+		Scheme:       scheme.Scheme,
+		templates:    templates,
+		Recorder:     record.NewFakeRecorder(100),
+		eventLimiter: newEventLimiter(),
 	}
 }
 
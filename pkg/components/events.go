@@ -0,0 +1,111 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// How long an identical event for the same object is suppressed for. Reconciles run far more often
+// than anything interesting happens, so without this every requeue would re-post the same event.
+const eventRateLimitWindow = 5 * time.Minute
+
+// Only sweep out stale entries once the limiter gets this big.
+const eventRateLimitSweepSize = 1000
+
+type eventKey struct {
+	uid       string
+	eventType string
+	reason    string
+	message   string
+}
+
+// A small rate limiter for events, shared by all reconciles of a single controller.
+type eventLimiter struct {
+	mutex sync.Mutex
+	last  map[eventKey]time.Time
+	now   func() time.Time
+}
+
+func newEventLimiter() *eventLimiter {
+	return &eventLimiter{last: map[eventKey]time.Time{}, now: time.Now}
+}
+
+// Returns true if the event should be sent, and records that it was.
+func (l *eventLimiter) allow(key eventKey) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	last, ok := l.last[key]
+	if ok && now.Sub(last) < eventRateLimitWindow {
+		return false
+	}
+	if len(l.last) >= eventRateLimitSweepSize {
+		for k, t := range l.last {
+			if now.Sub(t) >= eventRateLimitWindow {
+				delete(l.last, k)
+			}
+		}
+	}
+	l.last[key] = now
+	return true
+}
+
+// Eventf records an event on the top object. eventType should be corev1.EventTypeNormal or corev1.EventTypeWarning.
+// Identical events for the same object are rate limited.
+func (ctx *ComponentContext) Eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if ctx.Recorder == nil {
+		return
+	}
+	message := fmt.Sprintf(messageFmt, args...)
+	if ctx.eventLimiter != nil {
+		var uid string
+		if meta, ok := ctx.Top.(metav1.Object); ok {
+			uid = string(meta.GetUID())
+			if uid == "" {
+				// Mostly for tests, fall back to the name.
+				uid = meta.GetNamespace() + "/" + meta.GetName()
+			}
+		}
+		if !ctx.eventLimiter.allow(eventKey{uid: uid, eventType: eventType, reason: reason, message: message}) {
+			return
+		}
+	}
+	ctx.Recorder.Event(ctx.Top, eventType, reason, message)
+}
+
+// Event helper for reporting a successful change to a child object, such as "Created Deployment foo-web".
+func (ctx *ComponentContext) objectEvent(reason string, obj runtime.Object) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		// Typed objects usually don't have TypeMeta filled in, so use the Go type name.
+		kind = fmt.Sprintf("%T", obj)
+		kind = kind[strings.LastIndex(kind, ".")+1:]
+	}
+	name := ""
+	if meta, ok := obj.(metav1.Object); ok {
+		name = meta.GetName()
+	}
+	ctx.Eventf(corev1.EventTypeNormal, reason, "%s %s %s", reason, kind, name)
+}
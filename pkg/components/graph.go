@@ -0,0 +1,142 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The dependency graph (a DAG) for the components of a reconciler.
+type componentGraph struct {
+	components []Component
+	// Indexes of the components each component depends on.
+	deps [][]int
+	// Component indexes grouped into levels. Each level only depends on earlier levels, so
+	// everything inside a level can be reconciled concurrently. Indexes within a level are sorted.
+	levels [][]int
+}
+
+func newComponentGraph(comps []Component) (*componentGraph, error) {
+	g := &componentGraph{
+		components: comps,
+		deps:       make([][]int, len(comps)),
+	}
+
+	for i, comp := range comps {
+		depComp, ok := comp.(DependentComponent)
+		if !ok {
+			// No declared dependencies, so preserve the list ordering by depending on everything before this.
+			for j := 0; j < i; j++ {
+				g.deps[i] = append(g.deps[i], j)
+			}
+			continue
+		}
+		depTypes := map[reflect.Type]bool{}
+		for _, dep := range depComp.DependsOn() {
+			depTypes[reflect.TypeOf(dep)] = true
+		}
+		for j, other := range comps {
+			if j != i && depTypes[reflect.TypeOf(other)] {
+				g.deps[i] = append(g.deps[i], j)
+			}
+		}
+	}
+
+	// Assign each component to a level one past its deepest dependency.
+	level := make([]int, len(comps))
+	done := make([]bool, len(comps))
+	remaining := len(comps)
+	for remaining > 0 {
+		progress := false
+		for i := range comps {
+			if done[i] {
+				continue
+			}
+			ready := true
+			level[i] = 0
+			for _, dep := range g.deps[i] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+				if level[dep]+1 > level[i] {
+					level[i] = level[dep] + 1
+				}
+			}
+			if !ready {
+				continue
+			}
+			done[i] = true
+			remaining--
+			progress = true
+			for len(g.levels) <= level[i] {
+				g.levels = append(g.levels, []int{})
+			}
+			g.levels[level[i]] = append(g.levels[level[i]], i)
+		}
+		if !progress {
+			stuck := []string{}
+			for i := range comps {
+				if !done[i] {
+					stuck = append(stuck, componentName(comps[i]))
+				}
+			}
+			return nil, errors.Errorf("dependency cycle between components: %s", strings.Join(stuck, ", "))
+		}
+	}
+	for _, lvl := range g.levels {
+		sort.Ints(lvl)
+	}
+
+	return g, nil
+}
+
+// Returns the index of a dependency of the given component which is in the failed set, or -1 if there is none.
+func (g *componentGraph) failedDependency(i int, failed []bool) int {
+	for _, dep := range g.deps[i] {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return -1
+}
+
+// A short, human readable name for a component to use in logs and status messages.
+func componentName(comp Component) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", comp), "*")
+}
+
+// DependencyLevels groups components into the levels they are reconciled in. Everything in a level runs
+// concurrently, and a level only starts once every earlier level has finished.
+func DependencyLevels(comps []Component) ([][]Component, error) {
+	g, err := newComponentGraph(comps)
+	if err != nil {
+		return nil, err
+	}
+	levels := make([][]Component, len(g.levels))
+	for n, level := range g.levels {
+		for _, i := range level {
+			levels[n] = append(levels[n], comps[i])
+		}
+	}
+	return levels, nil
+}
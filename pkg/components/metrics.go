@@ -0,0 +1,64 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+// Record the requeue and status metrics for a finished reconcile.
+func (cr *componentReconciler) observeResult(request reconcile.Request, ctx *ComponentContext, result reconcile.Result, err error) {
+	if err != nil {
+		metrics.Requeues.WithLabelValues(cr.name, "error").Inc()
+	} else if result.Requeue {
+		metrics.Requeues.WithLabelValues(cr.name, "immediate").Inc()
+	} else if result.RequeueAfter != 0 {
+		metrics.Requeues.WithLabelValues(cr.name, "delayed").Inc()
+	}
+
+	if status, ok := StatusValue(ctx.Top); ok {
+		cr.statuses.Set(request.NamespacedName.String(), status)
+	}
+}
+
+// StatusValue returns the .Status.Status string of an object, if it has one.
+func StatusValue(obj runtime.Object) (string, bool) {
+	statuser, ok := obj.(Statuser)
+	if !ok {
+		return "", false
+	}
+	val := reflect.ValueOf(statuser.GetStatus())
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", false
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return "", false
+	}
+	field := val.FieldByName("Status")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return "", false
+	}
+	return field.String(), true
+}
@@ -21,6 +21,9 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -38,15 +41,26 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 func NewReconciler(name string, mgr manager.Manager, top runtime.Object, templates http.FileSystem, components []Component) (*componentReconciler, error) {
+	graph, err := newComponentGraph(components)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to build component graph for %s", name)
+	}
+
 	cr := &componentReconciler{
-		name:       name,
-		top:        top,
-		templates:  templates,
-		components: components,
-		manager:    mgr,
+		name:         name,
+		top:          top,
+		templates:    templates,
+		components:   components,
+		graph:        graph,
+		manager:      mgr,
+		recorder:     mgr.GetRecorder(name),
+		eventLimiter: newEventLimiter(),
+		statuses:     metrics.NewStatusTracker(name),
 	}
 
 	// Create the controller.
@@ -132,9 +146,11 @@ func (cr *componentReconciler) newContext(request reconcile.Request) (*Component
 	}
 
 	ctx := &ComponentContext{
-		templates: cr.templates,
-		Context:   reqCtx,
-		Top:       top,
+		templates:    cr.templates,
+		Context:      reqCtx,
+		Top:          top,
+		Recorder:     cr.recorder,
+		eventLimiter: cr.eventLimiter,
 	}
 	err = cr.manager.SetFields(ctx)
 	if err != nil {
@@ -145,15 +161,21 @@ func (cr *componentReconciler) newContext(request reconcile.Request) (*Component
 
 func (cr *componentReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 	glog.Infof("[%s] %s: Reconciling!", request.NamespacedName, cr.name)
+	start := time.Now()
+	defer func() {
+		metrics.ReconcileDuration.WithLabelValues(cr.name).Observe(time.Since(start).Seconds())
+	}()
 
 	// Build a reconciler context to pass around.
 	ctx, err := cr.newContext(request)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
 			// Top object not found, likely already deleted.
+			cr.statuses.Delete(request.NamespacedName.String())
 			return reconcile.Result{}, nil
 		}
 		// Some other fetch error, try again on the next tick.
+		metrics.Requeues.WithLabelValues(cr.name, "error").Inc()
 		return reconcile.Result{Requeue: true}, err
 	}
 
@@ -171,30 +193,37 @@ func (cr *componentReconciler) Reconcile(request reconcile.Request) (reconcile.R
 	}
 
 	// Reconcile all the components.
-	// start := time.Now()
 	result, err := cr.reconcileComponents(ctx)
-	// fmt.Printf("$$$ Reconcile took %s\n", time.Since(start))
 	if err != nil {
-		// fmt.Printf("@@@@ Reconcile error %v\n", err)
 		if _, ok := interface{}(ctx.Top).(Statuser); ok {
 			ctx.Top.(Statuser).SetErrorStatus(err.Error())
 		}
 	}
 
+	// Recompute the standard conditions. The modifier is kept so it gets replayed in case of a write collision.
+	if _, ok := ctx.Top.(ConditionStatuser); ok {
+		conditionsModifier := coreConditionsModifier(err, result.blocked)
+		// Linting ignored "Error not handled", this modifier never returns an error.
+		conditionsModifier(ctx.Top) //nolint
+		result.statusModifiers = append(result.statusModifiers, conditionsModifier)
+	}
+
 	// Check if Object has Status methods
 	if _, ok := interface{}(ctx.Top).(Statuser); ok {
 		// Check if an update to the status subresource is required.
 		if !reflect.DeepEqual(ctx.Top.(Statuser).GetStatus(), cleanTop.(Statuser).GetStatus()) {
 			// Update the top object status.
 			glog.V(2).Infof("[%s] Reconcile: Updating Status\n", request.NamespacedName)
-			err = cr.modifyStatus(ctx, result.statusModifiers)
-			if err != nil {
+			statusErr := cr.modifyStatus(ctx, result.statusModifiers)
+			if statusErr != nil {
 				result.result.Requeue = true
-				return result.result, err
+				cr.observeResult(request, ctx, result.result, statusErr)
+				return result.result, statusErr
 			}
 		}
 	}
 
+	cr.observeResult(request, ctx, result.result, err)
 	return result.result, nil
 }
 
@@ -208,6 +237,8 @@ type reconcilerResults struct {
 	statusModifiers []StatusModifier
 	// The most recent error.
 	err error
+	// Names of components skipped because something they depend on failed.
+	blocked []string
 }
 
 func (r *reconcilerResults) mergeResult(componentResult Result, component Component, err error) error {
@@ -238,41 +269,89 @@ func (r *reconcilerResults) mergeResult(componentResult Result, component Compon
 
 func (cr *componentReconciler) reconcileComponents(ctx *ComponentContext) (*reconcilerResults, error) {
 	instance := ctx.Top.(metav1.Object)
+	reconcilable := make([]bool, len(cr.components))
 	ready := []Component{}
-	for _, component := range cr.components {
+	for i, component := range cr.components {
 		glog.V(10).Infof("[%s/%s] reconcileComponents: Checking if %#v is available to reconcile", instance.GetNamespace(), instance.GetName(), component)
 		if component.IsReconcilable(ctx) {
 			glog.V(9).Infof("[%s/%s] reconcileComponents: %#v is available to reconcile", instance.GetNamespace(), instance.GetName(), component)
+			reconcilable[i] = true
 			ready = append(ready, component)
 		}
 	}
+
 	res := &reconcilerResults{ctx: ctx}
-	for _, component := range ready {
-		// fmt.Printf("### Reconciling %#v\n", component)
-		// start := time.Now()
-		innerRes, err := component.Reconcile(ctx)
-		// fmt.Printf("### Done reconciling %#v, took %s\n", component, time.Since(start))
-		// Update result. This should be checked before the err!=nil because sometimes
-		// we want to requeue immediately on error.
-		err = res.mergeResult(innerRes, component, err)
-		if err != nil {
-			for _, errComponent := range ready {
-				errReconciler, ok := errComponent.(ErrorHandler)
-				if !ok {
-					// Not an error handler, push on.
-					continue
-				}
-				innerRes, errorErr := errReconciler.ReconcileError(ctx, err)
-				// Linting ignored "Error not handled", not an error that needs to be handled.
-				res.mergeResult(innerRes, errComponent, nil) //nolint
-				if errorErr != nil {
-					// Can't really do much more than log it, sigh. Some day this should set a prometheus metric.
-					glog.Errorf("[%s/%s] Error running error handler %#v: %s", instance.GetNamespace(), instance.GetName(), errComponent, errorErr)
+	// Components which either errored or were blocked by an upstream error.
+	failed := make([]bool, len(cr.components))
+	var firstErr error
+	for _, level := range cr.graph.levels {
+		toRun := []int{}
+		for _, i := range level {
+			if !reconcilable[i] {
+				continue
+			}
+			if dep := cr.graph.failedDependency(i, failed); dep != -1 {
+				// Something upstream failed, skip this whole branch.
+				failed[i] = true
+				res.blocked = append(res.blocked, componentName(cr.components[i]))
+				glog.V(2).Infof("[%s/%s] reconcileComponents: %#v blocked by failed dependency %#v", instance.GetNamespace(), instance.GetName(), cr.components[i], cr.components[dep])
+				continue
+			}
+			toRun = append(toRun, i)
+		}
+
+		// Everything in a level is independent, so run them all concurrently.
+		results := make([]Result, len(toRun))
+		errs := make([]error, len(toRun))
+		var wg sync.WaitGroup
+		for n, i := range toRun {
+			wg.Add(1)
+			go func(n int, component Component) {
+				defer wg.Done()
+				componentStart := time.Now()
+				results[n], errs[n] = component.Reconcile(ctx)
+				metrics.ComponentDuration.WithLabelValues(cr.name, componentName(component)).Observe(time.Since(componentStart).Seconds())
+			}(n, cr.components[i])
+		}
+		wg.Wait()
+
+		// Merge results in list order so status modifiers are applied deterministically.
+		for n, i := range toRun {
+			// Update result. This should be checked before the err!=nil because sometimes
+			// we want to requeue immediately on error.
+			err := res.mergeResult(results[n], cr.components[i], errs[n])
+			if err != nil {
+				metrics.ComponentErrors.WithLabelValues(cr.name, componentName(cr.components[i])).Inc()
+				ctx.Eventf(corev1.EventTypeWarning, "ReconcileError", "Error reconciling %s: %s", componentName(cr.components[i]), err)
+				failed[i] = true
+				if firstErr == nil {
+					firstErr = err
 				}
 			}
-			return res, err
 		}
 	}
+
+	if firstErr != nil {
+		if len(res.blocked) > 0 {
+			glog.Errorf("[%s/%s] reconcileComponents: %d components blocked by errors: %s", instance.GetNamespace(), instance.GetName(), len(res.blocked), strings.Join(res.blocked, ", "))
+		}
+		for _, errComponent := range ready {
+			errReconciler, ok := errComponent.(ErrorHandler)
+			if !ok {
+				// Not an error handler, push on.
+				continue
+			}
+			innerRes, errorErr := errReconciler.ReconcileError(ctx, firstErr)
+			// Linting ignored "Error not handled", not an error that needs to be handled.
+			res.mergeResult(innerRes, errComponent, nil) //nolint
+			if errorErr != nil {
+				// Can't really do much more than log it and count it, sigh.
+				metrics.ComponentErrors.WithLabelValues(cr.name, componentName(errComponent)).Inc()
+				glog.Errorf("[%s/%s] Error running error handler %#v: %s", instance.GetNamespace(), instance.GetName(), errComponent, errorErr)
+			}
+		}
+		return res, firstErr
+	}
 	return res, nil
 }
 
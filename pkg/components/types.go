@@ -22,11 +22,15 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 // // A componentReconciler is the data for a single reconciler. These are our
@@ -36,9 +40,15 @@ type componentReconciler struct {
 	top        runtime.Object
 	templates  http.FileSystem
 	components []Component
+	graph      *componentGraph
 	client     client.Client
 	manager    manager.Manager
 	Controller controller.Controller
+	// Event recorder and rate limiter shared by all reconciles.
+	recorder     record.EventRecorder
+	eventLimiter *eventLimiter
+	// Tracks the current status of each object for the objects-by-status gauge.
+	statuses *metrics.StatusTracker
 }
 
 // A ComponentContext is the state for a single reconcile request to the controller.
@@ -48,6 +58,9 @@ type ComponentContext struct {
 	Context   context.Context // This should probably go away
 	Top       runtime.Object
 	Scheme    *runtime.Scheme
+	// Recorder for Kubernetes Events on the Top object. Prefer ctx.Eventf, which is rate limited.
+	Recorder     record.EventRecorder
+	eventLimiter *eventLimiter
 }
 
 // A function which modifies component status.
@@ -80,6 +93,14 @@ type MapFuncWatcher interface {
 	WatchMap(handler.MapObject, client.Client) ([]reconcile.Request, error)
 }
 
+// An optional interface for Components which declare exactly which other components they depend on. Dependencies
+// are matched by type, so depending on a component type waits on every instance of it in the reconciler. Components
+// without this interface depend on everything before them in the list. Components with declared dependencies may
+// be reconciled concurrently with others, so they must only modify the top object through a StatusModifier.
+type DependentComponent interface {
+	DependsOn() []Component
+}
+
 // Opaque type for some kind of status substruct.
 type Status interface{}
 
@@ -89,3 +110,10 @@ type Statuser interface {
 	SetStatus(Status)
 	SetErrorStatus(string)
 }
+
+// Optional interface for top-level objects which expose standard status conditions. The reconciler
+// maintains the Ready, Progressing, and Degraded conditions automatically for these objects.
+type ConditionStatuser interface {
+	GetConditions() []conditions.Condition
+	SetConditions([]conditions.Condition)
+}
@@ -28,6 +28,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 type defaultsComponent struct {
@@ -35,7 +36,7 @@ type defaultsComponent struct {
 }
 
 func NewDefaults() *defaultsComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	rdsService := rds.New(sess)
 	return &defaultsComponent{rdsAPI: rdsService}
 }
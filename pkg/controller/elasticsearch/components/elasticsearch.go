@@ -35,6 +35,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -46,7 +47,7 @@ type elasticSearchComponent struct {
 }
 
 func NewElasticSearch() *elasticSearchComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	esService := es.New(sess)
 	iamService := iam.New(sess)
 	return &elasticSearchComponent{esAPI: esService, iamAPI: iamService}
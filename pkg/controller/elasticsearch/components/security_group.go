@@ -31,6 +31,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 const elasticSearchSecurityGroupFinalizer = "elasticsearch.securitygroup.finalizer"
@@ -40,7 +41,7 @@ type esSecurityGroupComponent struct {
 }
 
 func NewESSecurityGroup() *esSecurityGroupComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	ec2Service := ec2.New(sess)
 	return &esSecurityGroupComponent{ec2API: ec2Service}
 }
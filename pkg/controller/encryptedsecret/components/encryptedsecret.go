@@ -20,12 +20,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/gob"
+	"log"
+	"os"
 	"strings"
+	"sync"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/nacl/secretbox"
@@ -44,22 +44,51 @@ type payload struct {
 }
 
 type EncryptedSecretComponent struct {
-	kmsAPI kmsiface.KMSAPI
+	newKMSAPI   func(string) kmsiface.KMSAPI
+	kmsAPIs     map[string]kmsiface.KMSAPI
+	kmsAPIsLock sync.Mutex
+	gcpKMS      GCPKMSDecrypter
 }
 
+// InjectKMSAPI makes every AWS region use the given client.
 func (comp *EncryptedSecretComponent) InjectKMSAPI(kmsapi kmsiface.KMSAPI) {
-	comp.kmsAPI = kmsapi
+	comp.kmsAPIsLock.Lock()
+	defer comp.kmsAPIsLock.Unlock()
+	comp.newKMSAPI = func(_ string) kmsiface.KMSAPI { return kmsapi }
+	comp.kmsAPIs = map[string]kmsiface.KMSAPI{}
+}
+
+func (comp *EncryptedSecretComponent) InjectGCPKMS(gcpKMS GCPKMSDecrypter) {
+	comp.gcpKMS = gcpKMS
 }
 
 func NewEncryptedSecret() *EncryptedSecretComponent {
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-		Config: aws.Config{
-			Region: aws.String("us-west-1"),
-		},
-	}))
-	kmsService := kms.New(sess)
-	return &EncryptedSecretComponent{kmsAPI: kmsService}
+	var gcpKMS GCPKMSDecrypter
+	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+		var err error
+		gcpKMS, err = newRealGCPKMSDecrypter()
+		if err != nil {
+			// We need better handling of this, so far we haven't have components that can fail to create.
+			log.Fatal(err)
+		}
+	}
+	return &EncryptedSecretComponent{
+		newKMSAPI: newAWSKMSAPI,
+		kmsAPIs:   map[string]kmsiface.KMSAPI{},
+		gcpKMS:    gcpKMS,
+	}
+}
+
+// Get the KMS client for a region, creating it on first use.
+func (comp *EncryptedSecretComponent) getKMSAPI(region string) kmsiface.KMSAPI {
+	comp.kmsAPIsLock.Lock()
+	defer comp.kmsAPIsLock.Unlock()
+	kmsAPI, ok := comp.kmsAPIs[region]
+	if !ok {
+		kmsAPI = comp.newKMSAPI(region)
+		comp.kmsAPIs[region] = kmsAPI
+	}
+	return kmsAPI
 }
 
 func (_ *EncryptedSecretComponent) WatchTypes() []runtime.Object {
@@ -84,7 +113,12 @@ func (comp *EncryptedSecretComponent) Reconcile(ctx *components.ComponentContext
 		Data: make(map[string][]byte),
 	}
 
-	// Key map for holding plainDataKey to avoid repetative KMS decrypt calls for single cipherDataKey
+	provider, err := comp.newKeyProvider(ctx, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
+	// Key map for holding plainDataKey to avoid repetative decrypt calls for single cipherDataKey
 	keyMap := map[string]*[32]byte{}
 
 	for k, v := range instance.Data {
@@ -101,64 +135,48 @@ func (comp *EncryptedSecretComponent) Reconcile(ctx *components.ComponentContext
 
 		decodedValue, err := base64.StdEncoding.DecodeString(v)
 		if err != nil {
-			return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to base64 decode secret")
+			return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to base64 decode secret[%s]", k)
 		}
 
+		var plaintext []byte
 		if useDataKey {
 			var p payload
 			err = gob.NewDecoder(bytes.NewReader(decodedValue)).Decode(&p)
 			if err != nil {
-				return components.Result{}, errors.Wrapf(err, "error decoding value for payload")
+				return components.Result{}, errors.Wrapf(err, "encryptedsecret: error decoding payload for secret[%s]", k)
 			}
 			plainDataKey, ok := keyMap[string(p.Key)]
 			if !ok {
 				// Decrypt cipherdatakey
-				decryptedValue, err := comp.kmsAPI.Decrypt(&kms.DecryptInput{
-					CiphertextBlob: p.Key,
-					EncryptionContext: map[string]*string{
-						"RidecellOperator": aws.String("true"),
-					},
-				})
+				decryptedKey, err := provider.Decrypt(p.Key)
 				if err != nil {
-					return components.Result{}, errors.Wrapf(err, "error decrypting value for cipherDatakey")
+					return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to decrypt data key for secret[%s] with %s", k, provider)
 				}
 				plainDataKey = &[32]byte{}
-				copy(plainDataKey[:], decryptedValue.Plaintext)
+				copy(plainDataKey[:], decryptedKey)
 				keyMap[string(p.Key)] = plainDataKey
 			}
 			// Decrypt message
-			var plaintext []byte
 			plaintext, ok = secretbox.Open(plaintext, p.Message, p.Nonce, plainDataKey)
 			if !ok {
-				return components.Result{}, errors.Wrapf(err, "error decrypting value with data key for %s", k)
+				return components.Result{}, errors.Errorf("encryptedsecret: failed to decrypt secret[%s] with data key from %s", k, provider)
 			}
-			if bytes.Equal(plaintext, []byte(secretsv1beta1.EncryptedSecretEmptyKey)) {
-				// Decode the magic value to an empty string.
-				newSecret.Data[k] = []byte{}
-			} else {
-				newSecret.Data[k] = plaintext
+		} else {
+			plaintext, err = provider.Decrypt(decodedValue)
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to decrypt secret[%s] with %s", k, provider)
 			}
-			continue
 		}
 
-		decryptedValue, err := comp.kmsAPI.Decrypt(&kms.DecryptInput{
-			CiphertextBlob: decodedValue,
-			EncryptionContext: map[string]*string{
-				"RidecellOperator": aws.String("true"),
-			},
-		})
-		if err != nil {
-			return components.Result{}, errors.Wrapf(err, "encryptedsecret: failed to decrypt secret")
-		}
-		if bytes.Equal(decryptedValue.Plaintext, []byte(secretsv1beta1.EncryptedSecretEmptyKey)) {
+		if bytes.Equal(plaintext, []byte(secretsv1beta1.EncryptedSecretEmptyKey)) {
 			// Decode the magic value to an empty string.
 			newSecret.Data[k] = []byte{}
 		} else {
-			newSecret.Data[k] = decryptedValue.Plaintext
+			newSecret.Data[k] = plaintext
 		}
 	}
 
-	_, err := controllerutil.CreateOrUpdate(ctx.Context, ctx, newSecret.DeepCopy(), func(existingObj runtime.Object) error {
+	_, err = controllerutil.CreateOrUpdate(ctx.Context, ctx, newSecret.DeepCopy(), func(existingObj runtime.Object) error {
 		existing := existingObj.(*corev1.Secret)
 		// Sync important fields.
 		err := controllerutil.SetControllerReference(instance, existing, ctx.Scheme)
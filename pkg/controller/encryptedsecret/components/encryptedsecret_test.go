@@ -17,19 +17,28 @@ limitations under the License.
 package components_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
 	"strings"
 
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/googleapi"
 	"k8s.io/apimachinery/pkg/types"
 
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	encryptedsecretcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/encryptedsecret/components"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,14 +46,24 @@ import (
 
 type mockKMSClient struct {
 	kmsiface.KMSAPI
+	encryptionContext map[string]*string
+}
+
+type mockGCPKMSClient struct {
+	keyName string
+	aad     string
 }
 
 var _ = Describe("encryptedsecret Component", func() {
 	comp := encryptedsecretcomponents.NewEncryptedSecret()
+	var mockKMS *mockKMSClient
+	var mockGCPKMS *mockGCPKMSClient
 
 	BeforeEach(func() {
-		mockKMS := &mockKMSClient{}
+		mockKMS = &mockKMSClient{}
 		comp.InjectKMSAPI(mockKMS)
+		mockGCPKMS = &mockGCPKMSClient{}
+		comp.InjectGCPKMS(mockGCPKMS)
 	})
 
 	It("is reconcilable", func() {
@@ -116,6 +135,199 @@ var _ = Describe("encryptedsecret Component", func() {
 		Expect(string(fetchSecret.Data["TEST_EMPTY_VALUE"])).To(Equal(""))
 	})
 
+	It("names the failing key and provider", func() {
+		instance.Data = map[string]string{
+			// echo -n garbage | base64
+			"TEST_BAD_VALUE": "Z2FyYmFnZQ==",
+		}
+
+		_, err := comp.Reconcile(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("secret[TEST_BAD_VALUE]"))
+		Expect(err.Error()).To(ContainSubstring("aws-kms in us-west-1"))
+	})
+
+	It("rejects more than one key provider", func() {
+		instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+		instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+			AWSKMS: &secretsv1beta1.AWSKMSKeyProvider{},
+			Local:  &secretsv1beta1.LocalKeyProvider{SecretName: "key"},
+		}
+
+		_, err := comp.Reconcile(ctx)
+		Expect(err).To(MatchError("encryptedsecret: only one key provider can be set"))
+	})
+
+	Context("with AWS KMS", func() {
+		It("uses the default encryption context", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockKMS.encryptionContext).To(Equal(map[string]*string{"RidecellOperator": aws.String("true")}))
+		})
+
+		It("uses a custom encryption context", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+			instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+				AWSKMS: &secretsv1beta1.AWSKMSKeyProvider{
+					Region:            "eu-central-1",
+					EncryptionContext: map[string]string{"Tenant": "foo"},
+				},
+			}
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockKMS.encryptionContext).To(Equal(map[string]*string{"Tenant": aws.String("foo")}))
+		})
+
+		It("accepts values encrypted with the expected key", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+			instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+				AWSKMS: &secretsv1beta1.AWSKMSKeyProvider{KeyID: "test-key"},
+			}
+
+			Expect(comp).To(ReconcileContext(ctx))
+		})
+
+		It("rejects values encrypted with a different key", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+			instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+				AWSKMS: &secretsv1beta1.AWSKMSKeyProvider{Region: "eu-central-1", KeyID: "other-key"},
+			}
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("secret[TEST_VALUE0] with aws-kms key other-key in eu-central-1"))
+		})
+	})
+
+	Context("with GCP KMS", func() {
+		BeforeEach(func() {
+			instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+				GCPKMS: &secretsv1beta1.GCPKMSKeyProvider{
+					KeyName:                     "projects/test/locations/global/keyRings/test/cryptoKeys/test",
+					AdditionalAuthenticatedData: "foo",
+				},
+			}
+		})
+
+		It("decrypts values", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "Z2NwdGVzdDA="}
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockGCPKMS.keyName).To(Equal("projects/test/locations/global/keyRings/test/cryptoKeys/test"))
+			Expect(mockGCPKMS.aad).To(Equal("foo"))
+
+			fetchSecret := &corev1.Secret{}
+			err := ctx.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, fetchSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fetchSecret.Data["TEST_VALUE0"])).To(Equal("test0"))
+		})
+
+		It("names the failing key and provider", func() {
+			instance.Data = map[string]string{"TEST_VALUE0": "a21zdGVzdDA="}
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("secret[TEST_VALUE0] with gcp-kms key projects/test/locations/global/keyRings/test/cryptoKeys/test"))
+		})
+
+		It("fails without GCP credentials", func() {
+			comp.InjectGCPKMS(nil)
+			instance.Data = map[string]string{"TEST_VALUE0": "Z2NwdGVzdDA="}
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("GCP credentials are not configured"))
+		})
+	})
+
+	Context("with a local key", func() {
+		var secretKey *[32]byte
+
+		seal := func(plaintext []byte) []byte {
+			nonce := &[24]byte{}
+			_, err := rand.Read(nonce[:])
+			Expect(err).ToNot(HaveOccurred())
+			return secretbox.Seal(nonce[:], plaintext, nonce, secretKey)
+		}
+
+		BeforeEach(func() {
+			secretKey = &[32]byte{}
+			_, err := rand.Read(secretKey[:])
+			Expect(err).ToNot(HaveOccurred())
+			keySecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "dev-key", Namespace: instance.Namespace},
+				Data:       map[string][]byte{"key": secretKey[:]},
+			}
+			err = ctx.Create(context.TODO(), keySecret)
+			Expect(err).ToNot(HaveOccurred())
+			instance.KeyProvider = &secretsv1beta1.EncryptedSecretKeyProvider{
+				Local: &secretsv1beta1.LocalKeyProvider{SecretName: "dev-key"},
+			}
+		})
+
+		It("decrypts values", func() {
+			instance.Data = map[string]string{
+				"TEST_VALUE0":      base64.StdEncoding.EncodeToString(seal([]byte("test0"))),
+				"TEST_EMPTY_VALUE": base64.StdEncoding.EncodeToString(seal([]byte(secretsv1beta1.EncryptedSecretEmptyKey))),
+			}
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			fetchSecret := &corev1.Secret{}
+			err := ctx.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, fetchSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fetchSecret.Data["TEST_VALUE0"])).To(Equal("test0"))
+			Expect(string(fetchSecret.Data["TEST_EMPTY_VALUE"])).To(Equal(""))
+		})
+
+		It("decrypts values with a data key", func() {
+			dataKey := &[32]byte{}
+			_, err := rand.Read(dataKey[:])
+			Expect(err).ToNot(HaveOccurred())
+			nonce := &[24]byte{}
+			_, err = rand.Read(nonce[:])
+			Expect(err).ToNot(HaveOccurred())
+			buf := &bytes.Buffer{}
+			err = gob.NewEncoder(buf).Encode(struct {
+				Key     []byte
+				Nonce   *[24]byte
+				Message []byte
+			}{
+				Key:     seal(dataKey[:]),
+				Nonce:   nonce,
+				Message: secretbox.Seal(nil, []byte("test1"), nonce, dataKey),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			instance.Data = map[string]string{"TEST_VALUE1": "crypto " + base64.StdEncoding.EncodeToString(buf.Bytes())}
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			fetchSecret := &corev1.Secret{}
+			err = ctx.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, fetchSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fetchSecret.Data["TEST_VALUE1"])).To(Equal("test1"))
+		})
+
+		It("names the failing key and provider", func() {
+			otherKey := &[32]byte{}
+			nonce := &[24]byte{}
+			instance.Data = map[string]string{"TEST_VALUE0": base64.StdEncoding.EncodeToString(secretbox.Seal(nonce[:], []byte("test0"), nonce, otherKey))}
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("secret[TEST_VALUE0] with local key dev-key/key"))
+		})
+
+		It("fails if the key secret is missing", func() {
+			instance.KeyProvider.Local.SecretName = "missing"
+			instance.Data = map[string]string{"TEST_VALUE0": base64.StdEncoding.EncodeToString(seal([]byte("test0")))}
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to get local key missing/key"))
+		})
+	})
 })
 
 func (m *mockKMSClient) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
@@ -126,5 +338,23 @@ func (m *mockKMSClient) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, er
 	if !strings.HasPrefix(v, "kms") {
 		return &kms.DecryptOutput{}, awserr.New(kms.ErrCodeInvalidCiphertextException, "awsmock_decrypt: Invalid cipher text", errors.New(""))
 	}
-	return &kms.DecryptOutput{Plaintext: input.CiphertextBlob[3:]}, nil
+	m.encryptionContext = input.EncryptionContext
+	return &kms.DecryptOutput{KeyId: aws.String("arn:aws:kms:us-west-1:123456789012:key/test-key"), Plaintext: input.CiphertextBlob[3:]}, nil
+}
+
+func (m *mockGCPKMSClient) Decrypt(name string, req *cloudkms.DecryptRequest) (*cloudkms.DecryptResponse, error) {
+	m.keyName = name
+	aad, err := base64.StdEncoding.DecodeString(req.AdditionalAuthenticatedData)
+	if err != nil {
+		return nil, err
+	}
+	m.aad = string(aad)
+	ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(string(ciphertext), "gcp") {
+		return nil, &googleapi.Error{Code: 400, Message: "Decryption failed: the ciphertext is invalid."}
+	}
+	return &cloudkms.DecryptResponse{Plaintext: base64.StdEncoding.EncodeToString(ciphertext[3:])}, nil
 }
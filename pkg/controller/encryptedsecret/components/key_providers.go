@@ -0,0 +1,230 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+const defaultAWSKMSRegion = "us-west-1"
+
+// A keyProvider decrypts a single value or data key. The String() form is used in error messages.
+type keyProvider interface {
+	fmt.Stringer
+	Decrypt([]byte) ([]byte, error)
+}
+
+// Interface for a Cloud KMS client to allow for a mock implementation.
+type GCPKMSDecrypter interface {
+	Decrypt(string, *cloudkms.DecryptRequest) (*cloudkms.DecryptResponse, error)
+}
+
+type realGCPKMSDecrypter struct {
+	svc *cloudkms.Service
+}
+
+func newRealGCPKMSDecrypter() (*realGCPKMSDecrypter, error) {
+	client, err := google.DefaultClient(context.Background(), cloudkms.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := cloudkms.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &realGCPKMSDecrypter{svc: svc}, nil
+}
+
+func (r *realGCPKMSDecrypter) Decrypt(name string, req *cloudkms.DecryptRequest) (*cloudkms.DecryptResponse, error) {
+	return r.svc.Projects.Locations.KeyRings.CryptoKeys.Decrypt(name, req).Do()
+}
+
+func newAWSKMSAPI(region string) kmsiface.KMSAPI {
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Config: aws.Config{
+			Region: aws.String(region),
+		},
+	})))
+	return kms.New(sess)
+}
+
+type awsKMSKeyProvider struct {
+	kmsAPI            kmsiface.KMSAPI
+	region            string
+	keyID             string
+	encryptionContext map[string]*string
+}
+
+func (p *awsKMSKeyProvider) String() string {
+	if p.keyID != "" {
+		return fmt.Sprintf("aws-kms key %s in %s", p.keyID, p.region)
+	}
+	return fmt.Sprintf("aws-kms in %s", p.region)
+}
+
+func (p *awsKMSKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	decryptedValue, err := p.kmsAPI.Decrypt(&kms.DecryptInput{
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: p.encryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.keyID != "" {
+		// KMS always returns the key ARN, allow giving just the key ID.
+		keyArn := aws.StringValue(decryptedValue.KeyId)
+		if keyArn != p.keyID && !strings.HasSuffix(keyArn, ":key/"+p.keyID) {
+			return nil, errors.Errorf("value was encrypted with unexpected key %s", keyArn)
+		}
+	}
+	return decryptedValue.Plaintext, nil
+}
+
+type gcpKMSKeyProvider struct {
+	kms                         GCPKMSDecrypter
+	keyName                     string
+	additionalAuthenticatedData string
+}
+
+func (p *gcpKMSKeyProvider) String() string {
+	return fmt.Sprintf("gcp-kms key %s", p.keyName)
+}
+
+func (p *gcpKMSKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	req := &cloudkms.DecryptRequest{Ciphertext: base64.StdEncoding.EncodeToString(ciphertext)}
+	if p.additionalAuthenticatedData != "" {
+		req.AdditionalAuthenticatedData = base64.StdEncoding.EncodeToString([]byte(p.additionalAuthenticatedData))
+	}
+	resp, err := p.kms.Decrypt(p.keyName, req)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+type localKeyProvider struct {
+	secretName string
+	key        string
+	secretKey  *[32]byte
+}
+
+func (p *localKeyProvider) String() string {
+	return fmt.Sprintf("local key %s/%s", p.secretName, p.key)
+}
+
+func (p *localKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 24 {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce := &[24]byte{}
+	copy(nonce[:], ciphertext[:24])
+	plaintext, ok := secretbox.Open(nil, ciphertext[24:], nonce, p.secretKey)
+	if !ok {
+		return nil, errors.New("unable to open secretbox")
+	}
+	return plaintext, nil
+}
+
+// Build the key provider selected by the EncryptedSecret.
+func (comp *EncryptedSecretComponent) newKeyProvider(ctx *components.ComponentContext, instance *secretsv1beta1.EncryptedSecret) (keyProvider, error) {
+	spec := instance.KeyProvider
+	if spec == nil {
+		spec = &secretsv1beta1.EncryptedSecretKeyProvider{}
+	}
+	count := 0
+	if spec.AWSKMS != nil {
+		count++
+	}
+	if spec.GCPKMS != nil {
+		count++
+	}
+	if spec.Local != nil {
+		count++
+	}
+	if count > 1 {
+		return nil, errors.New("encryptedsecret: only one key provider can be set")
+	}
+
+	if spec.GCPKMS != nil {
+		if comp.gcpKMS == nil {
+			return nil, errors.Errorf("encryptedsecret: gcp-kms key %s requested but GCP credentials are not configured", spec.GCPKMS.KeyName)
+		}
+		return &gcpKMSKeyProvider{
+			kms:                         comp.gcpKMS,
+			keyName:                     spec.GCPKMS.KeyName,
+			additionalAuthenticatedData: spec.GCPKMS.AdditionalAuthenticatedData,
+		}, nil
+	}
+
+	if spec.Local != nil {
+		provider := &localKeyProvider{secretName: spec.Local.SecretName, key: spec.Local.Key}
+		if provider.key == "" {
+			provider.key = "key"
+		}
+		secret := &corev1.Secret{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: provider.secretName, Namespace: instance.Namespace}, secret)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encryptedsecret: failed to get %s", provider)
+		}
+		secretKey, ok := secret.Data[provider.key]
+		if !ok || len(secretKey) != 32 {
+			return nil, errors.Errorf("encryptedsecret: %s must be 32 bytes", provider)
+		}
+		provider.secretKey = &[32]byte{}
+		copy(provider.secretKey[:], secretKey)
+		return provider, nil
+	}
+
+	provider := &awsKMSKeyProvider{
+		region: defaultAWSKMSRegion,
+		encryptionContext: map[string]*string{
+			"RidecellOperator": aws.String("true"),
+		},
+	}
+	if spec.AWSKMS != nil {
+		if spec.AWSKMS.Region != "" {
+			provider.region = spec.AWSKMS.Region
+		}
+		provider.keyID = spec.AWSKMS.KeyID
+		if spec.AWSKMS.EncryptionContext != nil {
+			provider.encryptionContext = aws.StringMap(spec.AWSKMS.EncryptionContext)
+		}
+	}
+	provider.kmsAPI = comp.getKMSAPI(provider.region)
+	return provider, nil
+}
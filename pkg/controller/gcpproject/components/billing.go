@@ -22,12 +22,15 @@ import (
 	"os"
 
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/cloudbilling/v1"
+	"google.golang.org/api/option"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 // Interface for a firebase client to allow for a mock implementation.
@@ -42,7 +45,11 @@ type realCloudBilling struct {
 }
 
 func newRealCloudBilling() (*realCloudBilling, error) {
-	svc, err := cloudbilling.NewService(context.Background())
+	client, err := google.DefaultClient(context.Background(), cloudbilling.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := cloudbilling.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
 	if err != nil {
 		return nil, err
 	}
@@ -23,13 +23,16 @@ import (
 	"time"
 
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/firebase/v1beta1"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 // Interface for a firebase client to allow for a mock implementation.
@@ -45,7 +48,11 @@ type realFirebase struct {
 }
 
 func newRealFirebase() (*realFirebase, error) {
-	svc, err := firebase.NewService(context.Background())
+	client, err := google.DefaultClient(context.Background(), firebase.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := firebase.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
 	if err != nil {
 		return nil, err
 	}
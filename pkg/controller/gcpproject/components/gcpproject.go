@@ -22,13 +22,16 @@ import (
 	"time"
 
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 // Interface for a cloudresourcemanager client to allow for a mock implementation.
@@ -44,7 +47,11 @@ type realCloudResourceManager struct {
 }
 
 func newRealCloudResourceManager() (*realCloudResourceManager, error) {
-	svc, err := cloudresourcemanager.NewService(context.Background())
+	client, err := google.DefaultClient(context.Background(), cloudresourcemanager.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := cloudresourcemanager.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +34,7 @@ import (
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 func newRealHTTPClient() (*http.Client, error) {
@@ -46,7 +47,7 @@ func newRealHTTPClient() (*http.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return client, err
+	return metrics.InstrumentHTTPClient("gcp", client), err
 }
 
 type realtimedbComponent struct {
@@ -92,5 +92,11 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance.Spec.AssumeRolePolicyDocument = defaultPolicy
 	}
 
+	for i, serviceAccount := range instance.Spec.ServiceAccounts {
+		if serviceAccount.Namespace == "" {
+			instance.Spec.ServiceAccounts[i].Namespace = instance.Namespace
+		}
+	}
+
 	return components.Result{}, nil
 }
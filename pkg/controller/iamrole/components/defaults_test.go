@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	iamrolecomponents "github.com/Ridecell/ridecell-operator/pkg/controller/iamrole/components"
 )
 
@@ -74,4 +75,18 @@ var _ = Describe("iamrole Defaults Component", func() {
 		Expect(instance.Spec.AssumeRolePolicyDocument).To(MatchJSON(expectedAssumeRolePolicyDocument))
 	})
 
+	It("defaults service account namespaces", func() {
+		comp := iamrolecomponents.NewDefaults()
+		instance.Spec.ServiceAccounts = []awsv1beta1.IAMRoleServiceAccountRef{
+			{Name: "foo"},
+			{Name: "bar", Namespace: "other"},
+		}
+		Expect(comp).To(ReconcileContext(ctx))
+
+		Expect(instance.Spec.ServiceAccounts).To(Equal([]awsv1beta1.IAMRoleServiceAccountRef{
+			{Name: "foo", Namespace: "default"},
+			{Name: "bar", Namespace: "other"},
+		}))
+	})
+
 })
@@ -19,10 +19,14 @@ package components
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
 	"text/template"
+	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/aws/aws-sdk-go/aws"
@@ -36,6 +40,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -50,7 +55,7 @@ type templatingData struct {
 }
 
 func NewIAMRole() *iamRoleComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	iamService := iam.New(sess)
 	return &iamRoleComponent{iamAPI: iamService}
 }
@@ -90,6 +95,15 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 		inlinePolicies[policyName] = parsedPolicy
 	}
 
+	managedPolicyArns := map[string]bool{}
+	for _, policyArn := range instance.Spec.ManagedPolicyArns {
+		parsedArn, err := comp.parseField(policyArn)
+		if err != nil {
+			return components.Result{}, err
+		}
+		managedPolicyArns[parsedArn] = true
+	}
+
 	// if object is not being deleted
 	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
 		// Is our finalizer attached to the object?
@@ -120,6 +134,14 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 		// If object is being deleted and has no finalizer just exit.
 		return components.Result{}, nil
 	}
+	// Trust the listed ServiceAccounts through the cluster's OIDC provider.
+	if len(instance.Spec.ServiceAccounts) > 0 {
+		assumePolicyDocument, err = addWebIdentityStatement(assumePolicyDocument, instance.Spec.OIDCProviderArn, instance.Spec.ServiceAccounts)
+		if err != nil {
+			return components.Result{}, err
+		}
+	}
+
 	// check assumeRolePolicyDocument for valid JSON
 	// inlinepolicies is checked later in UnMarshal
 	if !json.Valid([]byte(assumePolicyDocument)) {
@@ -220,6 +242,15 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 		rolePolicies[aws.StringValue(getRolePolicy.PolicyName)] = decoded
 	}
 
+	// Anything changed or removed on an existing role was drift from outside the operator. Policies that were
+	// in effect last time and have gone missing since were removed out-of-band too.
+	driftCorrections := []string{}
+	previousPolicies := map[string]bool{}
+	for _, policy := range instance.Status.EffectivePolicies {
+		previousPolicies[policy] = true
+	}
+	effectivePolicies := []string{}
+
 	// If there is an inline policy that is not in the spec delete it
 	for rolePolicyName := range rolePolicies {
 		_, ok := inlinePolicies[rolePolicyName]
@@ -231,6 +262,7 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 			if err != nil {
 				return components.Result{}, errors.Wrapf(err, "iam_role: failed to delete role policy %s", rolePolicyName)
 			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("deleted inline policy %s", rolePolicyName))
 		}
 	}
 
@@ -255,6 +287,9 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 			if reflect.DeepEqual(existingPolicyObj, specPolicyObj) {
 				continue
 			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("updated inline policy %s", policyName))
+		} else if previousPolicies[policyName] {
+			driftCorrections = append(driftCorrections, fmt.Sprintf("restored inline policy %s", policyName))
 		}
 
 		_, err = comp.iamAPI.PutRolePolicy(&iam.PutRolePolicyInput{
@@ -268,6 +303,47 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 		}
 	}
 
+	// Sync the attached managed policies.
+	listAttachedRolePoliciesOutput, err := comp.iamAPI.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{RoleName: role.RoleName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_role: failed to list attached role policies")
+	}
+
+	attachedPolicyArns := map[string]bool{}
+	for _, attachedPolicy := range listAttachedRolePoliciesOutput.AttachedPolicies {
+		policyArn := aws.StringValue(attachedPolicy.PolicyArn)
+		attachedPolicyArns[policyArn] = true
+		if !managedPolicyArns[policyArn] {
+			_, err = comp.iamAPI.DetachRolePolicy(&iam.DetachRolePolicyInput{
+				PolicyArn: attachedPolicy.PolicyArn,
+				RoleName:  role.RoleName,
+			})
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "iam_role: failed to detach role policy %s", policyArn)
+			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("detached managed policy %s", policyArn))
+			continue
+		}
+		effectivePolicies = append(effectivePolicies, policyArn)
+	}
+
+	for policyArn := range managedPolicyArns {
+		if attachedPolicyArns[policyArn] {
+			continue
+		}
+		_, err = comp.iamAPI.AttachRolePolicy(&iam.AttachRolePolicyInput{
+			PolicyArn: aws.String(policyArn),
+			RoleName:  role.RoleName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to attach role policy %s", policyArn)
+		}
+		if previousPolicies[policyArn] {
+			driftCorrections = append(driftCorrections, fmt.Sprintf("reattached managed policy %s", policyArn))
+		}
+		effectivePolicies = append(effectivePolicies, policyArn)
+	}
+
 	// Now we need to check the assumeRolePolicy
 	// No really, PolicyDocument is URL-encoded. I have no idea why. https://docs.aws.amazon.com/IAM/latest/APIReference/API_GetRole.html
 	decodedExistingARPD, err := url.PathUnescape(aws.StringValue(role.AssumeRolePolicyDocument))
@@ -297,11 +373,24 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 		}
 	}
 
+	// Every inline policy in the spec was put above, so they are all in effect now.
+	for policyName := range inlinePolicies {
+		effectivePolicies = append(effectivePolicies, policyName)
+	}
+	sort.Strings(effectivePolicies)
+	sort.Strings(driftCorrections)
+
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*awsv1beta1.IAMRole)
 		instance.Status.Status = awsv1beta1.StatusReady
 		instance.Status.Message = "Role exists"
 		instance.Status.RoleName = aws.StringValue(role.RoleName)
+		instance.Status.RoleArn = aws.StringValue(role.Arn)
+		instance.Status.EffectivePolicies = effectivePolicies
+		if len(driftCorrections) > 0 {
+			instance.Status.LastDriftCorrection = time.Now().Format(time.UnixDate)
+			instance.Status.LastDriftCorrectionMessage = strings.Join(driftCorrections, ", ")
+		}
 		return nil
 	}}, nil
 }
@@ -334,6 +423,22 @@ func (comp *iamRoleComponent) deleteDependencies(roleName string) (components.Re
 			return components.Result{}, errors.Wrapf(err, "iam_role: failed to delete role policy for finalizer")
 		}
 	}
+	// Managed policies also have to be detached before role deletion
+	listAttachedRolePoliciesOutput, err := comp.iamAPI.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != iam.ErrCodeNoSuchEntityException {
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to list attached role policies for finalizer")
+		}
+	}
+	for _, attachedPolicy := range listAttachedRolePoliciesOutput.AttachedPolicies {
+		_, err = comp.iamAPI.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			RoleName:  aws.String(roleName),
+			PolicyArn: attachedPolicy.PolicyArn,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to detach role policy for finalizer")
+		}
+	}
 	_, err = comp.iamAPI.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(roleName)})
 	// If the role doesn't exist skip error
 	if err != nil {
@@ -344,6 +449,68 @@ func (comp *iamRoleComponent) deleteDependencies(roleName string) (components.Re
 	return components.Result{}, nil
 }
 
+// addWebIdentityStatement adds a statement to the trust policy allowing the ServiceAccounts to assume the
+// role using tokens from the OIDC provider.
+func addWebIdentityStatement(document string, oidcProviderArn string, serviceAccounts []awsv1beta1.IAMRoleServiceAccountRef) (string, error) {
+	if oidcProviderArn == "" {
+		return "", errors.New("iam_role: oidcProviderArn is required when using serviceAccounts")
+	}
+	arnParts := strings.SplitN(oidcProviderArn, ":oidc-provider/", 2)
+	if len(arnParts) != 2 || arnParts[1] == "" {
+		return "", errors.Errorf("iam_role: unable to get OIDC issuer from provider ARN %s", oidcProviderArn)
+	}
+	issuer := arnParts[1]
+
+	subjects := []string{}
+	for _, serviceAccount := range serviceAccounts {
+		subjects = append(subjects, fmt.Sprintf("system:serviceaccount:%s:%s", serviceAccount.Namespace, serviceAccount.Name))
+	}
+	sort.Strings(subjects)
+	// Write a single subject as a plain string rather than a one element list.
+	var subjectCondition interface{} = subjects
+	if len(subjects) == 1 {
+		subjectCondition = subjects[0]
+	}
+
+	statement := map[string]interface{}{
+		"Effect":    "Allow",
+		"Principal": map[string]interface{}{"Federated": oidcProviderArn},
+		"Action":    "sts:AssumeRoleWithWebIdentity",
+		"Condition": map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				issuer + ":aud": "sts.amazonaws.com",
+				issuer + ":sub": subjectCondition,
+			},
+		},
+	}
+
+	policy := map[string]interface{}{}
+	if document != "" {
+		err := json.Unmarshal([]byte(document), &policy)
+		if err != nil {
+			return "", errors.Wrap(err, "iam_role: assume role trust policy contains invalid json")
+		}
+	}
+	if _, ok := policy["Version"]; !ok {
+		policy["Version"] = "2012-10-17"
+	}
+	// Statement can be either a single statement or a list of them.
+	statements := []interface{}{}
+	switch existing := policy["Statement"].(type) {
+	case []interface{}:
+		statements = append(statements, existing...)
+	case map[string]interface{}:
+		statements = append(statements, existing)
+	}
+	policy["Statement"] = append(statements, statement)
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return "", errors.Wrap(err, "iam_role: unable to marshal assume role trust policy")
+	}
+	return string(policyJSON), nil
+}
+
 func (comp *iamRoleComponent) parseField(field string) (string, error) {
 	templateData := templatingData{
 		Region: os.Getenv("AWS_REGION"),
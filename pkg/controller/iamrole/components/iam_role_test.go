@@ -17,6 +17,10 @@ limitations under the License.
 package components_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -41,9 +45,13 @@ type mockIAMClient struct {
 	mockExtraRolePolicy bool
 	mockRoleTagged      bool
 	mockRoleCreated     bool
+	mockAttachedArns    []string
 
-	deleteRole    bool
-	finalizerTest bool
+	deleteRole       bool
+	finalizerTest    bool
+	attachedPolicies []string
+	detachedPolicies []string
+	updatedPolicy    string
 
 	expectedRoleName       string
 	expectedPolicyDocument string
@@ -85,6 +93,46 @@ var _ = Describe("iam_role aws Component", func() {
 		mockIAM.mockExtraRolePolicy = true
 
 		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.LastDriftCorrection).ToNot(BeEmpty())
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("deleted inline policy mock1"))
+	})
+
+	It("attaches managed policies", func() {
+		instance.Spec.InlinePolicies = map[string]string{
+			"test777": `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": "*"}}`,
+		}
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(mockIAM.detachedPolicies).To(BeEmpty())
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "test777"}))
+		Expect(instance.Status.LastDriftCorrection).To(BeEmpty())
+	})
+
+	It("detaches managed policies that are not in the spec", func() {
+		mockIAM.mockRoleHasTags = true
+		mockIAM.mockRoleExists = true
+		mockIAM.mockAttachedArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "arn:aws:iam::aws:policy/AdministratorAccess"}
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(BeEmpty())
+		Expect(mockIAM.detachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/AdministratorAccess"}))
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("detached managed policy arn:aws:iam::aws:policy/AdministratorAccess"))
+	})
+
+	It("records a managed policy reattached after being detached out-of-band", func() {
+		mockIAM.mockRoleHasTags = true
+		mockIAM.mockRoleExists = true
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+		instance.Status.EffectivePolicies = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("reattached managed policy arn:aws:iam::aws:policy/ReadOnlyAccess"))
 	})
 
 	It("creates new role with policies", func() {
@@ -123,6 +171,61 @@ var _ = Describe("iam_role aws Component", func() {
 		Expect(err).To(MatchError("iam_role: existing role is not tagged with ridecell-operator: True, aborting"))
 	})
 
+	Describe("service accounts", func() {
+		oidcProviderArn := "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE"
+		webIdentityStatement := `{
+			"Effect": "Allow",
+			"Principal": {"Federated": "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE"},
+			"Action": "sts:AssumeRoleWithWebIdentity",
+			"Condition": {
+				"StringEquals": {
+					"oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE:aud": "sts.amazonaws.com",
+					"oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE:sub": %s
+				}
+			}
+		}`
+
+		BeforeEach(func() {
+			instance.Spec.OIDCProviderArn = oidcProviderArn
+			instance.Spec.ServiceAccounts = []awsv1beta1.IAMRoleServiceAccountRef{{Name: "foo", Namespace: "default"}}
+		})
+
+		It("creates a role trusting the service account", func() {
+			mockIAM.expectedPolicyDocument = fmt.Sprintf(`{"Version": "2012-10-17", "Statement": [%s]}`, fmt.Sprintf(webIdentityStatement, `"system:serviceaccount:default:foo"`))
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.mockRoleCreated).To(BeTrue())
+			Expect(instance.Status.RoleArn).To(Equal("arn:aws:iam::123456789012:role/test-role"))
+		})
+
+		It("adds the web identity statement to an existing trust policy", func() {
+			mockIAM.mockRoleExists = true
+			mockIAM.mockRoleHasTags = true
+			kiamStatement := `{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:role/kiam"}, "Action": "sts:AssumeRole"}`
+			instance.Spec.AssumeRolePolicyDocument = fmt.Sprintf(`{"Version": "2012-10-17", "Statement": %s}`, kiamStatement)
+			instance.Spec.ServiceAccounts = append(instance.Spec.ServiceAccounts, awsv1beta1.IAMRoleServiceAccountRef{Name: "bar", Namespace: "other"})
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.updatedPolicy).To(MatchJSON(fmt.Sprintf(`{"Version": "2012-10-17", "Statement": [%s, %s]}`,
+				kiamStatement,
+				fmt.Sprintf(webIdentityStatement, `["system:serviceaccount:default:foo", "system:serviceaccount:other:bar"]`))))
+		})
+
+		It("requires an OIDC provider", func() {
+			instance.Spec.OIDCProviderArn = ""
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(MatchError("iam_role: oidcProviderArn is required when using serviceAccounts"))
+		})
+
+		It("rejects an ARN which isn't an OIDC provider", func() {
+			instance.Spec.OIDCProviderArn = "arn:aws:iam::123456789012:role/nope"
+
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(MatchError("iam_role: unable to get OIDC issuer from provider ARN arn:aws:iam::123456789012:role/nope"))
+		})
+	})
+
 	Describe("finalizer tests", func() {
 
 		It("adds finalizer when there isn't one", func() {
@@ -157,6 +260,18 @@ var _ = Describe("iam_role aws Component", func() {
 			Expect(mockIAM.deleteRole).To(BeTrue())
 		})
 
+		It("detaches managed policies before deleting the role", func() {
+			mockIAM.finalizerTest = true
+			mockIAM.mockRoleExists = true
+			mockIAM.mockAttachedArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+			currentTime := metav1.Now()
+			instance.ObjectMeta.SetDeletionTimestamp(&currentTime)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.detachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+			Expect(mockIAM.deleteRole).To(BeTrue())
+		})
+
 		It("simulates role not existing during finalizer deletion", func() {
 			currentTime := metav1.Now()
 			mockIAM.finalizerTest = true
@@ -189,12 +304,19 @@ func (m *mockIAMClient) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleO
 	if aws.StringValue(input.RoleName) != m.expectedRoleName {
 		return &iam.CreateRoleOutput{}, errors.New("awsmock_createrole: given rolename does not match expected")
 	}
-	if aws.StringValue(input.AssumeRolePolicyDocument) != m.expectedPolicyDocument {
+	var givenPolicy, expectedPolicy interface{}
+	json.Unmarshal([]byte(aws.StringValue(input.AssumeRolePolicyDocument)), &givenPolicy)
+	json.Unmarshal([]byte(m.expectedPolicyDocument), &expectedPolicy)
+	if !reflect.DeepEqual(givenPolicy, expectedPolicy) {
 		return &iam.CreateRoleOutput{}, errors.New("awsmock_createrole: given assume role policy document does not match spec")
 	}
 	m.mockRoleCreated = true
 	m.mockRoleHasTags = true
-	return &iam.CreateRoleOutput{Role: &iam.Role{RoleName: input.RoleName, AssumeRolePolicyDocument: input.AssumeRolePolicyDocument}}, nil
+	return &iam.CreateRoleOutput{Role: &iam.Role{
+		RoleName:                 input.RoleName,
+		Arn:                      aws.String("arn:aws:iam::123456789012:role/" + aws.StringValue(input.RoleName)),
+		AssumeRolePolicyDocument: input.AssumeRolePolicyDocument,
+	}}, nil
 }
 
 func (m *mockIAMClient) ListRolePolicies(input *iam.ListRolePoliciesInput) (*iam.ListRolePoliciesOutput, error) {
@@ -298,5 +420,33 @@ func (m *mockIAMClient) UpdateAssumeRolePolicy(input *iam.UpdateAssumeRolePolicy
 	if aws.StringValue(input.RoleName) != m.expectedRoleName {
 		return &iam.UpdateAssumeRolePolicyOutput{}, errors.New("awsmock_updateassumerolepolicy: rolename did not match expected")
 	}
+	m.updatedPolicy = aws.StringValue(input.PolicyDocument)
 	return &iam.UpdateAssumeRolePolicyOutput{}, nil
 }
+
+func (m *mockIAMClient) ListAttachedRolePolicies(input *iam.ListAttachedRolePoliciesInput) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if aws.StringValue(input.RoleName) != m.expectedRoleName || (!m.mockRoleExists && m.finalizerTest) {
+		return &iam.ListAttachedRolePoliciesOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_listattachedrolepolicies: given rolename does not match expected", errors.New(""))
+	}
+	attachedPolicies := []*iam.AttachedPolicy{}
+	for _, policyArn := range m.mockAttachedArns {
+		attachedPolicies = append(attachedPolicies, &iam.AttachedPolicy{PolicyArn: aws.String(policyArn)})
+	}
+	return &iam.ListAttachedRolePoliciesOutput{AttachedPolicies: attachedPolicies}, nil
+}
+
+func (m *mockIAMClient) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+	if aws.StringValue(input.RoleName) != m.expectedRoleName {
+		return &iam.AttachRolePolicyOutput{}, errors.New("awsmock_attachrolepolicy: rolename did not match expected")
+	}
+	m.attachedPolicies = append(m.attachedPolicies, aws.StringValue(input.PolicyArn))
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (m *mockIAMClient) DetachRolePolicy(input *iam.DetachRolePolicyInput) (*iam.DetachRolePolicyOutput, error) {
+	if aws.StringValue(input.RoleName) != m.expectedRoleName {
+		return &iam.DetachRolePolicyOutput{}, errors.New("awsmock_detachrolepolicy: rolename did not match expected")
+	}
+	m.detachedPolicies = append(m.detachedPolicies, aws.StringValue(input.PolicyArn))
+	return &iam.DetachRolePolicyOutput{}, nil
+}
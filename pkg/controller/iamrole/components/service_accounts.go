@@ -0,0 +1,128 @@
+/*
+Copyright 2020 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// Annotation read by the EKS pod identity webhook to inject credentials for the role.
+const RoleArnAnnotation = "eks.amazonaws.com/role-arn"
+
+type serviceAccountsComponent struct{}
+
+func NewServiceAccounts() *serviceAccountsComponent {
+	return &serviceAccountsComponent{}
+}
+
+// The ServiceAccounts aren't owned by the role so an owner watch would never fire for them, they get checked
+// whenever the role reconciles instead.
+func (_ *serviceAccountsComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *serviceAccountsComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*awsv1beta1.IAMRole)
+	// Wait for the role to exist so we have an ARN.
+	return instance.ObjectMeta.DeletionTimestamp.IsZero() && instance.Status.RoleArn != ""
+}
+
+func (comp *serviceAccountsComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*awsv1beta1.IAMRole)
+	roleArn := instance.Status.RoleArn
+
+	annotated := []string{}
+	missing := false
+	wanted := map[string]bool{}
+	for _, ref := range instance.Spec.ServiceAccounts {
+		key := fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)
+		wanted[key] = true
+
+		serviceAccount := &corev1.ServiceAccount{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, serviceAccount)
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				// Whoever owns the ServiceAccount hasn't created it yet, check back later.
+				missing = true
+				continue
+			}
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to get service account %s", key)
+		}
+		if serviceAccount.Annotations[RoleArnAnnotation] != roleArn {
+			if serviceAccount.Annotations == nil {
+				serviceAccount.Annotations = map[string]string{}
+			}
+			serviceAccount.Annotations[RoleArnAnnotation] = roleArn
+			err = ctx.Update(ctx.Context, serviceAccount)
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "iam_role: failed to annotate service account %s", key)
+			}
+		}
+		annotated = append(annotated, key)
+	}
+
+	// Remove the annotation from ServiceAccounts which were dropped from the spec.
+	for _, key := range instance.Status.ServiceAccounts {
+		if wanted[key] {
+			continue
+		}
+		keyParts := strings.SplitN(key, "/", 2)
+		if len(keyParts) != 2 {
+			continue
+		}
+		serviceAccount := &corev1.ServiceAccount{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: keyParts[1], Namespace: keyParts[0]}, serviceAccount)
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to get service account %s", key)
+		}
+		if serviceAccount.Annotations[RoleArnAnnotation] != roleArn {
+			// Pointed at another role since, leave it be.
+			continue
+		}
+		delete(serviceAccount.Annotations, RoleArnAnnotation)
+		err = ctx.Update(ctx.Context, serviceAccount)
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_role: failed to remove annotation from service account %s", key)
+		}
+	}
+	sort.Strings(annotated)
+
+	result := components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*awsv1beta1.IAMRole)
+		instance.Status.ServiceAccounts = annotated
+		return nil
+	}}
+	if missing {
+		result.RequeueAfter = time.Minute
+	}
+	return result, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	iamrolecomponents "github.com/Ridecell/ridecell-operator/pkg/controller/iamrole/components"
+)
+
+var _ = Describe("iamrole ServiceAccounts Component", func() {
+	var comp components.Component
+	roleArn := "arn:aws:iam::123456789012:role/test-role"
+
+	BeforeEach(func() {
+		comp = iamrolecomponents.NewServiceAccounts()
+		instance.Status.RoleArn = roleArn
+		instance.Spec.ServiceAccounts = []awsv1beta1.IAMRoleServiceAccountRef{
+			{Name: "foo", Namespace: "default"},
+		}
+	})
+
+	getServiceAccount := func(name string, namespace string) *corev1.ServiceAccount {
+		serviceAccount := &corev1.ServiceAccount{}
+		err := ctx.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, serviceAccount)
+		Expect(err).ToNot(HaveOccurred())
+		return serviceAccount
+	}
+
+	It("is not reconcilable before the role exists", func() {
+		instance.Status.RoleArn = ""
+		Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+	})
+
+	It("is reconcilable once the role exists", func() {
+		Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+	})
+
+	It("annotates an existing service account", func() {
+		serviceAccount := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Annotations: map[string]string{"other": "value"}},
+		}
+		ctx.Client = fake.NewFakeClient(instance, serviceAccount)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		serviceAccount = getServiceAccount("foo", "default")
+		Expect(serviceAccount.Annotations).To(HaveKeyWithValue(iamrolecomponents.RoleArnAnnotation, roleArn))
+		Expect(serviceAccount.Annotations).To(HaveKeyWithValue("other", "value"))
+		Expect(instance.Status.ServiceAccounts).To(Equal([]string{"default/foo"}))
+	})
+
+	It("waits for a missing service account", func() {
+		instance.Spec.ServiceAccounts = append(instance.Spec.ServiceAccounts, awsv1beta1.IAMRoleServiceAccountRef{Name: "bar", Namespace: "other"})
+		serviceAccount := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		}
+		ctx.Client = fake.NewFakeClient(instance, serviceAccount)
+
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(time.Minute))
+		Expect(res.StatusModifier(instance)).To(Succeed())
+		Expect(getServiceAccount("foo", "default").Annotations).To(HaveKeyWithValue(iamrolecomponents.RoleArnAnnotation, roleArn))
+		Expect(instance.Status.ServiceAccounts).To(Equal([]string{"default/foo"}))
+	})
+
+	It("removes the annotation from a service account dropped from the spec", func() {
+		instance.Status.ServiceAccounts = []string{"default/foo", "default/old", "default/moved"}
+		old := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: "default", Annotations: map[string]string{iamrolecomponents.RoleArnAnnotation: roleArn}},
+		}
+		moved := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "moved", Namespace: "default", Annotations: map[string]string{iamrolecomponents.RoleArnAnnotation: "arn:aws:iam::123456789012:role/other"}},
+		}
+		ctx.Client = fake.NewFakeClient(instance, old, moved)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(getServiceAccount("old", "default").Annotations).ToNot(HaveKey(iamrolecomponents.RoleArnAnnotation))
+		Expect(getServiceAccount("moved", "default").Annotations).To(HaveKeyWithValue(iamrolecomponents.RoleArnAnnotation, "arn:aws:iam::123456789012:role/other"))
+		Expect(instance.Status.ServiceAccounts).To(Equal([]string{"default/foo"}))
+	})
+})
@@ -30,6 +30,7 @@ func Add(mgr manager.Manager) error {
 	_, err := components.NewReconciler("iamrole-controller", mgr, &awsv1beta1.IAMRole{}, nil, []components.Component{
 		iamrolecomponents.NewDefaults(),
 		iamrolecomponents.NewIAMRole(),
+		iamrolecomponents.NewServiceAccounts(),
 	})
 	return err
 }
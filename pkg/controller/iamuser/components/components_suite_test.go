@@ -17,6 +17,7 @@ limitations under the License.
 package components_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/onsi/ginkgo"
@@ -41,6 +42,7 @@ func TestComponents(t *testing.T) {
 
 var _ = ginkgo.BeforeEach(func() {
 	// Set up default-y values for tests to use if they want.
+	os.Setenv("AWS_REGION", "us-test-1")
 	instance = &awsv1beta1.IAMUser{
 		ObjectMeta: metav1.ObjectMeta{Name: "test-user", Namespace: "default"},
 	}
@@ -17,6 +17,8 @@ limitations under the License.
 package components
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
@@ -45,5 +47,8 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 	if instance.Spec.UserName == "" {
 		instance.Spec.UserName = instance.Name
 	}
+	if instance.Spec.RotationGracePeriod.Duration == 0 {
+		instance.Spec.RotationGracePeriod.Duration = time.Hour
+	}
 	return components.Result{}, nil
 }
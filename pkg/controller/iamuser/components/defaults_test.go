@@ -17,8 +17,11 @@ limitations under the License.
 package components_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	iamusercomponents "github.com/Ridecell/ridecell-operator/pkg/controller/iamuser/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -28,9 +31,11 @@ var _ = Describe("iamuser Defaults Component", func() {
 	It("does nothing on a filled out object", func() {
 		comp := iamusercomponents.NewDefaults()
 		instance.Spec.UserName = "test"
+		instance.Spec.RotationGracePeriod = metav1.Duration{Duration: 10 * time.Minute}
 
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Spec.UserName).To(Equal("test"))
+		Expect(instance.Spec.RotationGracePeriod.Duration).To(Equal(10 * time.Minute))
 	})
 
 	It("sets defaults", func() {
@@ -38,6 +43,8 @@ var _ = Describe("iamuser Defaults Component", func() {
 		Expect(comp).To(ReconcileContext(ctx))
 
 		Expect(instance.Spec.UserName).To(Equal("test-user"))
+		Expect(instance.Spec.MaxKeyAge.Duration).To(BeZero())
+		Expect(instance.Spec.RotationGracePeriod.Duration).To(Equal(time.Hour))
 	})
 
 })
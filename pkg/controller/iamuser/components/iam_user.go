@@ -17,10 +17,16 @@ limitations under the License.
 package components
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/aws/aws-sdk-go/aws"
@@ -36,6 +42,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,8 +54,12 @@ type iamUserComponent struct {
 	iamAPI iamiface.IAMAPI
 }
 
+type templatingData struct {
+	Region string
+}
+
 func NewIAMUser() *iamUserComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	iamService := iam.New(sess)
 	return &iamUserComponent{iamAPI: iamService}
 }
@@ -65,6 +76,10 @@ func (_ *iamUserComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	return true
 }
 
+// Annotations on the aws-credentials secret tracking a key rotation in progress.
+const previousAccessKeyAnnotation = "ridecell.io/previous-access-key-id"
+const lastRotatedAnnotation = "ridecell.io/access-key-last-rotated"
+
 func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*awsv1beta1.IAMUser)
 
@@ -172,6 +187,15 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		userPolicies[aws.StringValue(getUserPolicy.PolicyName)] = decoded
 	}
 
+	// Anything changed or removed on an existing user was drift from outside the operator. Policies that were
+	// in effect last time and have gone missing since were removed out-of-band too.
+	driftCorrections := []string{}
+	previousPolicies := map[string]bool{}
+	for _, policy := range instance.Status.EffectivePolicies {
+		previousPolicies[policy] = true
+	}
+	effectivePolicies := []string{}
+
 	// If there is an inline policy that is not in the spec delete it
 	for userPolicyName := range userPolicies {
 		_, ok := instance.Spec.InlinePolicies[userPolicyName]
@@ -183,6 +207,7 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 			if err != nil {
 				return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete user policy %s", userPolicyName)
 			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("deleted inline policy %s", userPolicyName))
 		}
 	}
 
@@ -207,6 +232,9 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 			if reflect.DeepEqual(existingPolicyObj, specPolicyObj) {
 				continue
 			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("updated inline policy %s", policyName))
+		} else if previousPolicies[policyName] {
+			driftCorrections = append(driftCorrections, fmt.Sprintf("restored inline policy %s", policyName))
 		}
 
 		_, err = comp.iamAPI.PutUserPolicy(&iam.PutUserPolicyInput{
@@ -220,6 +248,63 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		}
 	}
 
+	// Sync the attached managed policies.
+	managedPolicyArns := map[string]bool{}
+	for _, policyArn := range instance.Spec.ManagedPolicyArns {
+		parsedArn, err := comp.parseField(policyArn)
+		if err != nil {
+			return components.Result{}, err
+		}
+		managedPolicyArns[parsedArn] = true
+	}
+
+	listAttachedUserPoliciesOutput, err := comp.iamAPI.ListAttachedUserPolicies(&iam.ListAttachedUserPoliciesInput{UserName: user.UserName})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "iam_user: failed to list attached user policies")
+	}
+
+	attachedPolicyArns := map[string]bool{}
+	for _, attachedPolicy := range listAttachedUserPoliciesOutput.AttachedPolicies {
+		policyArn := aws.StringValue(attachedPolicy.PolicyArn)
+		attachedPolicyArns[policyArn] = true
+		if !managedPolicyArns[policyArn] {
+			_, err = comp.iamAPI.DetachUserPolicy(&iam.DetachUserPolicyInput{
+				PolicyArn: attachedPolicy.PolicyArn,
+				UserName:  user.UserName,
+			})
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "iam_user: failed to detach user policy %s", policyArn)
+			}
+			driftCorrections = append(driftCorrections, fmt.Sprintf("detached managed policy %s", policyArn))
+			continue
+		}
+		effectivePolicies = append(effectivePolicies, policyArn)
+	}
+
+	for policyArn := range managedPolicyArns {
+		if attachedPolicyArns[policyArn] {
+			continue
+		}
+		_, err = comp.iamAPI.AttachUserPolicy(&iam.AttachUserPolicyInput{
+			PolicyArn: aws.String(policyArn),
+			UserName:  user.UserName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_user: failed to attach user policy %s", policyArn)
+		}
+		if previousPolicies[policyArn] {
+			driftCorrections = append(driftCorrections, fmt.Sprintf("reattached managed policy %s", policyArn))
+		}
+		effectivePolicies = append(effectivePolicies, policyArn)
+	}
+
+	// Every inline policy in the spec was put above, so they are all in effect now.
+	for policyName := range instance.Spec.InlinePolicies {
+		effectivePolicies = append(effectivePolicies, policyName)
+	}
+	sort.Strings(effectivePolicies)
+	sort.Strings(driftCorrections)
+
 	fetchAccessKey := &corev1.Secret{}
 	err = ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s.aws-credentials", instance.Name), Namespace: instance.Namespace}, fetchAccessKey)
 	if err != nil {
@@ -241,23 +326,67 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		fetchAccessKeyID = []byte{}
 	}
 
-	var foundAccessKeyID bool
+	// During a rotation the previous key is kept working until the grace period is over. The rotation state is
+	// stored on the secret so it is written together with the new key, not in a later status update.
+	now := time.Now()
+	previousAccessKeyID := fetchAccessKey.Annotations[previousAccessKeyAnnotation]
+	lastRotated := fetchAccessKey.Annotations[lastRotatedAnnotation]
+	gracePeriodEnd := now
+	if previousAccessKeyID != "" {
+		rotatedAt, err := time.Parse(time.UnixDate, lastRotated)
+		if err == nil {
+			gracePeriodEnd = rotatedAt.Add(instance.Spec.RotationGracePeriod.Duration)
+		}
+	}
+
+	var currentAccessKey *iam.AccessKeyMetadata
+	var foundPreviousAccessKey bool
 	for _, accessKeyMeta := range existingAccessKeys.AccessKeyMetadata {
-		if aws.StringValue(accessKeyMeta.AccessKeyId) == string(fetchAccessKeyID) {
-			foundAccessKeyID = true
-		} else {
-			// If the access key isn't known to the controller delete it
-			_, err := comp.iamAPI.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+		accessKeyID := aws.StringValue(accessKeyMeta.AccessKeyId)
+		if accessKeyID == string(fetchAccessKeyID) {
+			currentAccessKey = accessKeyMeta
+			continue
+		}
+		if accessKeyID == previousAccessKeyID {
+			if now.Before(gracePeriodEnd) {
+				foundPreviousAccessKey = true
+				continue
+			}
+			// Deactivate the old key first so it stops working even if the delete fails.
+			_, err := comp.iamAPI.UpdateAccessKey(&iam.UpdateAccessKeyInput{
 				AccessKeyId: accessKeyMeta.AccessKeyId,
+				Status:      aws.String(iam.StatusTypeInactive),
 				UserName:    user.UserName,
 			})
 			if err != nil {
-				return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete access keys")
+				return components.Result{}, errors.Wrapf(err, "iam_user: failed to deactivate previous access key")
 			}
 		}
+		// If the access key isn't known to the controller delete it
+		_, err := comp.iamAPI.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+			AccessKeyId: accessKeyMeta.AccessKeyId,
+			UserName:    user.UserName,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iam_user: failed to delete access keys")
+		}
+	}
+	if !foundPreviousAccessKey {
+		previousAccessKeyID = ""
 	}
 
-	if !foundAccessKeyID {
+	// Work out if the current key needs to be replaced. Only one rotation can be in progress at a time since
+	// IAM allows at most two keys per user.
+	rotate := false
+	var accessKeyCreated time.Time
+	if currentAccessKey != nil {
+		accessKeyCreated = aws.TimeValue(currentAccessKey.CreateDate)
+		maxKeyAge := instance.Spec.MaxKeyAge.Duration
+		rotate = maxKeyAge > 0 && previousAccessKeyID == "" && !accessKeyCreated.IsZero() && now.Sub(accessKeyCreated) >= maxKeyAge
+	}
+
+	accessKeyID := string(fetchAccessKeyID)
+	if currentAccessKey == nil || rotate {
 		// Make new access key and put it in a secret
 		createAccessKeyOutput, err := comp.iamAPI.CreateAccessKey(&iam.CreateAccessKeyInput{UserName: user.UserName})
 		if err != nil {
@@ -266,6 +395,16 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		fetchAccessKey.Data = make(map[string][]byte)
 		fetchAccessKey.Data["AWS_ACCESS_KEY_ID"] = []byte(aws.StringValue(createAccessKeyOutput.AccessKey.AccessKeyId))
 		fetchAccessKey.Data["AWS_SECRET_ACCESS_KEY"] = []byte(aws.StringValue(createAccessKeyOutput.AccessKey.SecretAccessKey))
+		if rotate {
+			previousAccessKeyID = accessKeyID
+			lastRotated = now.Format(time.UnixDate)
+			gracePeriodEnd = now.Add(instance.Spec.RotationGracePeriod.Duration)
+			if fetchAccessKey.Annotations == nil {
+				fetchAccessKey.Annotations = map[string]string{}
+			}
+			fetchAccessKey.Annotations[previousAccessKeyAnnotation] = previousAccessKeyID
+			fetchAccessKey.Annotations[lastRotatedAnnotation] = lastRotated
+		}
 
 		_, err = controllerutil.CreateOrUpdate(ctx.Context, ctx, fetchAccessKey.DeepCopyObject(), func(existingObj runtime.Object) error {
 			existing := existingObj.(*corev1.Secret)
@@ -283,12 +422,40 @@ func (comp *iamUserComponent) Reconcile(ctx *components.ComponentContext) (compo
 		if err != nil {
 			return components.Result{}, errors.Wrapf(err, "iam_user: failed to create or update secret")
 		}
+
+		accessKeyID = aws.StringValue(createAccessKeyOutput.AccessKey.AccessKeyId)
+		accessKeyCreated = aws.TimeValue(createAccessKeyOutput.AccessKey.CreateDate)
+		if accessKeyCreated.IsZero() {
+			accessKeyCreated = now
+		}
+	}
+
+	var accessKeyAge string
+	if !accessKeyCreated.IsZero() {
+		accessKeyAge = now.Sub(accessKeyCreated).Truncate(time.Hour).String()
+	}
+
+	// Come back when the grace period is over, or when the key is next due for rotation.
+	var requeueAfter time.Duration
+	if previousAccessKeyID != "" {
+		requeueAfter = gracePeriodEnd.Sub(now)
+	} else if instance.Spec.MaxKeyAge.Duration > 0 && !accessKeyCreated.IsZero() {
+		requeueAfter = accessKeyCreated.Add(instance.Spec.MaxKeyAge.Duration).Sub(now)
 	}
 
-	return components.Result{StatusModifier: func(obj runtime.Object) error {
+	return components.Result{RequeueAfter: requeueAfter, StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*awsv1beta1.IAMUser)
 		instance.Status.Status = awsv1beta1.StatusReady
 		instance.Status.Message = "User exists and has secret"
+		instance.Status.EffectivePolicies = effectivePolicies
+		if len(driftCorrections) > 0 {
+			instance.Status.LastDriftCorrection = now.Format(time.UnixDate)
+			instance.Status.LastDriftCorrectionMessage = strings.Join(driftCorrections, ", ")
+		}
+		instance.Status.AccessKeyID = accessKeyID
+		instance.Status.AccessKeyAge = accessKeyAge
+		instance.Status.LastRotated = lastRotated
+		instance.Status.PreviousAccessKeyID = previousAccessKeyID
 		return nil
 	}}, nil
 }
@@ -329,6 +496,22 @@ func (comp *iamUserComponent) deleteDependencies(ctx *components.ComponentContex
 			return components.Result{}, errors.Wrapf(err, "iamuser: failed to delete user policy for finalizer")
 		}
 	}
+	// Managed policies also have to be detached before user deletion
+	listAttachedUserPoliciesOutput, err := comp.iamAPI.ListAttachedUserPolicies(&iam.ListAttachedUserPoliciesInput{UserName: aws.String(instance.Spec.UserName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != iam.ErrCodeNoSuchEntityException {
+			return components.Result{}, errors.Wrapf(err, "iamuser: failed to list attached user policies for finalizer")
+		}
+	}
+	for _, attachedPolicy := range listAttachedUserPoliciesOutput.AttachedPolicies {
+		_, err = comp.iamAPI.DetachUserPolicy(&iam.DetachUserPolicyInput{
+			UserName:  aws.String(instance.Spec.UserName),
+			PolicyArn: attachedPolicy.PolicyArn,
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "iamuser: failed to detach user policy for finalizer")
+		}
+	}
 	_, err = comp.iamAPI.DeleteUser(&iam.DeleteUserInput{UserName: aws.String(instance.Spec.UserName)})
 	// If the user doesn't exist skip error
 	if err != nil {
@@ -338,3 +521,24 @@ func (comp *iamUserComponent) deleteDependencies(ctx *components.ComponentContex
 	}
 	return components.Result{}, nil
 }
+
+func (comp *iamUserComponent) parseField(field string) (string, error) {
+	templateData := templatingData{
+		Region: os.Getenv("AWS_REGION"),
+	}
+
+	buff := &bytes.Buffer{}
+
+	// Create template
+	fieldTemplate, err := template.New("").Parse(field)
+	if err != nil {
+		return "", errors.Wrapf(err, "iam_user: could not parse template")
+	}
+
+	// Swap template delimiters to [[]] and execute
+	err = fieldTemplate.Delims("[[", "]]").Execute(buff, templateData)
+	if err != nil {
+		return "", errors.Wrapf(err, "iam_user: could not execute template")
+	}
+	return buff.String(), nil
+}
@@ -46,9 +46,15 @@ type mockIAMClient struct {
 	mockExtraUserPolicy bool
 	mockHasAccessKey    bool
 	mockUserTagged      bool
-
-	deleteUser    bool
-	finalizerTest bool
+	mockAttachedArns    []string
+	mockAccessKeys      []*iam.AccessKeyMetadata
+
+	deleteUser            bool
+	finalizerTest         bool
+	attachedPolicies      []string
+	detachedPolicies      []string
+	deactivatedAccessKeys []string
+	deletedAccessKeys     []string
 }
 
 var _ = Describe("iam_user aws Component", func() {
@@ -117,6 +123,54 @@ var _ = Describe("iam_user aws Component", func() {
 		fetchAccessKey := &corev1.Secret{}
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "test-user.aws-credentials", Namespace: "default"}, fetchAccessKey)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(instance.Status.LastDriftCorrection).ToNot(BeEmpty())
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("deleted inline policy mock1"))
+	})
+
+	It("attaches managed policies", func() {
+		instance.Spec.InlinePolicies = map[string]string{
+			"test777": `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": "*"}}`,
+		}
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(mockIAM.detachedPolicies).To(BeEmpty())
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "test777"}))
+		Expect(instance.Status.LastDriftCorrection).To(BeEmpty())
+	})
+
+	It("detaches managed policies that are not in the spec", func() {
+		mockIAM.mockUserExists = true
+		mockIAM.mockUserHasTags = true
+		mockIAM.mockAttachedArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "arn:aws:iam::aws:policy/AdministratorAccess"}
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(BeEmpty())
+		Expect(mockIAM.detachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/AdministratorAccess"}))
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("detached managed policy arn:aws:iam::aws:policy/AdministratorAccess"))
+	})
+
+	It("records a managed policy reattached after being detached out-of-band", func() {
+		mockIAM.mockUserExists = true
+		mockIAM.mockUserHasTags = true
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+		instance.Status.EffectivePolicies = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+		Expect(instance.Status.LastDriftCorrectionMessage).To(Equal("reattached managed policy arn:aws:iam::aws:policy/ReadOnlyAccess"))
+	})
+
+	It("templates managed policy ARNs", func() {
+		instance.Spec.ManagedPolicyArns = []string{"arn:aws:iam::aws:policy/{{ .Region }}-ReadOnly"}
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockIAM.attachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/us-test-1-ReadOnly"}))
+		Expect(instance.Status.EffectivePolicies).To(Equal([]string{"arn:aws:iam::aws:policy/us-test-1-ReadOnly"}))
 	})
 
 	It("has an existing access key but no secret", func() {
@@ -151,6 +205,112 @@ var _ = Describe("iam_user aws Component", func() {
 		Expect(err).To(MatchError("iam_user: user policy from spec test has invalid JSON: invalid character 'n' looking for beginning of object key string"))
 	})
 
+	Describe("access key rotation", func() {
+		BeforeEach(func() {
+			mockIAM.mockUserExists = true
+			mockIAM.mockUserHasTags = true
+			instance.Spec.MaxKeyAge = metav1.Duration{Duration: 90 * 24 * time.Hour}
+			instance.Spec.RotationGracePeriod = metav1.Duration{Duration: time.Hour}
+		})
+
+		setSecret := func(accessKeyID string, annotations map[string]string) {
+			accessKey := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-user.aws-credentials", Namespace: "default", Annotations: annotations},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte(accessKeyID),
+					"AWS_SECRET_ACCESS_KEY": []byte("secret"),
+				},
+			}
+			ctx.Client = fake.NewFakeClient(accessKey)
+		}
+
+		accessKeyMeta := func(accessKeyID string, age time.Duration) *iam.AccessKeyMetadata {
+			return &iam.AccessKeyMetadata{AccessKeyId: aws.String(accessKeyID), CreateDate: aws.Time(time.Now().Add(-age))}
+		}
+
+		It("does not rotate a key younger than the max age", func() {
+			setSecret("old_key", nil)
+			mockIAM.mockAccessKeys = []*iam.AccessKeyMetadata{accessKeyMeta("old_key", 10*24*time.Hour)}
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(res.RequeueAfter).To(BeNumerically("~", 80*24*time.Hour, time.Minute))
+			Expect(instance.Status.AccessKeyID).To(Equal("old_key"))
+			Expect(instance.Status.AccessKeyAge).To(Equal("240h0m0s"))
+			Expect(instance.Status.LastRotated).To(BeEmpty())
+			Expect(mockIAM.deletedAccessKeys).To(BeEmpty())
+		})
+
+		It("rotates a key older than the max age", func() {
+			setSecret("old_key", nil)
+			mockIAM.mockAccessKeys = []*iam.AccessKeyMetadata{accessKeyMeta("old_key", 100*24*time.Hour)}
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(res.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			fetchAccessKey := &corev1.Secret{}
+			err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "test-user.aws-credentials", Namespace: "default"}, fetchAccessKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(fetchAccessKey.Data["AWS_ACCESS_KEY_ID"])).To(Equal("test_access_key"))
+			Expect(fetchAccessKey.Annotations).To(HaveKeyWithValue("ridecell.io/previous-access-key-id", "old_key"))
+			Expect(fetchAccessKey.Annotations).To(HaveKey("ridecell.io/access-key-last-rotated"))
+			Expect(instance.Status.AccessKeyID).To(Equal("test_access_key"))
+			Expect(instance.Status.PreviousAccessKeyID).To(Equal("old_key"))
+			Expect(instance.Status.LastRotated).ToNot(BeEmpty())
+			// The old key keeps working until the grace period is over.
+			Expect(mockIAM.deactivatedAccessKeys).To(BeEmpty())
+			Expect(mockIAM.deletedAccessKeys).To(BeEmpty())
+		})
+
+		It("keeps the previous key during the grace period", func() {
+			setSecret("test_access_key", map[string]string{
+				"ridecell.io/previous-access-key-id":  "old_key",
+				"ridecell.io/access-key-last-rotated": time.Now().Add(-10 * time.Minute).Format(time.UnixDate),
+			})
+			mockIAM.mockAccessKeys = []*iam.AccessKeyMetadata{accessKeyMeta("test_access_key", 10*time.Minute), accessKeyMeta("old_key", 100*24*time.Hour)}
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(res.RequeueAfter).To(BeNumerically("~", 50*time.Minute, time.Minute))
+			Expect(instance.Status.PreviousAccessKeyID).To(Equal("old_key"))
+			Expect(mockIAM.deletedAccessKeys).To(BeEmpty())
+		})
+
+		It("deactivates and deletes the previous key after the grace period", func() {
+			setSecret("test_access_key", map[string]string{
+				"ridecell.io/previous-access-key-id":  "old_key",
+				"ridecell.io/access-key-last-rotated": time.Now().Add(-2 * time.Hour).Format(time.UnixDate),
+			})
+			mockIAM.mockAccessKeys = []*iam.AccessKeyMetadata{accessKeyMeta("test_access_key", 2*time.Hour), accessKeyMeta("old_key", 100*24*time.Hour)}
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.deactivatedAccessKeys).To(Equal([]string{"old_key"}))
+			Expect(mockIAM.deletedAccessKeys).To(Equal([]string{"old_key"}))
+			Expect(instance.Status.PreviousAccessKeyID).To(BeEmpty())
+			Expect(instance.Status.AccessKeyID).To(Equal("test_access_key"))
+			Expect(instance.Status.LastRotated).ToNot(BeEmpty())
+		})
+
+		It("keeps the previous key when the rotation never made it to the status", func() {
+			setSecret("test_access_key", map[string]string{
+				"ridecell.io/previous-access-key-id":  "old_key",
+				"ridecell.io/access-key-last-rotated": time.Now().Add(-10 * time.Minute).Format(time.UnixDate),
+			})
+			mockIAM.mockAccessKeys = []*iam.AccessKeyMetadata{accessKeyMeta("test_access_key", 10*time.Minute), accessKeyMeta("old_key", 100*24*time.Hour)}
+			instance.Status.PreviousAccessKeyID = ""
+			instance.Status.LastRotated = ""
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.deactivatedAccessKeys).To(BeEmpty())
+			Expect(mockIAM.deletedAccessKeys).To(BeEmpty())
+			Expect(instance.Status.PreviousAccessKeyID).To(Equal("old_key"))
+		})
+	})
+
 	Describe("finalizer tests", func() {
 
 		It("adds finalizer when there isn't one", func() {
@@ -186,6 +346,18 @@ var _ = Describe("iam_user aws Component", func() {
 			Expect(mockIAM.deleteUser).To(BeTrue())
 		})
 
+		It("detaches managed policies before deleting the user", func() {
+			mockIAM.finalizerTest = true
+			mockIAM.mockUserExists = true
+			mockIAM.mockAttachedArns = []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}
+			currentTime := metav1.Now()
+			instance.ObjectMeta.SetDeletionTimestamp(&currentTime)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockIAM.detachedPolicies).To(Equal([]string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}))
+			Expect(mockIAM.deleteUser).To(BeTrue())
+		})
+
 		It("simulates user not existing during finalizer deletion", func() {
 			currentTime := metav1.Now()
 			mockIAM.finalizerTest = true
@@ -302,6 +474,12 @@ func (m *mockIAMClient) DeleteAccessKey(input *iam.DeleteAccessKeyInput) (*iam.D
 	if aws.StringValue(input.AccessKeyId) == "test_access_key" || m.finalizerTest {
 		return &iam.DeleteAccessKeyOutput{}, nil
 	}
+	for _, accessKeyMeta := range m.mockAccessKeys {
+		if aws.StringValue(accessKeyMeta.AccessKeyId) == aws.StringValue(input.AccessKeyId) {
+			m.deletedAccessKeys = append(m.deletedAccessKeys, aws.StringValue(input.AccessKeyId))
+			return &iam.DeleteAccessKeyOutput{}, nil
+		}
+	}
 	return &iam.DeleteAccessKeyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_deleteaccesskey: access key does not exist", errors.New(""))
 }
 
@@ -309,6 +487,9 @@ func (m *mockIAMClient) ListAccessKeys(input *iam.ListAccessKeysInput) (*iam.Lis
 	if aws.StringValue(input.UserName) != instance.Spec.UserName || (!m.mockUserExists && m.finalizerTest) {
 		return &iam.ListAccessKeysOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_listaccesskeys: username did not match spec", errors.New(""))
 	}
+	if m.mockAccessKeys != nil {
+		return &iam.ListAccessKeysOutput{AccessKeyMetadata: m.mockAccessKeys}, nil
+	}
 	if m.mockHasAccessKey {
 		return &iam.ListAccessKeysOutput{AccessKeyMetadata: []*iam.AccessKeyMetadata{&iam.AccessKeyMetadata{AccessKeyId: aws.String("test_access_key")}}}, nil
 	}
@@ -349,3 +530,40 @@ func (m *mockIAMClient) DeleteUser(input *iam.DeleteUserInput) (*iam.DeleteUserO
 	m.deleteUser = true
 	return &iam.DeleteUserOutput{}, nil
 }
+
+func (m *mockIAMClient) ListAttachedUserPolicies(input *iam.ListAttachedUserPoliciesInput) (*iam.ListAttachedUserPoliciesOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName || (!m.mockUserExists && m.finalizerTest) {
+		return &iam.ListAttachedUserPoliciesOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_listattacheduserpolicies: username did not match spec", errors.New(""))
+	}
+	attachedPolicies := []*iam.AttachedPolicy{}
+	for _, policyArn := range m.mockAttachedArns {
+		attachedPolicies = append(attachedPolicies, &iam.AttachedPolicy{PolicyArn: aws.String(policyArn)})
+	}
+	return &iam.ListAttachedUserPoliciesOutput{AttachedPolicies: attachedPolicies}, nil
+}
+
+func (m *mockIAMClient) AttachUserPolicy(input *iam.AttachUserPolicyInput) (*iam.AttachUserPolicyOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.AttachUserPolicyOutput{}, errors.New("awsmock_attachuserpolicy: username did not match spec")
+	}
+	m.attachedPolicies = append(m.attachedPolicies, aws.StringValue(input.PolicyArn))
+	return &iam.AttachUserPolicyOutput{}, nil
+}
+
+func (m *mockIAMClient) DetachUserPolicy(input *iam.DetachUserPolicyInput) (*iam.DetachUserPolicyOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.DetachUserPolicyOutput{}, errors.New("awsmock_detachuserpolicy: username did not match spec")
+	}
+	m.detachedPolicies = append(m.detachedPolicies, aws.StringValue(input.PolicyArn))
+	return &iam.DetachUserPolicyOutput{}, nil
+}
+
+func (m *mockIAMClient) UpdateAccessKey(input *iam.UpdateAccessKeyInput) (*iam.UpdateAccessKeyOutput, error) {
+	if aws.StringValue(input.UserName) != instance.Spec.UserName {
+		return &iam.UpdateAccessKeyOutput{}, awserr.New(iam.ErrCodeNoSuchEntityException, "awsmock_updateaccesskey: username did not match spec", errors.New(""))
+	}
+	if aws.StringValue(input.Status) == iam.StatusTypeInactive {
+		m.deactivatedAccessKeys = append(m.deactivatedAccessKeys, aws.StringValue(input.AccessKeyId))
+	}
+	return &iam.UpdateAccessKeyOutput{}, nil
+}
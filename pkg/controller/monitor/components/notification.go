@@ -18,6 +18,7 @@ package components
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
@@ -28,6 +29,7 @@ import (
 
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
 	monitoringv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/monitoring/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	pagerduty "github.com/heimweh/go-pagerduty/pagerduty"
 	alertmconfig "github.com/prometheus/alertmanager/config"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -36,6 +38,9 @@ import (
 
 const notificationFinalizer = "finalizer.notification.monitoring.ridecell.io"
 
+// HTTP client for PagerDuty, instrumented so API calls show up in the operator metrics.
+var pagerdutyHTTPClient = metrics.InstrumentHTTPClient("pagerduty", http.DefaultClient)
+
 type notificationComponent struct {
 	PgBaseURL string
 }
@@ -71,9 +76,9 @@ func (comp *notificationComponent) Reconcile(ctx *components.ComponentContext) (
 		return components.Result{}, nil
 	}
 
-	client, _ := pagerduty.NewClient(&pagerduty.Config{Token: os.Getenv("PG_API_KEY"), BaseURL: "https://api.pagerduty.com"})
+	client, _ := pagerduty.NewClient(&pagerduty.Config{Token: os.Getenv("PG_API_KEY"), BaseURL: "https://api.pagerduty.com", HTTPClient: pagerdutyHTTPClient})
 	if len(os.Getenv("PG_MOCK_URL")) > 0 {
-		client, _ = pagerduty.NewClient(&pagerduty.Config{Token: os.Getenv("PG_API_KEY"), BaseURL: os.Getenv("PG_MOCK_URL")})
+		client, _ = pagerduty.NewClient(&pagerduty.Config{Token: os.Getenv("PG_API_KEY"), BaseURL: os.Getenv("PG_MOCK_URL"), HTTPClient: pagerdutyHTTPClient})
 	}
 
 	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
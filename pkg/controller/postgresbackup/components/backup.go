@@ -0,0 +1,254 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+const PostgresBackupFinalizer = "postgresbackup.finalizer"
+
+// How long the upload URL for the dump is valid, this has to cover the job waiting to be scheduled and the dump itself.
+const uploadURLExpiry = 12 * time.Hour
+
+type S3Factory func(region string) (s3iface.S3API, error)
+
+type backupComponent struct {
+	s3Factory S3Factory
+}
+
+func realS3Factory(region string) (s3iface.S3API, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(metrics.InstrumentAWSSession(sess)), nil
+}
+
+func NewBackup() *backupComponent {
+	return &backupComponent{s3Factory: realS3Factory}
+}
+
+func (comp *backupComponent) InjectS3Factory(factory S3Factory) {
+	comp.s3Factory = factory
+}
+
+func (_ *backupComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&batchv1.Job{},
+	}
+}
+
+func (_ *backupComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (comp *backupComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresBackup)
+
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !helpers.ContainsFinalizer(PostgresBackupFinalizer, instance) {
+			instance.ObjectMeta.Finalizers = helpers.AppendFinalizer(PostgresBackupFinalizer, instance)
+			err := ctx.Update(ctx.Context, instance)
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "postgres_backup: failed to update instance while adding finalizer")
+			}
+		}
+	} else {
+		if helpers.ContainsFinalizer(PostgresBackupFinalizer, instance) {
+			err := comp.deleteDependencies(ctx, instance)
+			if err != nil {
+				return components.Result{}, err
+			}
+			// All operations complete, remove finalizer
+			instance.ObjectMeta.Finalizers = helpers.RemoveFinalizer(PostgresBackupFinalizer, instance)
+			err = ctx.Update(ctx.Context, instance.DeepCopy())
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "postgres_backup: failed to update object while removing finalizer")
+			}
+		}
+		// If object is being deleted and has no finalizer just exit.
+		return components.Result{}, nil
+	}
+
+	// Check if our object needs to be cleaned up
+	if instance.Spec.TTL.Duration != 0 && metav1.Now().After(instance.ObjectMeta.CreationTimestamp.Add(instance.Spec.TTL.Duration)) {
+		err := ctx.Client.Delete(ctx.Context, instance)
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "postgres_backup: failed to delete itself")
+		}
+		return components.Result{Requeue: true}, nil
+	}
+
+	// Backups are one-shot, nothing left to do once the dump is uploaded. The job is only cleaned up now that
+	// Ready has been saved, otherwise a failed status write would start the backup again.
+	if instance.Status.Status == dbv1beta1.StatusReady {
+		return components.Result{}, comp.deleteJob(ctx, instance)
+	}
+
+	job := &batchv1.Job{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Name + "-backup", Namespace: instance.Namespace}, job)
+	if err != nil && k8serrors.IsNotFound(err) {
+		return comp.startBackup(ctx, instance)
+	} else if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_backup: error getting backup job")
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			// Leave the job around for debugging, deleting it starts the backup again.
+			message := fmt.Sprintf("Backup job %s/%s failed: %s", job.Namespace, job.Name, condition.Message)
+			ctx.Eventf(corev1.EventTypeWarning, "BackupFailed", "%s", message)
+			return components.Result{StatusModifier: setStatus(dbv1beta1.StatusError, message)}, nil
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		// Still running, the job watch will trigger a reconcile when it finishes.
+		return components.Result{StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Backup job %s/%s running", job.Namespace, job.Name))}, nil
+	}
+
+	location := fmt.Sprintf("s3://%s/%s", instance.Spec.BucketName, instance.Spec.Key)
+	ctx.Eventf(corev1.EventTypeNormal, "BackupReady", "Backed up database %s to %s", instance.Spec.Database, location)
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresBackup)
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.Message = "Backup complete"
+		instance.Status.Location = location
+		instance.Status.CompletedAt = time.Now().Format(time.UnixDate)
+		return nil
+	}}, nil
+}
+
+func (comp *backupComponent) startBackup(ctx *components.ComponentContext, instance *dbv1beta1.PostgresBackup) (components.Result, error) {
+	if instance.Spec.BucketName == "" {
+		return components.Result{}, errors.New("postgres_backup: bucketName is required")
+	}
+
+	db := &dbv1beta1.PostgresDatabase{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.Database, Namespace: instance.Namespace}, db)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_backup: error getting postgres database %s", instance.Spec.Database)
+	}
+	if db.Status.Status != dbv1beta1.StatusReady {
+		return components.Result{RequeueAfter: 30 * time.Second, StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Waiting for postgres database %s", db.Name))}, nil
+	}
+
+	s3Service, err := comp.s3Factory(instance.Spec.Region)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_backup: failed to get s3 service")
+	}
+	req, _ := s3Service.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(instance.Spec.BucketName),
+		Key:    aws.String(instance.Spec.Key),
+	})
+	urlStr, err := req.Presign(uploadURLExpiry)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_backup: failed to presign s3 url")
+	}
+
+	extra := map[string]interface{}{}
+	extra["database"] = db
+	_, _, err = ctx.CreateOrUpdate("url-secret.yml.tpl", extra, func(_goalObj, existingObj runtime.Object) error {
+		existing := existingObj.(*corev1.Secret)
+		existing.Data = map[string][]byte{"url": []byte(urlStr)}
+		return nil
+	})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_backup: error creating upload url secret")
+	}
+	obj, err := ctx.GetTemplate("job.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, err
+	}
+	job := obj.(*batchv1.Job)
+	err = controllerutil.SetControllerReference(instance, job, ctx.Scheme)
+	if err != nil {
+		return components.Result{}, err
+	}
+	err = ctx.Create(ctx.Context, job)
+	if err != nil {
+		return components.Result{Requeue: true}, errors.Wrapf(err, "postgres_backup: error creating backup job %s/%s", job.Namespace, job.Name)
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "BackupStarted", "Backing up database %s to s3://%s/%s", db.Spec.DatabaseName, instance.Spec.BucketName, instance.Spec.Key)
+	return components.Result{StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Backup job %s/%s started", job.Namespace, job.Name))}, nil
+}
+
+func (comp *backupComponent) deleteDependencies(ctx *components.ComponentContext, instance *dbv1beta1.PostgresBackup) error {
+	if instance.Status.Location == "" {
+		// Never uploaded anything.
+		return nil
+	}
+	s3Service, err := comp.s3Factory(instance.Spec.Region)
+	if err != nil {
+		return errors.Wrapf(err, "postgres_backup: failed to get s3 service")
+	}
+	// Deleting a missing key is not an error in S3.
+	_, err = s3Service.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(instance.Spec.BucketName),
+		Key:    aws.String(instance.Spec.Key),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "postgres_backup: failed to delete %s", instance.Status.Location)
+	}
+	return nil
+}
+
+func (comp *backupComponent) deleteJob(ctx *components.ComponentContext, instance *dbv1beta1.PostgresBackup) error {
+	meta := metav1.ObjectMeta{Name: instance.Name + "-backup", Namespace: instance.Namespace}
+	err := ctx.Delete(ctx.Context, &batchv1.Job{ObjectMeta: meta}, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "postgres_backup: error deleting backup job")
+	}
+	meta.Name = instance.Name + "-backup-url"
+	err = ctx.Delete(ctx.Context, &corev1.Secret{ObjectMeta: meta})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "postgres_backup: error deleting upload url secret")
+	}
+	return nil
+}
+
+func setStatus(status, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresBackup)
+		instance.Status.Status = status
+		instance.Status.Message = message
+		return nil
+	}
+}
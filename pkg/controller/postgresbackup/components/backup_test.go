@@ -0,0 +1,217 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	pbcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresbackup/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+type mockBackupS3Client struct {
+	s3iface.S3API
+	deleted []string
+}
+
+var _ = Describe("PostgresBackup Backup Component", func() {
+	comp := pbcomponents.NewBackup()
+	var mockS3 *mockBackupS3Client
+	var db *dbv1beta1.PostgresDatabase
+
+	BeforeEach(func() {
+		mockS3 = &mockBackupS3Client{}
+		comp.InjectS3Factory(func(region string) (s3iface.S3API, error) {
+			return mockS3, nil
+		})
+
+		instance.ObjectMeta.Finalizers = []string{"postgresbackup.finalizer"}
+		instance.Spec.BucketName = "ridecell-backups"
+		instance.Spec.Region = "us-west-2"
+		instance.Spec.Key = "postgres-backup/summon-dev/foo-dev-backup.dump"
+
+		db = &dbv1beta1.PostgresDatabase{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
+			Spec: dbv1beta1.PostgresDatabaseSpec{
+				DatabaseName: "foo_dev",
+				Owner:        "foo_dev",
+			},
+			Status: dbv1beta1.PostgresDatabaseStatus{
+				Status: dbv1beta1.StatusReady,
+				AdminConnection: dbv1beta1.PostgresConnection{
+					Host:              "mydb",
+					Port:              5432,
+					Username:          "myuser",
+					PasswordSecretRef: helpers.SecretRef{Name: "mysecret", Key: "password"},
+					Database:          "postgres",
+				},
+			},
+		}
+	})
+
+	// Set up the client after the test has changed the instance.
+	setupClient := func(objs ...runtime.Object) {
+		ctx.Client = fake.NewFakeClient(append([]runtime.Object{instance, db}, objs...)...)
+	}
+
+	getJob := func() (*batchv1.Job, error) {
+		job := &batchv1.Job{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-backup-backup", Namespace: "summon-dev"}, job)
+		return job, err
+	}
+
+	It("adds a finalizer", func() {
+		instance.ObjectMeta.Finalizers = nil
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(ConsistOf("postgresbackup.finalizer"))
+	})
+
+	It("starts a backup job", func() {
+		// Presigning doesn't talk to AWS, a real client with fake credentials works.
+		comp.InjectS3Factory(func(region string) (s3iface.S3API, error) {
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region:      aws.String(region),
+				Credentials: credentials.NewStaticCredentials("garbage", "garbage", ""),
+			}))
+			return s3.New(sess), nil
+		})
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusCreating))
+		job, err := getJob()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(job.OwnerReferences).To(HaveLen(1))
+		Expect(job.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "PGDATABASE", Value: "foo_dev"}))
+		Expect(job.Spec.Template.Spec.Containers[0].Env[0].Value).To(BeEmpty())
+		Expect(job.Spec.Template.Spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("foo-dev-backup-backup-url"))
+
+		secret := &corev1.Secret{}
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-backup-backup-url", Namespace: "summon-dev"}, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(secret.Data["url"])).To(ContainSubstring("postgres-backup/summon-dev/foo-dev-backup.dump"))
+		Expect(string(secret.Data["url"])).To(ContainSubstring("X-Amz-Signature="))
+	})
+
+	It("waits for the database to be ready", func() {
+		db.Status.Status = dbv1beta1.StatusCreating
+		setupClient()
+
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).ToNot(BeZero())
+		_, err = getJob()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("errors without a bucket", func() {
+		instance.Spec.BucketName = ""
+		setupClient()
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+
+	It("reports a failed job", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-backup-backup", Namespace: "summon-dev"},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}},
+			},
+		}
+		setupClient(job)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusError))
+		Expect(instance.Status.Message).To(ContainSubstring("BackoffLimitExceeded"))
+	})
+
+	It("finishes once the job succeeds", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-backup-backup", Namespace: "summon-dev"},
+			Status:     batchv1.JobStatus{Succeeded: 1},
+		}
+		setupClient(job)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+		Expect(instance.Status.Location).To(Equal("s3://ridecell-backups/postgres-backup/summon-dev/foo-dev-backup.dump"))
+		Expect(instance.Status.CompletedAt).ToNot(BeEmpty())
+		// Kept until Ready has been saved.
+		_, err := getJob()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("cleans up the job once ready", func() {
+		instance.Status.Status = dbv1beta1.StatusReady
+		meta := metav1.ObjectMeta{Name: "foo-dev-backup-backup", Namespace: "summon-dev"}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-backup-backup-url", Namespace: "summon-dev"}}
+		setupClient(&batchv1.Job{ObjectMeta: meta, Status: batchv1.JobStatus{Succeeded: 1}}, secret)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		_, err := getJob()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-backup-backup-url", Namespace: "summon-dev"}, &corev1.Secret{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("deletes itself once the ttl expires", func() {
+		instance.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		instance.Spec.TTL = metav1.Duration{Duration: time.Hour}
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-backup", Namespace: "summon-dev"}, &dbv1beta1.PostgresBackup{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("deletes the dump from s3 on deletion", func() {
+		now := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&now)
+		instance.Status.Location = "s3://ridecell-backups/postgres-backup/summon-dev/foo-dev-backup.dump"
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+		Expect(mockS3.deleted).To(ConsistOf("postgres-backup/summon-dev/foo-dev-backup.dump"))
+	})
+})
+
+func (m *mockBackupS3Client) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	if aws.StringValue(input.Bucket) != "ridecell-backups" {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.deleted = append(m.deleted, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
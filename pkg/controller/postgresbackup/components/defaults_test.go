@@ -0,0 +1,51 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pbcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresbackup/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresBackup Defaults Component", func() {
+	AfterEach(func() {
+		os.Unsetenv("POSTGRES_BACKUP_BUCKET")
+	})
+
+	It("sets the bucket and key", func() {
+		os.Setenv("POSTGRES_BACKUP_BUCKET", "ridecell-backups")
+		comp := pbcomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.BucketName).To(Equal("ridecell-backups"))
+		Expect(instance.Spec.Key).To(Equal("postgres-backup/summon-dev/foo-dev-backup.dump"))
+	})
+
+	It("does not override a set bucket and key", func() {
+		os.Setenv("POSTGRES_BACKUP_BUCKET", "ridecell-backups")
+		instance.Spec.BucketName = "other"
+		instance.Spec.Key = "foo.dump"
+		comp := pbcomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.BucketName).To(Equal("other"))
+		Expect(instance.Spec.Key).To(Equal("foo.dump"))
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgresbackup
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	pbcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresbackup/components"
+)
+
+// Add creates a new postgres backup Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	c, err := components.NewReconciler("postgres-backup-controller", mgr, &dbv1beta1.PostgresBackup{}, Templates, []components.Component{
+		pbcomponents.NewDefaults(),
+		pbcomponents.NewBackup(),
+	})
+	if err != nil {
+		return err
+	}
+
+	genericChannel := make(chan event.GenericEvent)
+
+	go watchTTL(genericChannel, c.GetComponentClient())
+
+	err = c.Controller.Watch(
+		&source.Channel{Source: genericChannel},
+		&handler.EnqueueRequestForObject{},
+	)
+	return err
+}
+
+func watchTTL(watchChannel chan event.GenericEvent, k8sClient client.Client) {
+	for {
+		backups := &dbv1beta1.PostgresBackupList{}
+		err := k8sClient.List(context.TODO(), &client.ListOptions{}, backups)
+		if err != nil {
+			// Make this do something useful or ignore it.
+			panic(err)
+		}
+
+		for n, backup := range backups.Items {
+			// ignore object early if object has no TTL set
+			if backup.Spec.TTL.Duration == 0 {
+				continue
+			}
+
+			// Check if our object is expired
+			deletionTime := backup.ObjectMeta.CreationTimestamp.Add(backup.Spec.TTL.Duration)
+			if time.Now().After(deletionTime) {
+				// Send a generic event to our watched channel to cause a reconcile of specified object
+				watchChannel <- event.GenericEvent{Object: &backups.Items[n], Meta: &backups.Items[n]}
+			}
+		}
+		time.Sleep(time.Minute)
+	}
+}
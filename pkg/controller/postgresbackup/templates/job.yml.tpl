@@ -0,0 +1,83 @@
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-backup
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: backup
+    app.kubernetes.io/instance: {{ .Instance.Name }}-backup
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: backup
+        app.kubernetes.io/instance: {{ .Instance.Name }}-backup
+        app.kubernetes.io/component: database
+        app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+        app.kubernetes.io/managed-by: ridecell-operator
+    spec:
+      restartPolicy: Never
+      volumes:
+      - name: dump
+        emptyDir: {}
+      initContainers:
+      # Dump to a local file first so the upload knows the size up front.
+      - name: dump
+        image: postgres:13-alpine
+        command:
+        - pg_dump
+        - --format=custom
+        - --no-owner
+        - --file=/dump/database.dump
+        env:
+        {{- with .Extra.database }}
+        - name: PGHOST
+          value: {{ .Status.AdminConnection.Host | quote }}
+        - name: PGPORT
+          value: {{ .Status.AdminConnection.Port | default 5432 | quote }}
+        - name: PGUSER
+          value: {{ .Status.AdminConnection.Username | quote }}
+        - name: PGDATABASE
+          value: {{ .Spec.DatabaseName | quote }}
+        - name: PGSSLMODE
+          value: {{ .Status.AdminConnection.SSLMode | default "require" | quote }}
+        - name: PGPASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Status.AdminConnection.PasswordSecretRef.Name }}
+              key: {{ .Status.AdminConnection.PasswordSecretRef.Key | default "password" }}
+        {{- end }}
+        terminationMessagePolicy: FallbackToLogsOnError
+        resources:
+          requests:
+            memory: 256M
+            cpu: 100m
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
+      containers:
+      - name: upload
+        image: curlimages/curl:7.73.0
+        command:
+        - curl
+        - --fail
+        - --silent
+        - --show-error
+        - --upload-file
+        - /dump/database.dump
+        - $(UPLOAD_URL)
+        env:
+        # Presigned URLs are bearer credentials, keep them out of the Job spec.
+        - name: UPLOAD_URL
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Instance.Name }}-backup-url
+              key: url
+        terminationMessagePolicy: FallbackToLogsOnError
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
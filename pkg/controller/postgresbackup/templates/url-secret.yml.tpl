@@ -0,0 +1,12 @@
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Instance.Name }}-backup-url
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: backup
+    app.kubernetes.io/instance: {{ .Instance.Name }}-backup
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+data: {}
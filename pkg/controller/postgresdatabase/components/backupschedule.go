@@ -0,0 +1,81 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+type backupScheduleComponent struct{}
+
+func NewBackupSchedule() *backupScheduleComponent {
+	return &backupScheduleComponent{}
+}
+
+func (_ *backupScheduleComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *backupScheduleComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	return instance.Spec.Backups.Interval.Duration != 0 && instance.DeletionTimestamp.IsZero() && instance.Status.Status == dbv1beta1.StatusReady
+}
+
+func (comp *backupScheduleComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	interval := instance.Spec.Backups.Interval.Duration
+
+	now := time.Now()
+	if instance.Status.LastBackup.CreatedAt != "" {
+		lastBackup, err := time.Parse(time.UnixDate, instance.Status.LastBackup.CreatedAt)
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "backup_schedule: failed to parse last backup time")
+		}
+		if next := lastBackup.Add(interval); now.Before(next) {
+			return components.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	name := fmt.Sprintf("%s-%s", instance.Name, now.UTC().Format("20060102-150405"))
+	extra := map[string]interface{}{}
+	extra["name"] = name
+	obj, err := ctx.GetTemplate("backup.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, err
+	}
+	// Not owned by the instance, backups have to survive the database being deleted.
+	err = ctx.Create(ctx.Context, obj)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "backup_schedule: error creating postgres backup %s", name)
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "BackupScheduled", "Created postgres backup %s", name)
+	return components.Result{RequeueAfter: interval, StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.LastBackup.Name = name
+		instance.Status.LastBackup.CreatedAt = now.Format(time.UnixDate)
+		return nil
+	}}, nil
+}
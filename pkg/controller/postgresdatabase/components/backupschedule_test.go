@@ -0,0 +1,100 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresDatabase BackupSchedule Component", func() {
+	comp := pdcomponents.NewBackupSchedule()
+
+	BeforeEach(func() {
+		instance.Spec.Backups = dbv1beta1.PostgresDatabaseBackupsSpec{
+			Interval:   metav1.Duration{Duration: 24 * time.Hour},
+			TTL:        metav1.Duration{Duration: 7 * 24 * time.Hour},
+			BucketName: "ridecell-backups",
+		}
+		instance.Status.Status = dbv1beta1.StatusReady
+	})
+
+	Describe("IsReconcilable", func() {
+		It("is not reconcilable without an interval", func() {
+			instance.Spec.Backups.Interval.Duration = 0
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
+
+		It("is not reconcilable until the database is ready", func() {
+			instance.Status.Status = dbv1beta1.StatusCreating
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
+
+		It("is reconcilable with an interval", func() {
+			Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		})
+	})
+
+	It("creates the first backup", func() {
+		ctx.Client = fake.NewFakeClient(instance)
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(24 * time.Hour))
+		Expect(res.StatusModifier(instance)).To(Succeed())
+		Expect(instance.Status.LastBackup.Name).To(HavePrefix("foo-dev-"))
+		Expect(instance.Status.LastBackup.CreatedAt).ToNot(BeEmpty())
+
+		backup := &dbv1beta1.PostgresBackup{}
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Status.LastBackup.Name, Namespace: "summon-dev"}, backup)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(backup.OwnerReferences).To(BeEmpty())
+		Expect(backup.Spec.Database).To(Equal("foo-dev"))
+		Expect(backup.Spec.BucketName).To(Equal("ridecell-backups"))
+		Expect(backup.Spec.TTL.Duration).To(Equal(7 * 24 * time.Hour))
+	})
+
+	It("waits for the interval to pass", func() {
+		instance.Status.LastBackup = dbv1beta1.PostgresDatabaseBackupStatus{
+			Name:      "foo-dev-old",
+			CreatedAt: time.Now().Add(-time.Hour).Format(time.UnixDate),
+		}
+		ctx.Client = fake.NewFakeClient(instance)
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically("~", 23*time.Hour, time.Minute))
+		Expect(res.StatusModifier).To(BeNil())
+	})
+
+	It("creates another backup once the interval has passed", func() {
+		instance.Status.LastBackup = dbv1beta1.PostgresDatabaseBackupStatus{
+			Name:      "foo-dev-old",
+			CreatedAt: time.Now().Add(-25 * time.Hour).Format(time.UnixDate),
+		}
+		ctx.Client = fake.NewFakeClient(instance)
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.LastBackup.Name).ToNot(Equal("foo-dev-old"))
+	})
+})
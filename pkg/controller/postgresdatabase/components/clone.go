@@ -0,0 +1,397 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+	"github.com/Ridecell/ridecell-operator/pkg/utils"
+)
+
+type cloneComponent struct {
+	rdsAPI rdsiface.RDSAPI
+}
+
+func NewClone() *cloneComponent {
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
+	rdsService := rds.New(sess)
+	return &cloneComponent{rdsAPI: rdsService}
+}
+
+func (comp *cloneComponent) InjectRDSAPI(rdsapi rdsiface.RDSAPI) {
+	comp.rdsAPI = rdsapi
+}
+
+func (_ *cloneComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&batchv1.Job{},
+	}
+}
+
+func (_ *cloneComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	// The database component marks a clone as pending when it creates a new database.
+	return instance.Status.DatabaseStatus == dbv1beta1.StatusReady && instance.Status.Clone.Status != "" && instance.Status.Clone.Status != dbv1beta1.StatusReady && instance.DeletionTimestamp.IsZero()
+}
+
+func (comp *cloneComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	cloneFrom := instance.Spec.CloneFrom
+	if cloneFrom == nil {
+		return components.Result{}, errors.New("clone: cloneFrom was removed before the clone finished, put it back or delete the database to start over")
+	}
+
+	job := &batchv1.Job{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s-clone", instance.Name), Namespace: instance.Namespace}, job)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return components.Result{}, errors.Wrapf(err, "clone: error getting clone job")
+	}
+	if err == nil {
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				// Leave the job around for debugging, deleting it starts the copy again. The restored instance
+				// isn't needed to look into it and is recreated on retry, so don't keep paying for it.
+				err = comp.cleanupSource(ctx, instance)
+				if err != nil {
+					return components.Result{}, err
+				}
+				return components.Result{}, errors.Errorf("clone: clone job %s/%s failed, drop the partial copy and delete the job to retry", job.Namespace, job.Name)
+			}
+		}
+	}
+
+	var source *dbv1beta1.PostgresConnection
+	var res components.Result
+	if cloneFrom.PostgresDatabaseRef.Name != "" && cloneFrom.RDSSnapshotRef.Name != "" {
+		return components.Result{}, errors.New("clone: only one of postgresDatabaseRef and rdsSnapshotRef can be set")
+	} else if cloneFrom.PostgresDatabaseRef.Name != "" {
+		source, res, err = comp.databaseSource(ctx, instance)
+	} else if cloneFrom.RDSSnapshotRef.Name != "" {
+		source, res, err = comp.snapshotSource(ctx, instance)
+	} else {
+		return components.Result{}, errors.New("clone: one of postgresDatabaseRef or rdsSnapshotRef must be set")
+	}
+	if err != nil || source == nil {
+		return res, err
+	}
+
+	return comp.copy(ctx, instance, source)
+}
+
+// Name of the secret holding the source password, either copied from another namespace or generated for a restored snapshot.
+func cloneSecretName(instance *dbv1beta1.PostgresDatabase) string {
+	return fmt.Sprintf("%s.clone-source", instance.Name)
+}
+
+// Find the connection for another PostgresDatabase and copy its password secret into our namespace for the job.
+func (comp *cloneComponent) databaseSource(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) (*dbv1beta1.PostgresConnection, components.Result, error) {
+	ref := instance.Spec.CloneFrom.PostgresDatabaseRef
+	if ref.Namespace == "" {
+		ref.Namespace = instance.Namespace
+	}
+	sourceDB := &dbv1beta1.PostgresDatabase{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, sourceDB)
+	if err != nil {
+		return nil, components.Result{}, errors.Wrapf(err, "clone: unable to get source database %s/%s", ref.Namespace, ref.Name)
+	}
+	if sourceDB.Status.Status != dbv1beta1.StatusReady {
+		return nil, components.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	sourceSecret := &corev1.Secret{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: sourceDB.Status.AdminConnection.PasswordSecretRef.Name, Namespace: sourceDB.Namespace}, sourceSecret)
+	if err != nil {
+		return nil, components.Result{}, errors.Wrapf(err, "clone: unable to get source password secret")
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: cloneSecretName(instance), Namespace: instance.Namespace},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx.Context, ctx, secret, func(existingObj runtime.Object) error {
+		existing := existingObj.(*corev1.Secret)
+		existing.Type = corev1.SecretTypeOpaque
+		existing.Data = sourceSecret.Data
+		return controllerutil.SetControllerReference(instance, existing, ctx.Scheme)
+	})
+	if err != nil {
+		return nil, components.Result{}, errors.Wrap(err, "clone: failed to copy source password secret")
+	}
+
+	source := sourceDB.Status.AdminConnection.DeepCopy()
+	source.Database = sourceDB.Spec.DatabaseName
+	source.PasswordSecretRef.Name = secret.Name
+	return source, components.Result{}, nil
+}
+
+// Restore the snapshot to a temporary RDS instance and return the connection once it is ready.
+func (comp *cloneComponent) snapshotSource(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) (*dbv1beta1.PostgresConnection, components.Result, error) {
+	cloneFrom := instance.Spec.CloneFrom
+	if cloneFrom.DatabaseName == "" {
+		return nil, components.Result{}, errors.New("clone: databaseName is required to clone from an RDS snapshot")
+	}
+	ref := cloneFrom.RDSSnapshotRef
+	if ref.Namespace == "" {
+		ref.Namespace = instance.Namespace
+	}
+	snapshot := &dbv1beta1.RDSSnapshot{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, snapshot)
+	if err != nil {
+		return nil, components.Result{}, errors.Wrapf(err, "clone: unable to get snapshot %s/%s", ref.Namespace, ref.Name)
+	}
+	if snapshot.Status.Status != dbv1beta1.StatusReady {
+		return nil, components.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	restoreID := fmt.Sprintf("%s-clone", instance.Name)
+	describeOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(restoreID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != rds.ErrCodeDBInstanceNotFoundFault {
+			return nil, components.Result{}, errors.Wrapf(err, "clone: unable to describe db instance %s", restoreID)
+		}
+		err = comp.restoreSnapshot(ctx, instance, snapshot, restoreID)
+		if err != nil {
+			return nil, components.Result{}, err
+		}
+		return nil, components.Result{RequeueAfter: time.Minute, StatusModifier: setCloneStatus(dbv1beta1.CloneStatusRestoring, fmt.Sprintf("Restoring snapshot %s to %s", snapshot.Status.SnapshotID, restoreID))}, nil
+	}
+
+	if len(describeOutput.DBInstances) == 0 {
+		return nil, components.Result{RequeueAfter: time.Minute, StatusModifier: setCloneStatus(dbv1beta1.CloneStatusRestoring, fmt.Sprintf("Waiting for restored instance %s", restoreID))}, nil
+	}
+	restored := describeOutput.DBInstances[0]
+	if aws.StringValue(restored.DBInstanceStatus) != "available" || restored.PendingModifiedValues != nil && restored.PendingModifiedValues.MasterUserPassword != nil {
+		return nil, components.Result{RequeueAfter: time.Minute, StatusModifier: setCloneStatus(dbv1beta1.CloneStatusRestoring, fmt.Sprintf("Waiting for restored instance %s, currently %s", restoreID, aws.StringValue(restored.DBInstanceStatus)))}, nil
+	}
+
+	// The master password is whatever it was when the snapshot was taken, so reset it to one we know.
+	secret := &corev1.Secret{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: cloneSecretName(instance), Namespace: instance.Namespace}, secret)
+	if err != nil && k8serrors.IsNotFound(err) {
+		password, err := utils.RandomBytes(32)
+		if err != nil {
+			return nil, components.Result{}, errors.Wrap(err, "clone: failed to generate password")
+		}
+		_, err = comp.rdsAPI.ModifyDBInstance(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: aws.String(restoreID),
+			MasterUserPassword:   aws.String(string(password)),
+			ApplyImmediately:     aws.Bool(true),
+		})
+		if err != nil {
+			return nil, components.Result{}, errors.Wrapf(err, "clone: failed to reset password on %s", restoreID)
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: cloneSecretName(instance), Namespace: instance.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"password": password},
+		}
+		err = controllerutil.SetControllerReference(instance, secret, ctx.Scheme)
+		if err != nil {
+			return nil, components.Result{}, err
+		}
+		err = ctx.Create(ctx.Context, secret)
+		if err != nil {
+			return nil, components.Result{}, errors.Wrap(err, "clone: failed to create password secret")
+		}
+		return nil, components.Result{RequeueAfter: time.Minute, StatusModifier: setCloneStatus(dbv1beta1.CloneStatusRestoring, fmt.Sprintf("Resetting password on restored instance %s", restoreID))}, nil
+	} else if err != nil {
+		return nil, components.Result{}, errors.Wrap(err, "clone: failed to get password secret")
+	}
+
+	return &dbv1beta1.PostgresConnection{
+		Host:              aws.StringValue(restored.Endpoint.Address),
+		Port:              int(aws.Int64Value(restored.Endpoint.Port)),
+		Username:          aws.StringValue(restored.MasterUsername),
+		Database:          cloneFrom.DatabaseName,
+		PasswordSecretRef: helpers.SecretRef{Name: secret.Name, Key: "password"},
+	}, components.Result{}, nil
+}
+
+// Start restoring the snapshot, using the network settings of the instance it was taken from.
+func (comp *cloneComponent) restoreSnapshot(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase, snapshot *dbv1beta1.RDSSnapshot, restoreID string) error {
+	describeOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(snapshot.Spec.RDSInstanceID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "clone: unable to describe snapshot source instance %s", snapshot.Spec.RDSInstanceID)
+	}
+	if len(describeOutput.DBInstances) == 0 {
+		return errors.Errorf("clone: snapshot source instance %s not found", snapshot.Spec.RDSInstanceID)
+	}
+	sourceInstance := describeOutput.DBInstances[0]
+	securityGroupIDs := []*string{}
+	for _, group := range sourceInstance.VpcSecurityGroups {
+		securityGroupIDs = append(securityGroupIDs, group.VpcSecurityGroupId)
+	}
+	input := &rds.RestoreDBInstanceFromDBSnapshotInput{
+		DBInstanceIdentifier: aws.String(restoreID),
+		DBSnapshotIdentifier: aws.String(snapshot.Status.SnapshotID),
+		DBInstanceClass:      sourceInstance.DBInstanceClass,
+		PubliclyAccessible:   sourceInstance.PubliclyAccessible,
+		VpcSecurityGroupIds:  securityGroupIDs,
+		MultiAZ:              aws.Bool(false),
+		Tags: []*rds.Tag{
+			&rds.Tag{
+				Key:   aws.String("Ridecell-Operator"),
+				Value: aws.String("true"),
+			},
+			&rds.Tag{
+				Key:   aws.String("tenant"),
+				Value: aws.String(instance.Name),
+			},
+		},
+	}
+	if sourceInstance.DBSubnetGroup != nil {
+		input.DBSubnetGroupName = sourceInstance.DBSubnetGroup.DBSubnetGroupName
+	}
+	_, err = comp.rdsAPI.RestoreDBInstanceFromDBSnapshot(input)
+	if err != nil {
+		return errors.Wrapf(err, "clone: unable to restore snapshot %s", snapshot.Status.SnapshotID)
+	}
+	ctx.Eventf(corev1.EventTypeNormal, "CloneRestoring", "Restoring snapshot %s to %s", snapshot.Status.SnapshotID, restoreID)
+	return nil
+}
+
+// Run the copy job and clean up once it is done.
+func (comp *cloneComponent) copy(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase, source *dbv1beta1.PostgresConnection) (components.Result, error) {
+	extra := map[string]interface{}{}
+	extra["source"] = source
+	obj, err := ctx.GetTemplate("clone.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, err
+	}
+	job := obj.(*batchv1.Job)
+
+	existing := &batchv1.Job{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, existing)
+	if err != nil && k8serrors.IsNotFound(err) {
+		err = controllerutil.SetControllerReference(instance, job, ctx.Scheme)
+		if err != nil {
+			return components.Result{}, err
+		}
+		err = ctx.Create(ctx.Context, job)
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "clone: error creating clone job %s/%s", job.Namespace, job.Name)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "CloneStarted", "Copying %s from %s", source.Database, source.Host)
+		return components.Result{StatusModifier: setCloneStatus(dbv1beta1.CloneStatusCopying, fmt.Sprintf("Clone job %s/%s started", job.Namespace, job.Name))}, nil
+	} else if err != nil {
+		return components.Result{}, errors.Wrapf(err, "clone: error getting clone job")
+	}
+
+	// Failed jobs were already handled in Reconcile.
+	if existing.Status.Succeeded == 0 {
+		// Still running, the job watch will trigger a reconcile when it finishes.
+		return components.Result{StatusModifier: setCloneStatus(dbv1beta1.CloneStatusCopying, fmt.Sprintf("Clone job %s/%s running", existing.Namespace, existing.Name))}, nil
+	}
+
+	err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return components.Result{}, errors.Wrapf(err, "clone: error deleting clone job")
+	}
+	err = comp.cleanupSource(ctx, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "CloneComplete", "Copied %s from %s", source.Database, source.Host)
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.Clone.Status = dbv1beta1.StatusReady
+		instance.Status.Clone.Message = fmt.Sprintf("Copied %s from %s", source.Database, source.Host)
+		instance.Status.Clone.RestoreInstanceID = ""
+		instance.Status.Clone.CompletedAt = time.Now().Format(time.UnixDate)
+		return nil
+	}}, nil
+}
+
+// Delete the restored instance along with the source password secret. A new restore comes back with the
+// snapshot's password, so the secret must not outlive the instance or the reset would be skipped.
+func (comp *cloneComponent) cleanupSource(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) error {
+	err := deleteRestoredInstance(comp.rdsAPI, instance.Status.Clone.RestoreInstanceID)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: cloneSecretName(instance), Namespace: instance.Namespace}}
+	err = ctx.Delete(ctx.Context, secret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "clone: error deleting source password secret")
+	}
+	return nil
+}
+
+// Delete the temporary instance restored from a snapshot, if there is one. Also used by the deletion component
+// so a database deleted mid-clone doesn't leave it behind.
+func deleteRestoredInstance(rdsAPI rdsiface.RDSAPI, restoreID string) error {
+	if restoreID == "" {
+		return nil
+	}
+	describeOutput, err := rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(restoreID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBInstanceNotFoundFault {
+			return nil
+		}
+		return errors.Wrapf(err, "clone: unable to describe restored instance %s", restoreID)
+	}
+	if len(describeOutput.DBInstances) > 0 && aws.StringValue(describeOutput.DBInstances[0].DBInstanceStatus) == "deleting" {
+		return nil
+	}
+	_, err = rdsAPI.DeleteDBInstance(&rds.DeleteDBInstanceInput{
+		DBInstanceIdentifier: aws.String(restoreID),
+		SkipFinalSnapshot:    aws.Bool(true),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != rds.ErrCodeDBInstanceNotFoundFault {
+			return errors.Wrapf(err, "clone: unable to delete restored instance %s", restoreID)
+		}
+	}
+	return nil
+}
+
+func setCloneStatus(status string, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.Clone.Status = status
+		instance.Status.Clone.Message = message
+		if status == dbv1beta1.CloneStatusRestoring {
+			instance.Status.Clone.RestoreInstanceID = fmt.Sprintf("%s-clone", instance.Name)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+type mockCloneRDSClient struct {
+	rdsiface.RDSAPI
+
+	instances map[string]*rds.DBInstance
+	restored  *rds.RestoreDBInstanceFromDBSnapshotInput
+	modified  bool
+	deleted   bool
+}
+
+var _ = Describe("PostgresDatabase Clone Component", func() {
+	comp := pdcomponents.NewClone()
+	var mockRDS *mockCloneRDSClient
+
+	BeforeEach(func() {
+		mockRDS = &mockCloneRDSClient{instances: map[string]*rds.DBInstance{}}
+		comp.InjectRDSAPI(mockRDS)
+
+		instance.Spec.DatabaseName = "foo_dev"
+		instance.Spec.Owner = "foo_dev"
+		instance.Status.DatabaseStatus = dbv1beta1.StatusReady
+		instance.Status.Clone.Status = dbv1beta1.CloneStatusPending
+	})
+
+	// Set up the client after the test has changed the instance.
+	setupClient := func(objs ...runtime.Object) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "summon-dev"},
+			Data: map[string][]byte{
+				"password": []byte("mypassword"),
+			},
+		}
+		ctx.Client = fake.NewFakeClient(append([]runtime.Object{instance, secret}, objs...)...)
+	}
+
+	getJob := func() (*batchv1.Job, error) {
+		job := &batchv1.Job{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-clone", Namespace: "summon-dev"}, job)
+		return job, err
+	}
+
+	Describe("IsReconcilable", func() {
+		It("runs for a pending clone", func() {
+			Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		})
+
+		It("does nothing without a clone", func() {
+			instance.Status.Clone.Status = ""
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
+
+		It("does nothing once the clone is done", func() {
+			instance.Status.Clone.Status = dbv1beta1.StatusReady
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
+	})
+
+	It("requires a source", func() {
+		instance.Spec.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{}
+		setupClient()
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+
+	Context("from another PostgresDatabase", func() {
+		var sourceDB *dbv1beta1.PostgresDatabase
+		var sourceSecret *corev1.Secret
+
+		BeforeEach(func() {
+			instance.Spec.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{
+				PostgresDatabaseRef: corev1.ObjectReference{Name: "foo-uat", Namespace: "summon-uat"},
+				ScrubScriptRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "scrub"},
+					Key:                  "anonymize.sql",
+				},
+			}
+			sourceDB = &dbv1beta1.PostgresDatabase{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-uat", Namespace: "summon-uat"},
+				Spec:       dbv1beta1.PostgresDatabaseSpec{DatabaseName: "foo_uat"},
+				Status: dbv1beta1.PostgresDatabaseStatus{
+					Status: dbv1beta1.StatusReady,
+					AdminConnection: dbv1beta1.PostgresConnection{
+						Host:              "uatdb",
+						Port:              5432,
+						Username:          "uatadmin",
+						PasswordSecretRef: helpers.SecretRef{Name: "uatsecret", Key: "password"},
+					},
+				},
+			}
+			sourceSecret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "uatsecret", Namespace: "summon-uat"},
+				Data:       map[string][]byte{"password": []byte("uatpassword")},
+			}
+		})
+
+		It("waits for the source database", func() {
+			sourceDB.Status.Status = dbv1beta1.StatusCreating
+			setupClient(sourceDB, sourceSecret)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			_, err := getJob()
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("copies the password and starts a clone job", func() {
+			setupClient(sourceDB, sourceSecret)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Clone.Status).To(Equal(dbv1beta1.CloneStatusCopying))
+
+			secret := &corev1.Secret{}
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev.clone-source", Namespace: "summon-dev"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data["password"]).To(Equal([]byte("uatpassword")))
+
+			job, err := getJob()
+			Expect(err).ToNot(HaveOccurred())
+			env := job.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "SOURCE_HOST", Value: "uatdb"}))
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "SOURCE_DATABASE", Value: "foo_uat"}))
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "PGDATABASE", Value: "foo_dev"}))
+			Expect(job.Spec.Template.Spec.Volumes[1].ConfigMap.Name).To(Equal("scrub"))
+			Expect(job.Spec.Template.Spec.Volumes[1].ConfigMap.Items[0].Key).To(Equal("anonymize.sql"))
+		})
+
+		It("reports a failed clone job", func() {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-clone", Namespace: "summon-dev"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+				},
+			}
+			setupClient(sourceDB, sourceSecret, job)
+
+			Expect(comp).NotTo(ReconcileContext(ctx))
+		})
+
+		It("finishes the clone", func() {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-clone", Namespace: "summon-dev"},
+				Status:     batchv1.JobStatus{Succeeded: 1},
+			}
+			setupClient(sourceDB, sourceSecret, job)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Clone.Status).To(Equal(dbv1beta1.StatusReady))
+			Expect(instance.Status.Clone.CompletedAt).ToNot(BeEmpty())
+			_, err := getJob()
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev.clone-source", Namespace: "summon-dev"}, &corev1.Secret{})
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("from an RDSSnapshot", func() {
+		var snapshot *dbv1beta1.RDSSnapshot
+
+		BeforeEach(func() {
+			instance.Spec.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{
+				RDSSnapshotRef: corev1.ObjectReference{Name: "uat-snapshot"},
+				DatabaseName:   "foo_uat",
+			}
+			snapshot = &dbv1beta1.RDSSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "uat-snapshot", Namespace: "summon-dev"},
+				Spec:       dbv1beta1.RDSSnapshotSpec{RDSInstanceID: "foo-uat"},
+				Status:     dbv1beta1.RDSSnapshotStatus{Status: dbv1beta1.StatusReady, SnapshotID: "foo-uat-snapshot"},
+			}
+			mockRDS.instances["foo-uat"] = &rds.DBInstance{
+				DBInstanceIdentifier: aws.String("foo-uat"),
+				DBInstanceClass:      aws.String("db.t3.small"),
+				DBSubnetGroup:        &rds.DBSubnetGroup{DBSubnetGroupName: aws.String("subnets")},
+				VpcSecurityGroups:    []*rds.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-1234")}},
+				PubliclyAccessible:   aws.Bool(true),
+			}
+		})
+
+		It("requires the database name", func() {
+			instance.Spec.CloneFrom.DatabaseName = ""
+			setupClient(snapshot)
+			Expect(comp).NotTo(ReconcileContext(ctx))
+		})
+
+		It("restores the snapshot", func() {
+			setupClient(snapshot)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.restored).ToNot(BeNil())
+			Expect(aws.StringValue(mockRDS.restored.DBInstanceIdentifier)).To(Equal("foo-dev-clone"))
+			Expect(aws.StringValue(mockRDS.restored.DBSnapshotIdentifier)).To(Equal("foo-uat-snapshot"))
+			Expect(aws.StringValue(mockRDS.restored.DBSubnetGroupName)).To(Equal("subnets"))
+			Expect(instance.Status.Clone.Status).To(Equal(dbv1beta1.CloneStatusRestoring))
+			Expect(instance.Status.Clone.RestoreInstanceID).To(Equal("foo-dev-clone"))
+		})
+
+		It("waits for the restored instance", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{DBInstanceStatus: aws.String("creating")}
+			setupClient(snapshot)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modified).To(BeFalse())
+			_, err := getJob()
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("resets the master password", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{DBInstanceStatus: aws.String("available")}
+			setupClient(snapshot)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modified).To(BeTrue())
+			secret := &corev1.Secret{}
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev.clone-source", Namespace: "summon-dev"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data["password"]).ToNot(BeEmpty())
+		})
+
+		It("copies from the restored instance", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{
+				DBInstanceStatus: aws.String("available"),
+				MasterUsername:   aws.String("foo_uat"),
+				Endpoint:         &rds.Endpoint{Address: aws.String("foo-dev-clone.rds.amazonaws.com"), Port: aws.Int64(5432)},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.clone-source", Namespace: "summon-dev"},
+				Data:       map[string][]byte{"password": []byte("clonepassword")},
+			}
+			setupClient(snapshot, secret)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			job, err := getJob()
+			Expect(err).ToNot(HaveOccurred())
+			env := job.Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "SOURCE_HOST", Value: "foo-dev-clone.rds.amazonaws.com"}))
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "SOURCE_USER", Value: "foo_uat"}))
+			Expect(env).To(ContainElement(corev1.EnvVar{Name: "SOURCE_DATABASE", Value: "foo_uat"}))
+		})
+
+		It("deletes the restored instance when done", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{
+				DBInstanceStatus: aws.String("available"),
+				MasterUsername:   aws.String("foo_uat"),
+				Endpoint:         &rds.Endpoint{Address: aws.String("foo-dev-clone.rds.amazonaws.com"), Port: aws.Int64(5432)},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.clone-source", Namespace: "summon-dev"},
+				Data:       map[string][]byte{"password": []byte("clonepassword")},
+			}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-clone", Namespace: "summon-dev"},
+				Status:     batchv1.JobStatus{Succeeded: 1},
+			}
+			instance.Status.Clone.RestoreInstanceID = "foo-dev-clone"
+			setupClient(snapshot, secret, job)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.deleted).To(BeTrue())
+			Expect(instance.Status.Clone.Status).To(Equal(dbv1beta1.StatusReady))
+			Expect(instance.Status.Clone.RestoreInstanceID).To(BeEmpty())
+		})
+
+		It("deletes the restored instance when the copy fails", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{DBInstanceStatus: aws.String("available")}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-clone", Namespace: "summon-dev"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+				},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.clone-source", Namespace: "summon-dev"},
+				Data:       map[string][]byte{"password": []byte("secret")},
+			}
+			instance.Status.Clone.RestoreInstanceID = "foo-dev-clone"
+			setupClient(snapshot, job, secret)
+
+			Expect(comp).NotTo(ReconcileContext(ctx))
+			Expect(mockRDS.deleted).To(BeTrue())
+			_, err := getJob()
+			Expect(err).ToNot(HaveOccurred())
+			// The next restore gets the snapshot's password again so it has to be reset.
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev.clone-source", Namespace: "summon-dev"}, &corev1.Secret{})
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("does not delete a restored instance which is already going away", func() {
+			mockRDS.instances["foo-dev-clone"] = &rds.DBInstance{DBInstanceStatus: aws.String("deleting")}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-clone", Namespace: "summon-dev"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+				},
+			}
+			instance.Status.Clone.RestoreInstanceID = "foo-dev-clone"
+			setupClient(snapshot, job)
+
+			Expect(comp).NotTo(ReconcileContext(ctx))
+			Expect(mockRDS.deleted).To(BeFalse())
+		})
+	})
+})
+
+// Mock aws functions below
+
+func (m *mockCloneRDSClient) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	dbInstance, ok := m.instances[aws.StringValue(input.DBInstanceIdentifier)]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "awsmock_describedbinstances: instance does not exist", nil)
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{dbInstance}}, nil
+}
+
+func (m *mockCloneRDSClient) RestoreDBInstanceFromDBSnapshot(input *rds.RestoreDBInstanceFromDBSnapshotInput) (*rds.RestoreDBInstanceFromDBSnapshotOutput, error) {
+	m.restored = input
+	return &rds.RestoreDBInstanceFromDBSnapshotOutput{}, nil
+}
+
+func (m *mockCloneRDSClient) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.ModifyDBInstanceOutput, error) {
+	m.modified = true
+	return &rds.ModifyDBInstanceOutput{}, nil
+}
+
+func (m *mockCloneRDSClient) DeleteDBInstance(input *rds.DeleteDBInstanceInput) (*rds.DeleteDBInstanceOutput, error) {
+	m.deleted = true
+	return &rds.DeleteDBInstanceOutput{}, nil
+}
@@ -42,7 +42,8 @@ func (_ *databaseComponent) WatchTypes() []runtime.Object {
 
 func (_ *databaseComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
-	return (instance.Status.DatabaseClusterStatus == dbv1beta1.StatusReady || instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String()) && (instance.Spec.SkipUser || instance.Status.UserStatus == dbv1beta1.StatusReady)
+	// Don't recreate the database while the deletion component is dropping it.
+	return (instance.Status.DatabaseClusterStatus == dbv1beta1.StatusReady || instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String()) && (instance.Spec.SkipUser || instance.Status.UserStatus == dbv1beta1.StatusReady) && instance.DeletionTimestamp.IsZero()
 }
 
 func (comp *databaseComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
@@ -76,17 +77,24 @@ func (comp *databaseComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 	}
 
+	created := false
 	if count == 0 {
 		// Time to make the database.
 		_, err = db.Exec(fmt.Sprintf(`CREATE DATABASE %s WITH OWNER = %s`, pq.QuoteIdentifier(instance.Spec.DatabaseName), utils.QuoteLiteral(instance.Spec.Owner)))
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "database: error creating database")
 		}
+		created = true
 	}
 
 	dbName := instance.Spec.DatabaseName
+	// Only clone into a brand new database, never over the top of existing data.
+	startClone := created && instance.Spec.CloneFrom != nil
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*dbv1beta1.PostgresDatabase)
+		if startClone {
+			instance.Status.Clone = dbv1beta1.PostgresDatabaseCloneStatus{Status: dbv1beta1.CloneStatusPending}
+		}
 		instance.Status.DatabaseStatus = dbv1beta1.StatusReady
 		instance.Status.Status = dbv1beta1.StatusCreating
 		instance.Status.Message = fmt.Sprintf("Created database %s", dbName)
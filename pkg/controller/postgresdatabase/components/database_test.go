@@ -79,6 +79,14 @@ var _ = Describe("PostgresDatabase Database Component", func() {
 			instance.Status.UserStatus = ""
 			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
 		})
+
+		It("does not create a database while it is being deleted", func() {
+			instance.Status.DatabaseClusterStatus = dbv1beta1.StatusReady
+			instance.Status.UserStatus = dbv1beta1.StatusReady
+			now := metav1.Now()
+			instance.ObjectMeta.SetDeletionTimestamp(&now)
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
 	})
 
 	It("creates a database", func() {
@@ -93,4 +101,23 @@ var _ = Describe("PostgresDatabase Database Component", func() {
 
 		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusCreating))
 	})
+
+	It("marks a new database to be cloned", func() {
+		instance.Spec.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{PostgresDatabaseRef: corev1.ObjectReference{Name: "foo-uat"}}
+		dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		dbMock.ExpectQuery(`SELECT pg_has_role`).WithArgs("myuser", "foo").WillReturnRows(sqlmock.NewRows([]string{"pg_has_role"}).AddRow(1))
+		dbMock.ExpectExec(`CREATE DATABASE "foo_dev" WITH OWNER = 'foo'`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Clone.Status).To(Equal(dbv1beta1.CloneStatusPending))
+	})
+
+	It("does not clone over an existing database", func() {
+		instance.Spec.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{PostgresDatabaseRef: corev1.ObjectReference{Name: "foo-uat"}}
+		dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		dbMock.ExpectQuery(`SELECT pg_has_role`).WithArgs("myuser", "foo").WillReturnRows(sqlmock.NewRows([]string{"pg_has_role"}).AddRow(1))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Clone.Status).To(BeEmpty())
+	})
 })
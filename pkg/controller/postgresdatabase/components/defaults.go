@@ -17,6 +17,7 @@ limitations under the License.
 package components
 
 import (
+	"os"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -48,6 +49,27 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 	if instance.Spec.Owner == "" {
 		instance.Spec.Owner = instance.Spec.DatabaseName
 	}
+	if instance.Spec.DeletionPolicy == "" {
+		instance.Spec.DeletionPolicy = dbv1beta1.DeletionPolicyRetain
+	}
+	if instance.Spec.Archive.Region == "" {
+		instance.Spec.Archive.Region = os.Getenv("AWS_REGION")
+	}
+	if instance.Spec.Archive.Prefix == "" {
+		instance.Spec.Archive.Prefix = "postgres-archive"
+	}
+	if instance.Spec.Pooler != nil {
+		if instance.Spec.Pooler.PoolSize == 0 {
+			instance.Spec.Pooler.PoolSize = 20
+		}
+		if instance.Spec.Pooler.MaxClientConnections == 0 {
+			instance.Spec.Pooler.MaxClientConnections = 1000
+		}
+		if instance.Spec.Pooler.Replicas == nil {
+			replicas := int32(2)
+			instance.Spec.Pooler.Replicas = &replicas
+		}
+	}
 
 	return components.Result{}, nil
 }
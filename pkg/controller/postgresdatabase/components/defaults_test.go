@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -41,4 +42,24 @@ var _ = Describe("PostgresDatabase Defaults Component", func() {
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Spec.Owner).To(Equal("foo_dev"))
 	})
+
+	It("defaults to retaining the database", func() {
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.DeletionPolicy).To(Equal("Retain"))
+		Expect(instance.Spec.Archive.Prefix).To(Equal("postgres-archive"))
+	})
+
+	It("leaves the pooler off", func() {
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.Pooler).To(BeNil())
+	})
+
+	It("sets pooler defaults", func() {
+		instance.Spec.Pooler = &dbv1beta1.PostgresDatabasePoolerSpec{PoolSize: 50}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.Pooler.PoolMode).To(BeEmpty())
+		Expect(instance.Spec.Pooler.PoolSize).To(Equal(50))
+		Expect(instance.Spec.Pooler.MaxClientConnections).To(Equal(1000))
+		Expect(*instance.Spec.Pooler.Replicas).To(Equal(int32(2)))
+	})
 })
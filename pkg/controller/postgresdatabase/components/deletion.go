@@ -0,0 +1,300 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/components/postgres"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+const postgresDatabaseFinalizer = "postgresdatabase.database.finalizer"
+
+// Annotation on the archive job with the S3 location of the dump.
+const archiveLocationAnnotation = "ridecell.io/archive-location"
+
+// How long the upload URL for the dump is valid, this has to cover the job waiting to be scheduled and the dump itself.
+const archiveURLExpiry = 12 * time.Hour
+
+type S3Factory func(region string) (s3iface.S3API, error)
+
+type deletionComponent struct {
+	s3Factory S3Factory
+	rdsAPI    rdsiface.RDSAPI
+}
+
+func realS3Factory(region string) (s3iface.S3API, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(metrics.InstrumentAWSSession(sess)), nil
+}
+
+func NewDeletion() *deletionComponent {
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
+	rdsService := rds.New(sess)
+	return &deletionComponent{s3Factory: realS3Factory, rdsAPI: rdsService}
+}
+
+func (comp *deletionComponent) InjectS3Factory(factory S3Factory) {
+	comp.s3Factory = factory
+}
+
+func (comp *deletionComponent) InjectRDSAPI(rdsapi rdsiface.RDSAPI) {
+	comp.rdsAPI = rdsapi
+}
+
+func (_ *deletionComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *deletionComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (comp *deletionComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+
+	// if object is not being deleted
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		// The finalizer is only needed when there is something to do on deletion, including an RDS instance
+		// restored for a clone which hasn't finished yet.
+		wantFinalizer := instance.Spec.DeletionPolicy == dbv1beta1.DeletionPolicyArchive || instance.Spec.DeletionPolicy == dbv1beta1.DeletionPolicyDelete || instance.Status.Clone.RestoreInstanceID != ""
+		hasFinalizer := helpers.ContainsFinalizer(postgresDatabaseFinalizer, instance)
+		if wantFinalizer && !hasFinalizer {
+			instance.ObjectMeta.Finalizers = helpers.AppendFinalizer(postgresDatabaseFinalizer, instance)
+			err := ctx.Update(ctx.Context, instance)
+			if err != nil {
+				return components.Result{Requeue: true}, errors.Wrapf(err, "postgres_database: failed to update instance while adding finalizer")
+			}
+		} else if !wantFinalizer && hasFinalizer {
+			instance.ObjectMeta.Finalizers = helpers.RemoveFinalizer(postgresDatabaseFinalizer, instance)
+			err := ctx.Update(ctx.Context, instance)
+			if err != nil {
+				return components.Result{Requeue: true}, errors.Wrapf(err, "postgres_database: failed to update instance while removing finalizer")
+			}
+		}
+		return components.Result{}, nil
+	}
+
+	// If object is being deleted and has no finalizer just exit.
+	if !helpers.ContainsFinalizer(postgresDatabaseFinalizer, instance) {
+		return components.Result{}, nil
+	}
+	if flag := instance.Annotations["ridecell.io/skip-finalizer"]; flag != "true" && os.Getenv("ENABLE_FINALIZERS") == "true" {
+		err := deleteRestoredInstance(comp.rdsAPI, instance.Status.Clone.RestoreInstanceID)
+		if err != nil {
+			return components.Result{}, err
+		}
+		result, done, err := comp.deleteDependencies(ctx, instance)
+		if err != nil || !done {
+			return result, err
+		}
+	}
+	// All operations complete, remove finalizer
+	instance.ObjectMeta.Finalizers = helpers.RemoveFinalizer(postgresDatabaseFinalizer, instance)
+	err := ctx.Update(ctx.Context, instance)
+	if err != nil {
+		return components.Result{Requeue: true}, errors.Wrapf(err, "postgres_database: failed to update instance while removing finalizer")
+	}
+	return components.Result{}, nil
+}
+
+// Archive and/or drop the database. Returns false until it is safe to remove the finalizer.
+func (comp *deletionComponent) deleteDependencies(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) (components.Result, bool, error) {
+	if instance.Spec.DeletionPolicy != dbv1beta1.DeletionPolicyArchive && instance.Spec.DeletionPolicy != dbv1beta1.DeletionPolicyDelete {
+		return components.Result{}, true, nil
+	}
+	if instance.Status.AdminConnection.Host == "" {
+		// Never got as far as connecting to the database server, so nothing was created.
+		return components.Result{}, true, nil
+	}
+
+	db, err := postgres.Open(ctx, &instance.Status.AdminConnection)
+	if err != nil {
+		return components.Result{}, false, err
+	}
+
+	row := db.QueryRow(`SELECT COUNT(*) FROM pg_catalog.pg_database WHERE datname = $1`, instance.Spec.DatabaseName)
+	var count int
+	err = row.Scan(&count)
+	if err != nil {
+		return components.Result{}, false, errors.Wrap(err, "postgres_database: error running db check query")
+	}
+
+	if count > 0 {
+		if instance.Spec.DeletionPolicy == dbv1beta1.DeletionPolicyArchive {
+			result, done, err := comp.archive(ctx, instance)
+			if err != nil || !done {
+				return result, false, err
+			}
+		}
+
+		quotedDatabase := pq.QuoteIdentifier(instance.Spec.DatabaseName)
+		// Stop anything reconnecting while the existing sessions are closed.
+		_, err = db.Exec(fmt.Sprintf(`ALTER DATABASE %s ALLOW_CONNECTIONS false`, quotedDatabase))
+		if err != nil {
+			return components.Result{}, false, errors.Wrapf(err, "postgres_database: error disabling connections to %s", instance.Spec.DatabaseName)
+		}
+		_, err = db.Exec(`SELECT pg_terminate_backend(pid) FROM pg_catalog.pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()`, instance.Spec.DatabaseName)
+		if err != nil {
+			return components.Result{}, false, errors.Wrapf(err, "postgres_database: error terminating sessions on %s", instance.Spec.DatabaseName)
+		}
+		_, err = db.Exec(fmt.Sprintf(`DROP DATABASE IF EXISTS %s`, quotedDatabase))
+		if err != nil {
+			return components.Result{}, false, errors.Wrapf(err, "postgres_database: error dropping database %s", instance.Spec.DatabaseName)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "DatabaseDropped", "Dropped database %s", instance.Spec.DatabaseName)
+	}
+
+	if !instance.Spec.SkipUser {
+		// Remove the PostgresUser first so its controller doesn't put the role back.
+		user := &dbv1beta1.PostgresUser{ObjectMeta: metav1.ObjectMeta{Name: instance.Name, Namespace: instance.Namespace}}
+		err = ctx.Delete(ctx.Context, user)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return components.Result{}, false, errors.Wrapf(err, "postgres_database: error deleting postgres user %s", instance.Name)
+		}
+		_, err = db.Exec(fmt.Sprintf(`DROP ROLE IF EXISTS %s`, pq.QuoteIdentifier(instance.Spec.Owner)))
+		if err != nil {
+			return components.Result{}, false, errors.Wrapf(err, "postgres_database: error dropping owner %s", instance.Spec.Owner)
+		}
+	}
+
+	return components.Result{}, true, nil
+}
+
+// Dump the database to S3 using a job. Returns true once the dump is uploaded.
+func (comp *deletionComponent) archive(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) (components.Result, bool, error) {
+	job := &batchv1.Job{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Name + "-archive", Namespace: instance.Namespace}, job)
+	if err != nil && k8serrors.IsNotFound(err) {
+		return comp.startArchive(ctx, instance)
+	} else if err != nil {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: error getting archive job")
+	}
+
+	location := job.Annotations[archiveLocationAnnotation]
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			// Leave the job around for debugging, deleting it starts a new dump.
+			return components.Result{}, false, errors.Errorf("postgres_database: archive job %s/%s failed, the database was not dropped", job.Namespace, job.Name)
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		// Jobs are not owned by the instance so they can outlive it, check back later.
+		return components.Result{RequeueAfter: 30 * time.Second, StatusModifier: func(obj runtime.Object) error {
+			instance := obj.(*dbv1beta1.PostgresDatabase)
+			instance.Status.Message = fmt.Sprintf("Archiving database to %s", location)
+			return nil
+		}}, false, nil
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "DatabaseArchived", "Archived database %s to %s", instance.Spec.DatabaseName, location)
+	err = ctx.Delete(ctx.Context, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: error deleting archive job")
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: instance.Name + "-archive-url", Namespace: instance.Namespace}}
+	err = ctx.Delete(ctx.Context, secret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: error deleting archive url secret")
+	}
+	return components.Result{}, true, nil
+}
+
+func (comp *deletionComponent) startArchive(ctx *components.ComponentContext, instance *dbv1beta1.PostgresDatabase) (components.Result, bool, error) {
+	if instance.Spec.Archive.BucketName == "" {
+		return components.Result{}, false, errors.New("postgres_database: archive.bucketName is required to archive the database")
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s.dump", instance.Spec.Archive.Prefix, instance.Namespace, instance.Name, time.Now().UTC().Format("20060102T150405Z"))
+	location := fmt.Sprintf("s3://%s/%s", instance.Spec.Archive.BucketName, key)
+
+	s3Service, err := comp.s3Factory(instance.Spec.Archive.Region)
+	if err != nil {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: failed to get s3 service")
+	}
+	req, _ := s3Service.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(instance.Spec.Archive.BucketName),
+		Key:    aws.String(key),
+	})
+	urlStr, err := req.Presign(archiveURLExpiry)
+	if err != nil {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: failed to presign s3 url")
+	}
+
+	extra := map[string]interface{}{}
+	extra["location"] = location
+	secretObj, err := ctx.GetTemplate("archive-url-secret.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, false, err
+	}
+	// Not owned by the instance either, the job still needs it while the instance is being deleted.
+	goal := secretObj.(*corev1.Secret)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: goal.Name, Namespace: goal.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx.Context, ctx, secret, func(existingObj runtime.Object) error {
+		existing := existingObj.(*corev1.Secret)
+		existing.Labels = goal.Labels
+		existing.Data = map[string][]byte{"url": []byte(urlStr)}
+		return nil
+	})
+	if err != nil {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: error creating archive url secret")
+	}
+	obj, err := ctx.GetTemplate("archive.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, false, err
+	}
+	// Not owned by the instance, the garbage collector could otherwise remove it while the instance is being deleted.
+	err = ctx.Create(ctx.Context, obj)
+	if err != nil {
+		return components.Result{}, false, errors.Wrapf(err, "postgres_database: error creating archive job")
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "ArchiveStarted", "Archiving database %s to %s", instance.Spec.DatabaseName, location)
+	return components.Result{RequeueAfter: 30 * time.Second, StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.Message = fmt.Sprintf("Archiving database to %s", location)
+		return nil
+	}}, false, nil
+}
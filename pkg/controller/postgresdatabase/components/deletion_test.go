@@ -0,0 +1,268 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
+	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresDatabase Deletion Component", func() {
+	comp := pdcomponents.NewDeletion()
+	var dbMock sqlmock.Sqlmock
+	var db *sql.DB
+
+	BeforeEach(func() {
+		comp.InjectS3Factory(func(region string) (s3iface.S3API, error) {
+			// Presigning doesn't talk to AWS, a real client with fake credentials works.
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region:      aws.String(region),
+				Credentials: credentials.NewStaticCredentials("garbage", "garbage", ""),
+			}))
+			return s3.New(sess), nil
+		})
+
+		var err error
+		db, dbMock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		dbpool.Dbs.Store("postgres host=mydb port=5432 dbname=postgres user=myuser password='mypassword' sslmode=require", db)
+		os.Setenv("ENABLE_FINALIZERS", "true")
+
+		instance.Spec.DatabaseName = "foo_dev"
+		instance.Spec.Owner = "foo_dev"
+		instance.Spec.DeletionPolicy = "Delete"
+		instance.Spec.Archive = dbv1beta1.PostgresDatabaseArchiveSpec{BucketName: "ridecell-archive", Region: "us-west-2", Prefix: "postgres-archive"}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("ENABLE_FINALIZERS")
+		db.Close()
+		dbpool.Dbs.Delete("postgres host=mydb port=5432 dbname=postgres user=myuser password='mypassword' sslmode=require")
+
+		// Check for any unmet expectations.
+		err := dbMock.ExpectationsWereMet()
+		if err != nil {
+			Fail(fmt.Sprintf("there were unfulfilled database expectations: %s", err))
+		}
+	})
+
+	// Set up the client after the test has changed the instance.
+	setupClient := func(objs ...runtime.Object) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "summon-dev"},
+			Data: map[string][]byte{
+				"password": []byte("mypassword"),
+			},
+		}
+		ctx.Client = fake.NewFakeClient(append([]runtime.Object{instance, secret}, objs...)...)
+	}
+
+	deleting := func() {
+		now := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&now)
+		instance.ObjectMeta.Finalizers = []string{"postgresdatabase.database.finalizer"}
+	}
+
+	It("adds a finalizer for the delete policy", func() {
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(ConsistOf("postgresdatabase.database.finalizer"))
+	})
+
+	It("does not add a finalizer for the retain policy", func() {
+		instance.Spec.DeletionPolicy = "Retain"
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+
+	It("adds a finalizer while a clone has a restored instance", func() {
+		instance.Spec.DeletionPolicy = "Retain"
+		instance.Status.Clone.RestoreInstanceID = "foo-dev-clone"
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(ConsistOf("postgresdatabase.database.finalizer"))
+	})
+
+	It("deletes the restored instance of an unfinished clone", func() {
+		mockRDS := &mockCloneRDSClient{instances: map[string]*rds.DBInstance{
+			"foo-dev-clone": &rds.DBInstance{DBInstanceStatus: aws.String("available")},
+		}}
+		comp.InjectRDSAPI(mockRDS)
+		instance.Spec.DeletionPolicy = "Retain"
+		instance.Status.Clone.RestoreInstanceID = "foo-dev-clone"
+		deleting()
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deleted).To(BeTrue())
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+
+	It("removes the finalizer when switched back to retain", func() {
+		instance.Spec.DeletionPolicy = "Retain"
+		instance.ObjectMeta.Finalizers = []string{"postgresdatabase.database.finalizer"}
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+
+	It("drops the database and owner", func() {
+		deleting()
+		user := &dbv1beta1.PostgresUser{ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"}}
+		setupClient(user)
+
+		dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		dbMock.ExpectExec(`ALTER DATABASE "foo_dev" ALLOW_CONNECTIONS false`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`SELECT pg_terminate_backend`).WithArgs("foo_dev").WillReturnResult(sqlmock.NewResult(0, 2))
+		dbMock.ExpectExec(`DROP DATABASE IF EXISTS "foo_dev"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`DROP ROLE IF EXISTS "foo_dev"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev", Namespace: "summon-dev"}, user)
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("leaves a shared owner alone with skipUser", func() {
+		deleting()
+		instance.Spec.SkipUser = true
+		setupClient()
+
+		dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+
+	It("skips cleanup with the skip-finalizer annotation", func() {
+		deleting()
+		instance.Annotations = map[string]string{"ridecell.io/skip-finalizer": "true"}
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+
+	Context("with the archive policy", func() {
+		BeforeEach(func() {
+			instance.Spec.DeletionPolicy = "Archive"
+			deleting()
+		})
+
+		It("starts an archive job before dropping anything", func() {
+			setupClient()
+			dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.ObjectMeta.Finalizers).To(HaveLen(1))
+			Expect(instance.Status.Message).To(HavePrefix("Archiving database to s3://ridecell-archive/postgres-archive/summon-dev/foo-dev/"))
+
+			job := &batchv1.Job{}
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-archive", Namespace: "summon-dev"}, job)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(job.Annotations["ridecell.io/archive-location"]).To(HaveSuffix(".dump"))
+			Expect(job.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "PGDATABASE", Value: "foo_dev"}))
+			Expect(job.Spec.Template.Spec.Containers[0].Env[0].Value).To(BeEmpty())
+			Expect(job.Spec.Template.Spec.Containers[0].Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("foo-dev-archive-url"))
+
+			secret := &corev1.Secret{}
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-archive-url", Namespace: "summon-dev"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.OwnerReferences).To(BeEmpty())
+			Expect(string(secret.Data["url"])).To(ContainSubstring("postgres-archive/summon-dev/foo-dev/"))
+			Expect(string(secret.Data["url"])).To(ContainSubstring("X-Amz-Signature="))
+		})
+
+		It("requires a bucket", func() {
+			instance.Spec.Archive.BucketName = ""
+			setupClient()
+			dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+			Expect(comp).NotTo(ReconcileContext(ctx))
+		})
+
+		It("waits for the archive job", func() {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-archive", Namespace: "summon-dev"}}
+			setupClient(job)
+			dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).ToNot(BeZero())
+			Expect(instance.ObjectMeta.Finalizers).To(HaveLen(1))
+		})
+
+		It("does not drop the database if the archive job failed", func() {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-archive", Namespace: "summon-dev"},
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+				},
+			}
+			setupClient(job)
+			dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+			Expect(comp).NotTo(ReconcileContext(ctx))
+			Expect(instance.ObjectMeta.Finalizers).To(HaveLen(1))
+		})
+
+		It("drops the database once the archive is uploaded", func() {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-archive", Namespace: "summon-dev"},
+				Status:     batchv1.JobStatus{Succeeded: 1},
+			}
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-archive-url", Namespace: "summon-dev"}}
+			setupClient(job, secret)
+			dbMock.ExpectQuery(`SELECT COUNT`).WithArgs("foo_dev").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			dbMock.ExpectExec(`ALTER DATABASE "foo_dev" ALLOW_CONNECTIONS false`).WillReturnResult(sqlmock.NewResult(0, 0))
+			dbMock.ExpectExec(`SELECT pg_terminate_backend`).WithArgs("foo_dev").WillReturnResult(sqlmock.NewResult(0, 0))
+			dbMock.ExpectExec(`DROP DATABASE IF EXISTS "foo_dev"`).WillReturnResult(sqlmock.NewResult(0, 0))
+			dbMock.ExpectExec(`DROP ROLE IF EXISTS "foo_dev"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-archive", Namespace: "summon-dev"}, job)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-archive-url", Namespace: "summon-dev"}, secret)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
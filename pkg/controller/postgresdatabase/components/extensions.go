@@ -37,7 +37,7 @@ func (comp *extensionsComponent) WatchTypes() []runtime.Object {
 
 func (_ *extensionsComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
-	return instance.Status.DatabaseStatus == dbv1beta1.StatusReady
+	return instance.Status.DatabaseStatus == dbv1beta1.StatusReady && instance.DeletionTimestamp.IsZero()
 }
 
 func (_ *extensionsComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
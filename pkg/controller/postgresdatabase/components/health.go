@@ -0,0 +1,138 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/components/postgres"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+// How often to collect, the queries are cheap but there can be hundreds of databases on one server.
+const healthInterval = 5 * time.Minute
+
+type healthComponent struct{}
+
+func NewHealth() *healthComponent {
+	return &healthComponent{}
+}
+
+func (_ *healthComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *healthComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	// Also run on deletion to clean up the metrics.
+	return instance.Status.Status == dbv1beta1.StatusReady || !instance.DeletionTimestamp.IsZero()
+}
+
+func (comp *healthComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+
+	if !instance.DeletionTimestamp.IsZero() {
+		metrics.DeletePostgresDatabase(instance.Namespace, instance.Name)
+		return components.Result{}, nil
+	}
+
+	if instance.Status.Health.CollectedAt != "" {
+		collectedAt, err := time.Parse(time.UnixDate, instance.Status.Health.CollectedAt)
+		if err == nil && time.Since(collectedAt) < healthInterval {
+			return components.Result{RequeueAfter: healthInterval - time.Since(collectedAt)}, nil
+		}
+	}
+
+	conn := instance.Status.AdminConnection.DeepCopy()
+	conn.Database = instance.Spec.DatabaseName
+	db, err := postgres.Open(ctx, conn)
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "health: error connecting to database")
+	}
+
+	health := dbv1beta1.PostgresDatabaseHealthStatus{
+		Extensions:  map[string]string{},
+		CollectedAt: time.Now().Format(time.UnixDate),
+	}
+	err = db.QueryRow(`SELECT pg_database_size(current_database()), current_setting('max_connections')::int`).Scan(&health.SizeBytes, &health.MaxConnections)
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "health: error querying database size")
+	}
+
+	err = db.QueryRow(`SELECT COUNT(*), COUNT(*) FILTER (WHERE xact_start < now() - interval '5 minutes'), COALESCE(EXTRACT(EPOCH FROM MAX(now() - xact_start)), 0)::bigint FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid()`).Scan(&health.Connections, &health.LongTransactions, &health.OldestTransactionSeconds)
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "health: error querying connections")
+	}
+
+	// RDS replication is reported by the RDS controller, only local Zalando clusters are visible from here.
+	if instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String() {
+		err = db.QueryRow(`SELECT COALESCE(EXTRACT(EPOCH FROM MAX(replay_lag)), 0)::bigint FROM pg_stat_replication`).Scan(&health.ReplicationLagSeconds)
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "health: error querying replication lag")
+		}
+	}
+
+	rows, err := db.Query(`SELECT extname, extversion FROM pg_extension`)
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "health: error querying extensions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, version sql.NullString
+		err = rows.Scan(&name, &version)
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "health: error reading extensions")
+		}
+		health.Extensions[name.String] = version.String
+	}
+	err = rows.Err()
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "health: error reading extensions")
+	}
+
+	metrics.PostgresDatabaseSize.WithLabelValues(instance.Namespace, instance.Name).Set(float64(health.SizeBytes))
+	metrics.PostgresDatabaseConnections.WithLabelValues(instance.Namespace, instance.Name).Set(float64(health.Connections))
+	metrics.PostgresDatabaseMaxConnections.WithLabelValues(instance.Namespace, instance.Name).Set(float64(health.MaxConnections))
+	metrics.PostgresDatabaseLongTransactions.WithLabelValues(instance.Namespace, instance.Name).Set(float64(health.LongTransactions))
+	metrics.PostgresDatabaseReplicationLag.WithLabelValues(instance.Namespace, instance.Name).Set(float64(health.ReplicationLagSeconds))
+
+	if instance.Spec.SizeQuota != nil {
+		quota := instance.Spec.SizeQuota.Value()
+		metrics.PostgresDatabaseQuota.WithLabelValues(instance.Namespace, instance.Name).Set(float64(quota))
+		health.QuotaExceeded = health.SizeBytes > quota
+		// Only warn when first going over, not on every collection.
+		if health.QuotaExceeded && !instance.Status.Health.QuotaExceeded {
+			ctx.Eventf(corev1.EventTypeWarning, "QuotaExceeded", "Database %s is %d bytes, over its quota of %s", instance.Spec.DatabaseName, health.SizeBytes, instance.Spec.SizeQuota.String())
+		}
+	} else {
+		metrics.PostgresDatabaseQuota.DeleteLabelValues(instance.Namespace, instance.Name)
+	}
+
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.Health = health
+		return nil
+	}, RequeueAfter: healthInterval}, nil
+}
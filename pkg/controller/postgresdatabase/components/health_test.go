@@ -0,0 +1,160 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
+	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresDatabase Health Component", func() {
+	comp := pdcomponents.NewHealth()
+	var dbMock sqlmock.Sqlmock
+	var db *sql.DB
+
+	BeforeEach(func() {
+		comp = pdcomponents.NewHealth()
+		var err error
+		db, dbMock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		dbpool.Dbs.Store("postgres host=mydb port=5432 dbname=foo_dev user=myuser password='mypassword' sslmode=require", db)
+
+		instance.Spec.DatabaseName = "foo_dev"
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.DatabaseClusterStatus = dbv1beta1.StatusReady
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "summon-dev"},
+			Data: map[string][]byte{
+				"password": []byte("mypassword"),
+			},
+		}
+		ctx.Client = fake.NewFakeClient(instance, secret)
+	})
+
+	AfterEach(func() {
+		db.Close()
+		dbpool.Dbs.Delete("postgres host=mydb port=5432 dbname=foo_dev user=myuser password='mypassword' sslmode=require")
+
+		// Check for any unmet expectations.
+		err := dbMock.ExpectationsWereMet()
+		if err != nil {
+			Fail(fmt.Sprintf("there were unfulfilled database expectations: %s", err))
+		}
+	})
+
+	expectQueries := func(size int64) {
+		dbMock.ExpectQuery(`SELECT pg_database_size`).WillReturnRows(sqlmock.NewRows([]string{"size", "max"}).AddRow(size, 100))
+		dbMock.ExpectQuery(`FROM pg_stat_activity`).WillReturnRows(sqlmock.NewRows([]string{"count", "long", "oldest"}).AddRow(12, 1, 900))
+		dbMock.ExpectQuery(`FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname", "extversion"}).AddRow("plpgsql", "1.0").AddRow("postgis", "3.0.1"))
+	}
+
+	It("is not reconcilable until the database is ready", func() {
+		instance.Status.Status = dbv1beta1.StatusCreating
+		Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+	})
+
+	It("collects database health", func() {
+		expectQueries(1024)
+
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(5 * time.Minute))
+		Expect(res.StatusModifier(instance)).To(Succeed())
+
+		health := instance.Status.Health
+		Expect(health.SizeBytes).To(Equal(int64(1024)))
+		Expect(health.MaxConnections).To(Equal(100))
+		Expect(health.Connections).To(Equal(12))
+		Expect(health.LongTransactions).To(Equal(1))
+		Expect(health.OldestTransactionSeconds).To(Equal(int64(900)))
+		Expect(health.Extensions).To(Equal(map[string]string{"plpgsql": "1.0", "postgis": "3.0.1"}))
+		Expect(health.QuotaExceeded).To(BeFalse())
+		Expect(health.CollectedAt).ToNot(BeEmpty())
+		Expect(testutil.ToFloat64(metrics.PostgresDatabaseSize.WithLabelValues("summon-dev", "foo-dev"))).To(Equal(1024.0))
+		Expect(testutil.ToFloat64(metrics.PostgresDatabaseConnections.WithLabelValues("summon-dev", "foo-dev"))).To(Equal(12.0))
+	})
+
+	It("collects replication lag for local clusters", func() {
+		instance.Status.DatabaseClusterStatus = "Running"
+		dbMock.ExpectQuery(`SELECT pg_database_size`).WillReturnRows(sqlmock.NewRows([]string{"size", "max"}).AddRow(1024, 100))
+		dbMock.ExpectQuery(`FROM pg_stat_activity`).WillReturnRows(sqlmock.NewRows([]string{"count", "long", "oldest"}).AddRow(1, 0, 0))
+		dbMock.ExpectQuery(`FROM pg_stat_replication`).WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(3))
+		dbMock.ExpectQuery(`FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname", "extversion"}))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Health.ReplicationLagSeconds).To(Equal(int64(3)))
+	})
+
+	It("does not collect again too soon", func() {
+		instance.Status.Health.CollectedAt = time.Now().Add(-time.Minute).Format(time.UnixDate)
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusModifier).To(BeNil())
+		Expect(res.RequeueAfter).To(BeNumerically("~", 4*time.Minute, time.Second*2))
+	})
+
+	It("warns when the database goes over its quota", func() {
+		quota := resource.MustParse("1Ki")
+		instance.Spec.SizeQuota = &quota
+		expectQueries(2048)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Health.QuotaExceeded).To(BeTrue())
+		Expect(testutil.ToFloat64(metrics.PostgresDatabaseQuota.WithLabelValues("summon-dev", "foo-dev"))).To(Equal(1024.0))
+		recorder := ctx.Recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix(corev1.EventTypeWarning + " QuotaExceeded")))
+	})
+
+	It("only warns once while over quota", func() {
+		quota := resource.MustParse("1Ki")
+		instance.Spec.SizeQuota = &quota
+		instance.Status.Health.QuotaExceeded = true
+		expectQueries(2048)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Health.QuotaExceeded).To(BeTrue())
+		recorder := ctx.Recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("removes the metrics on deletion", func() {
+		metrics.PostgresDatabaseSize.WithLabelValues("summon-dev", "foo-dev").Set(1024)
+		now := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&now)
+		Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		Expect(comp).To(ReconcileContext(ctx))
+		// A fresh gauge starts back at zero.
+		Expect(testutil.ToFloat64(metrics.PostgresDatabaseSize.WithLabelValues("summon-dev", "foo-dev"))).To(Equal(0.0))
+	})
+})
@@ -42,8 +42,8 @@ func (_ *periscopeUserComponent) WatchTypes() []runtime.Object {
 
 func (_ *periscopeUserComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
-	// Reconcilable so long as database and periscope user is ready.
-	return (instance.Status.DatabaseStatus == dbv1beta1.StatusReady && (instance.Status.SharedUsers.Periscope == dbv1beta1.StatusReady || instance.Status.SharedUsers.Periscope == dbv1beta1.StatusGranted))
+	// Reconcilable so long as database and periscope user is ready, and the database isn't being deleted.
+	return (instance.Status.DatabaseStatus == dbv1beta1.StatusReady && (instance.Status.SharedUsers.Periscope == dbv1beta1.StatusReady || instance.Status.SharedUsers.Periscope == dbv1beta1.StatusGranted)) && instance.DeletionTimestamp.IsZero()
 }
 
 func (comp *periscopeUserComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	postgresv1 "github.com/zalando-incubator/postgres-operator/pkg/apis/acid.zalan.do/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+type poolerComponent struct{}
+
+func NewPooler() *poolerComponent {
+	return &poolerComponent{}
+}
+
+func (_ *poolerComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{},
+		&corev1.Service{},
+	}
+}
+
+func (_ *poolerComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	return (instance.Status.DatabaseClusterStatus == dbv1beta1.StatusReady || instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String()) && instance.DeletionTimestamp.IsZero()
+}
+
+func (comp *poolerComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+
+	if instance.Spec.Pooler == nil {
+		return comp.deletePooler(ctx)
+	}
+
+	// Always build the direct connection from scratch, Status.Connection may already point at the pooler.
+	conn := instance.Status.Connection.DeepCopy()
+	conn.Host = instance.Status.AdminConnection.Host
+	conn.Port = instance.Status.AdminConnection.Port
+	conn.SSLMode = instance.Status.AdminConnection.SSLMode
+	conn.Database = instance.Spec.DatabaseName
+	if conn.Username == "" || conn.PasswordSecretRef.Name == "" {
+		// Owner user isn't ready yet.
+		return components.Result{}, nil
+	}
+
+	password, err := conn.PasswordSecretRef.Resolve(ctx, "password")
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "pooler: error getting owner password")
+	}
+	passwordHash := sha1.Sum([]byte(password))
+
+	extra := map[string]interface{}{}
+	extra["Conn"] = conn
+	extra["passwordHash"] = hex.EncodeToString(passwordHash[:])
+
+	var existing *appsv1.Deployment
+	res, _, err := ctx.CreateOrUpdate("pgbouncer.yml.tpl", extra, func(goalObj, existingObj runtime.Object) error {
+		goal := goalObj.(*appsv1.Deployment)
+		existing = existingObj.(*appsv1.Deployment)
+		existing.Spec = goal.Spec
+		return nil
+	})
+	if err != nil {
+		return res, errors.Wrap(err, "pooler: error reconciling pgbouncer deployment")
+	}
+	_, _, err = ctx.CreateOrUpdate("pgbouncer-service.yml.tpl", nil, func(goalObj, existingObj runtime.Object) error {
+		goal := goalObj.(*corev1.Service)
+		existing := existingObj.(*corev1.Service)
+		// Special case: Services mutate the ClusterIP value in the Spec and it should be preserved.
+		goal.Spec.ClusterIP = existing.Spec.ClusterIP
+		existing.Spec = goal.Spec
+		return nil
+	})
+	if err != nil {
+		return res, errors.Wrap(err, "pooler: error reconciling pgbouncer service")
+	}
+
+	poolerHost := fmt.Sprintf("%s-pgbouncer", instance.Name)
+	setConnection := func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.PoolerHost = poolerHost
+		instance.Status.Connection.Host = poolerHost
+		instance.Status.Connection.Port = 5432
+		// TLS is between PgBouncer and the database, clients inside the cluster talk to it in plain text.
+		instance.Status.Connection.SSLMode = "disable"
+		return nil
+	}
+
+	if existing.Status.AvailableReplicas == 0 {
+		// Keep using the direct connection until a pooler is up so clients don't get pointed at nothing. Once
+		// they have been moved over stay on the pooler, flipping DATABASE_URL would restart every app.
+		if instance.Status.PoolerHost == poolerHost {
+			return components.Result{StatusModifier: setConnection, RequeueAfter: 10 * time.Second}, nil
+		}
+		return components.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	return components.Result{StatusModifier: setConnection}, nil
+}
+
+func (comp *poolerComponent) deletePooler(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
+	meta := metav1.ObjectMeta{Name: fmt.Sprintf("%s-pgbouncer", instance.Name), Namespace: instance.Namespace}
+	for _, obj := range []runtime.Object{&appsv1.Deployment{ObjectMeta: meta}, &corev1.Service{ObjectMeta: meta}} {
+		err := ctx.Delete(ctx.Context, obj)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return components.Result{}, errors.Wrap(err, "pooler: error deleting pgbouncer")
+		}
+	}
+	if instance.Status.PoolerHost == "" {
+		return components.Result{}, nil
+	}
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresDatabase)
+		instance.Status.PoolerHost = ""
+		return nil
+	}}, nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	pdcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresdatabase/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresDatabase Pooler Component", func() {
+	comp := pdcomponents.NewPooler()
+	var ownerSecret *corev1.Secret
+
+	BeforeEach(func() {
+		comp = pdcomponents.NewPooler()
+		replicas := int32(2)
+		instance.Spec.DatabaseName = "foo_dev"
+		instance.Spec.Pooler = &dbv1beta1.PostgresDatabasePoolerSpec{
+			PoolMode:             "transaction",
+			PoolSize:             20,
+			MaxClientConnections: 1000,
+			Replicas:             &replicas,
+		}
+		instance.Status.DatabaseClusterStatus = dbv1beta1.StatusReady
+		instance.Status.Connection = dbv1beta1.PostgresConnection{
+			Host:     "mydb",
+			Port:     5432,
+			Username: "foo_dev",
+			Database: "foo_dev",
+			PasswordSecretRef: helpers.SecretRef{
+				Name: "foo-dev.postgres-user-password",
+				Key:  "password",
+			},
+		}
+		ownerSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.postgres-user-password", Namespace: "summon-dev"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}
+		ctx.Client = fake.NewFakeClient(instance, ownerSecret)
+	})
+
+	It("is not reconcilable until the database cluster is ready", func() {
+		instance.Status.DatabaseClusterStatus = dbv1beta1.StatusCreating
+		Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+	})
+
+	It("creates a pgbouncer deployment and service", func() {
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "DB_HOST", Value: "mydb"}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "DB_NAME", Value: "foo_dev"}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "POOL_MODE", Value: "transaction"}))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "DEFAULT_POOL_SIZE", Value: "20"}))
+		Expect(deployment.Spec.Template.Annotations["ridecell.io/passwordHash"]).ToNot(BeEmpty())
+
+		service := &corev1.Service{}
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, service)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service.Spec.Ports[0].Port).To(Equal(int32(5432)))
+
+		// Not available yet, so still the direct connection.
+		Expect(instance.Status.Connection.Host).To(Equal("mydb"))
+	})
+
+	It("points the connection at the pooler once it is available", func() {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 2},
+		}
+		ctx.Client = fake.NewFakeClient(instance, ownerSecret, deployment)
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Connection.Host).To(Equal("foo-dev-pgbouncer"))
+		Expect(instance.Status.Connection.Port).To(Equal(5432))
+		Expect(instance.Status.Connection.Username).To(Equal("foo_dev"))
+		Expect(instance.Status.PoolerHost).To(Equal("foo-dev-pgbouncer"))
+	})
+
+	It("keeps the connection on the pooler while its pods restart", func() {
+		// Earlier components have already reset the connection to the database itself.
+		instance.Status.PoolerHost = "foo-dev-pgbouncer"
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 0},
+		}
+		ctx.Client = fake.NewFakeClient(instance, ownerSecret, deployment)
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Connection.Host).To(Equal("foo-dev-pgbouncer"))
+		Expect(instance.Status.Connection.SSLMode).To(Equal("disable"))
+	})
+
+	It("leaves the pool mode to pgbouncer when it isn't set", func() {
+		instance.Spec.Pooler.PoolMode = ""
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+			Expect(env.Name).ToNot(Equal("POOL_MODE"))
+		}
+	})
+
+	It("always connects pgbouncer to the database directly", func() {
+		instance.Status.Connection.Host = "foo-dev-pgbouncer"
+		instance.Status.Connection.SSLMode = "disable"
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DB_HOST", Value: "mydb"}))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "SERVER_TLS_SSLMODE", Value: "require"}))
+	})
+
+	It("removes the pooler when it is turned off", func() {
+		instance.Spec.Pooler = nil
+		meta := metav1.ObjectMeta{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}
+		ctx.Client = fake.NewFakeClient(instance, &appsv1.Deployment{ObjectMeta: meta}, &corev1.Service{ObjectMeta: meta})
+		Expect(comp).To(ReconcileContext(ctx))
+
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, &appsv1.Deployment{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-pgbouncer", Namespace: "summon-dev"}, &corev1.Service{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("forgets the pooler host when it is turned off", func() {
+		instance.Spec.Pooler = nil
+		instance.Status.PoolerHost = "foo-dev-pgbouncer"
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.PoolerHost).To(BeEmpty())
+	})
+})
@@ -46,6 +46,17 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 	status := instance.Status
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*dbv1beta1.PostgresDatabase)
+		// Replicas have the same users and databases as the primary, only the host differs. They are never
+		// behind the pooler.
+		var replicaConnections []dbv1beta1.PostgresConnection
+		for _, adminReplica := range instance.Status.AdminReplicaConnections {
+			replica := instance.Status.Connection
+			replica.Host = adminReplica.Host
+			replica.Port = adminReplica.Port
+			replica.SSLMode = adminReplica.SSLMode
+			replicaConnections = append(replicaConnections, replica)
+		}
+		instance.Status.ReplicaConnections = replicaConnections
 		if status.DatabaseClusterStatus != dbv1beta1.StatusReady && status.DatabaseClusterStatus != postgresv1.ClusterStatusRunning.String() {
 			return nil
 		}
@@ -64,6 +75,9 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 				return nil
 			}
 		}
+		if status.Clone.Status != "" && status.Clone.Status != dbv1beta1.StatusReady {
+			return nil
+		}
 		instance.Status.Status = dbv1beta1.StatusReady
 		// TODO a better message
 		instance.Status.Message = fmt.Sprintf("Database %s ready", dbName)
@@ -40,7 +40,8 @@ func (_ *userComponent) WatchTypes() []runtime.Object {
 
 func (_ *userComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*dbv1beta1.PostgresDatabase)
-	return (instance.Status.DatabaseClusterStatus == dbv1beta1.StatusReady || instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String()) && !instance.Spec.SkipUser
+	// Don't recreate the user while the deletion component is dropping it.
+	return (instance.Status.DatabaseClusterStatus == dbv1beta1.StatusReady || instance.Status.DatabaseClusterStatus == postgresv1.ClusterStatusRunning.String()) && !instance.Spec.SkipUser && instance.DeletionTimestamp.IsZero()
 }
 
 func (comp *userComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
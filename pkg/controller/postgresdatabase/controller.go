@@ -30,13 +30,19 @@ import (
 func Add(mgr manager.Manager) error {
 	_, err := components.NewReconciler("postgresdatabase-controller", mgr, &dbv1beta1.PostgresDatabase{}, Templates, []components.Component{
 		pdcomponents.NewDefaults(),
+		// Before anything that can fail, a failed component blocks everything after it.
+		pdcomponents.NewDeletion(),
 		spcomponents.NewPostgres("Exclusive"),
 		pdcomponents.NewSecret(),
 		pdcomponents.NewUser(),
 		pdcomponents.NewDatabase(),
 		pdcomponents.NewPeriscopeUser(),
 		pdcomponents.NewExtensions(),
+		pdcomponents.NewClone(),
+		pdcomponents.NewPooler(),
 		pdcomponents.NewStatus(),
+		pdcomponents.NewHealth(),
+		pdcomponents.NewBackupSchedule(),
 	})
 	return err
 }
@@ -0,0 +1,12 @@
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Instance.Name }}-archive-url
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: archive
+    app.kubernetes.io/instance: {{ .Instance.Name }}-archive
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+data: {}
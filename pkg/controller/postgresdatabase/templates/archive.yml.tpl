@@ -0,0 +1,82 @@
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-archive
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: archive
+    app.kubernetes.io/instance: {{ .Instance.Name }}-archive
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+  annotations:
+    ridecell.io/archive-location: {{ .Extra.location | quote }}
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: archive
+        app.kubernetes.io/instance: {{ .Instance.Name }}-archive
+        app.kubernetes.io/component: database
+        app.kubernetes.io/part-of: {{ .Instance.Name }}
+        app.kubernetes.io/managed-by: ridecell-operator
+    spec:
+      restartPolicy: Never
+      volumes:
+      - name: dump
+        emptyDir: {}
+      initContainers:
+      # Dump to a local file first so the upload knows the size up front.
+      - name: dump
+        image: postgres:13-alpine
+        command:
+        - pg_dump
+        - --format=custom
+        - --no-owner
+        - --file=/dump/database.dump
+        env:
+        - name: PGHOST
+          value: {{ .Instance.Status.AdminConnection.Host | quote }}
+        - name: PGPORT
+          value: {{ .Instance.Status.AdminConnection.Port | default 5432 | quote }}
+        - name: PGUSER
+          value: {{ .Instance.Status.AdminConnection.Username | quote }}
+        - name: PGDATABASE
+          value: {{ .Instance.Spec.DatabaseName | quote }}
+        - name: PGSSLMODE
+          value: {{ .Instance.Status.AdminConnection.SSLMode | default "require" | quote }}
+        - name: PGPASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Instance.Status.AdminConnection.PasswordSecretRef.Name }}
+              key: {{ .Instance.Status.AdminConnection.PasswordSecretRef.Key | default "password" }}
+        resources:
+          requests:
+            memory: 256M
+            cpu: 100m
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
+      containers:
+      - name: upload
+        image: curlimages/curl:7.73.0
+        command:
+        - curl
+        - --fail
+        - --silent
+        - --show-error
+        - --upload-file
+        - /dump/database.dump
+        - $(ARCHIVE_URL)
+        env:
+        # Presigned URLs are bearer credentials, keep them out of the Job spec.
+        - name: ARCHIVE_URL
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Instance.Name }}-archive-url
+              key: url
+        terminationMessagePolicy: FallbackToLogsOnError
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
@@ -0,0 +1,14 @@
+apiVersion: db.ridecell.io/v1beta1
+kind: PostgresBackup
+metadata:
+  name: {{ .Extra.name }}
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  database: {{ .Instance.Name }}
+  {{- with .Instance.Spec.Backups.BucketName }}
+  bucketName: {{ . }}
+  {{- end }}
+  ttl: {{ .Instance.Spec.Backups.TTL.Duration }}
@@ -0,0 +1,96 @@
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-clone
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: clone
+    app.kubernetes.io/instance: {{ .Instance.Name }}-clone
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: clone
+        app.kubernetes.io/instance: {{ .Instance.Name }}-clone
+        app.kubernetes.io/component: database
+        app.kubernetes.io/part-of: {{ .Instance.Name }}
+        app.kubernetes.io/managed-by: ridecell-operator
+    spec:
+      restartPolicy: Never
+      volumes:
+      - name: dump
+        emptyDir: {}
+      {{- with .Instance.Spec.CloneFrom.ScrubScriptRef }}
+      - name: scrub
+        configMap:
+          name: {{ .Name }}
+          items:
+          - key: {{ .Key }}
+            path: scrub.sql
+      {{- end }}
+      containers:
+      - name: clone
+        image: postgres:13-alpine
+        command:
+        - sh
+        - -c
+        # Extensions are installed by the operator before the copy, so leave them out of the restore.
+        - |
+          set -e
+          PGHOST="$SOURCE_HOST" PGPORT="$SOURCE_PORT" PGUSER="$SOURCE_USER" PGPASSWORD="$SOURCE_PASSWORD" PGSSLMODE="$SOURCE_SSLMODE" \
+            pg_dump --format=custom --no-owner --no-acl --dbname="$SOURCE_DATABASE" --file=/dump/database.dump
+          pg_restore --list /dump/database.dump | grep -v ' EXTENSION ' > /dump/restore.list
+          pg_restore --no-owner --no-acl --role="$OWNER" --use-list=/dump/restore.list --dbname="$PGDATABASE" /dump/database.dump
+          if [ -f /scrub/scrub.sql ]; then
+            PGOPTIONS="-c role=$OWNER" psql --single-transaction --set=ON_ERROR_STOP=1 --file=/scrub/scrub.sql
+          fi
+        env:
+        - name: SOURCE_HOST
+          value: {{ .Extra.source.Host | quote }}
+        - name: SOURCE_PORT
+          value: {{ .Extra.source.Port | default 5432 | quote }}
+        - name: SOURCE_USER
+          value: {{ .Extra.source.Username | quote }}
+        - name: SOURCE_DATABASE
+          value: {{ .Extra.source.Database | quote }}
+        - name: SOURCE_SSLMODE
+          value: {{ .Extra.source.SSLMode | default "require" | quote }}
+        - name: SOURCE_PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Extra.source.PasswordSecretRef.Name }}
+              key: {{ .Extra.source.PasswordSecretRef.Key | default "password" }}
+        - name: OWNER
+          value: {{ .Instance.Spec.Owner | quote }}
+        - name: PGHOST
+          value: {{ .Instance.Status.AdminConnection.Host | quote }}
+        - name: PGPORT
+          value: {{ .Instance.Status.AdminConnection.Port | default 5432 | quote }}
+        - name: PGUSER
+          value: {{ .Instance.Status.AdminConnection.Username | quote }}
+        - name: PGDATABASE
+          value: {{ .Instance.Spec.DatabaseName | quote }}
+        - name: PGSSLMODE
+          value: {{ .Instance.Status.AdminConnection.SSLMode | default "require" | quote }}
+        - name: PGPASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Instance.Status.AdminConnection.PasswordSecretRef.Name }}
+              key: {{ .Instance.Status.AdminConnection.PasswordSecretRef.Key | default "password" }}
+        # On failure the end of the logs ends up in the termination message.
+        terminationMessagePolicy: FallbackToLogsOnError
+        resources:
+          requests:
+            memory: 256M
+            cpu: 100m
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
+        {{- if .Instance.Spec.CloneFrom.ScrubScriptRef }}
+        - name: scrub
+          mountPath: /scrub
+        {{- end }}
@@ -0,0 +1,19 @@
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Instance.Name }}-pgbouncer
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: pgbouncer
+    app.kubernetes.io/instance: {{ .Instance.Name }}-pgbouncer
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  selector:
+    app.kubernetes.io/name: pgbouncer
+    app.kubernetes.io/instance: {{ .Instance.Name }}-pgbouncer
+  ports:
+  - name: postgres
+    port: 5432
+    targetPort: pgbouncer
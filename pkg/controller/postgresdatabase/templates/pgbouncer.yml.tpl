@@ -0,0 +1,70 @@
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Instance.Name }}-pgbouncer
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: pgbouncer
+    app.kubernetes.io/instance: {{ .Instance.Name }}-pgbouncer
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  replicas: {{ .Instance.Spec.Pooler.Replicas }}
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: pgbouncer
+      app.kubernetes.io/instance: {{ .Instance.Name }}-pgbouncer
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: pgbouncer
+        app.kubernetes.io/instance: {{ .Instance.Name }}-pgbouncer
+        app.kubernetes.io/component: database
+        app.kubernetes.io/part-of: {{ .Instance.Name }}
+        app.kubernetes.io/managed-by: ridecell-operator
+      annotations:
+        # PgBouncer only reads the password at startup, restart it when the password changes.
+        ridecell.io/passwordHash: {{ .Extra.passwordHash }}
+    spec:
+      containers:
+      - name: pgbouncer
+        image: edoburu/pgbouncer:1.15.0
+        env:
+        - name: DB_HOST
+          value: {{ .Extra.Conn.Host | quote }}
+        - name: DB_PORT
+          value: {{ .Extra.Conn.Port | default 5432 | quote }}
+        - name: DB_NAME
+          value: {{ .Extra.Conn.Database | quote }}
+        - name: DB_USER
+          value: {{ .Extra.Conn.Username | quote }}
+        - name: DB_PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Extra.Conn.PasswordSecretRef.Name }}
+              key: {{ .Extra.Conn.PasswordSecretRef.Key | default "password" }}
+        - name: SERVER_TLS_SSLMODE
+          value: {{ .Extra.Conn.SSLMode | default "require" | quote }}
+        - name: AUTH_TYPE
+          value: md5
+        - name: LISTEN_PORT
+          value: "6432"
+        {{ if .Instance.Spec.Pooler.PoolMode }}
+        - name: POOL_MODE
+          value: {{ .Instance.Spec.Pooler.PoolMode | quote }}
+        {{ end }}
+        - name: DEFAULT_POOL_SIZE
+          value: {{ .Instance.Spec.Pooler.PoolSize | quote }}
+        - name: MAX_CLIENT_CONN
+          value: {{ .Instance.Spec.Pooler.MaxClientConnections | quote }}
+        ports:
+        - name: pgbouncer
+          containerPort: 6432
+        readinessProbe:
+          tcpSocket:
+            port: pgbouncer
+        resources:
+          requests:
+            memory: 32M
+            cpu: 50m
@@ -6,3 +6,4 @@ metadata:
 spec:
   username: {{ .Instance.Spec.Owner | quote }}
   connection: {{ .Instance.Status.AdminConnection | toJson }}
+  rotation: {{ .Instance.Spec.PasswordRotation | toJson }}
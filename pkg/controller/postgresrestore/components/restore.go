@@ -0,0 +1,200 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+// How long the download URL for the dump is valid, this has to cover the job waiting to be scheduled.
+const downloadURLExpiry = 12 * time.Hour
+
+type S3Factory func(region string) (s3iface.S3API, error)
+
+type restoreComponent struct {
+	s3Factory S3Factory
+}
+
+func realS3Factory(region string) (s3iface.S3API, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(metrics.InstrumentAWSSession(sess)), nil
+}
+
+func NewRestore() *restoreComponent {
+	return &restoreComponent{s3Factory: realS3Factory}
+}
+
+func (comp *restoreComponent) InjectS3Factory(factory S3Factory) {
+	comp.s3Factory = factory
+}
+
+func (_ *restoreComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&batchv1.Job{},
+	}
+}
+
+func (_ *restoreComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (comp *restoreComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresRestore)
+
+	// Restores are one-shot, nothing left to do once the job has finished. The job is only cleaned up now that
+	// Ready has been saved, otherwise a failed status write would run the restore again.
+	if instance.Status.Status == dbv1beta1.StatusReady {
+		return components.Result{}, comp.deleteJob(ctx, instance)
+	}
+
+	job := &batchv1.Job{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Name + "-restore", Namespace: instance.Namespace}, job)
+	if err != nil && k8serrors.IsNotFound(err) {
+		return comp.startRestore(ctx, instance)
+	} else if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: error getting restore job")
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			// Leave the job around for debugging, deleting it starts the restore again.
+			message := fmt.Sprintf("Restore job %s/%s failed: %s", job.Namespace, job.Name, condition.Message)
+			ctx.Eventf(corev1.EventTypeWarning, "RestoreFailed", "%s", message)
+			return components.Result{StatusModifier: setStatus(dbv1beta1.StatusError, message)}, nil
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		// Still running, the job watch will trigger a reconcile when it finishes.
+		return components.Result{StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Restore job %s/%s running", job.Namespace, job.Name))}, nil
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "RestoreReady", "Restored backup %s into database %s", instance.Spec.Backup, instance.Spec.Database)
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresRestore)
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.Message = "Restore complete"
+		instance.Status.CompletedAt = time.Now().Format(time.UnixDate)
+		return nil
+	}}, nil
+}
+
+func (comp *restoreComponent) startRestore(ctx *components.ComponentContext, instance *dbv1beta1.PostgresRestore) (components.Result, error) {
+	backup := &dbv1beta1.PostgresBackup{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.Backup, Namespace: instance.Namespace}, backup)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: error getting postgres backup %s", instance.Spec.Backup)
+	}
+	if backup.Status.Status != dbv1beta1.StatusReady {
+		return components.Result{RequeueAfter: 30 * time.Second, StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Waiting for postgres backup %s", backup.Name))}, nil
+	}
+
+	db := &dbv1beta1.PostgresDatabase{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.Database, Namespace: instance.Namespace}, db)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: error getting postgres database %s", instance.Spec.Database)
+	}
+	if db.Status.Status != dbv1beta1.StatusReady {
+		return components.Result{RequeueAfter: 30 * time.Second, StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Waiting for postgres database %s", db.Name))}, nil
+	}
+
+	s3Service, err := comp.s3Factory(backup.Spec.Region)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: failed to get s3 service")
+	}
+	req, _ := s3Service.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(backup.Spec.BucketName),
+		Key:    aws.String(backup.Spec.Key),
+	})
+	urlStr, err := req.Presign(downloadURLExpiry)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: failed to presign s3 url")
+	}
+
+	extra := map[string]interface{}{}
+	extra["database"] = db
+	_, _, err = ctx.CreateOrUpdate("url-secret.yml.tpl", extra, func(_goalObj, existingObj runtime.Object) error {
+		existing := existingObj.(*corev1.Secret)
+		existing.Data = map[string][]byte{"url": []byte(urlStr)}
+		return nil
+	})
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "postgres_restore: error creating download url secret")
+	}
+	obj, err := ctx.GetTemplate("job.yml.tpl", extra)
+	if err != nil {
+		return components.Result{}, err
+	}
+	job := obj.(*batchv1.Job)
+	err = controllerutil.SetControllerReference(instance, job, ctx.Scheme)
+	if err != nil {
+		return components.Result{}, err
+	}
+	err = ctx.Create(ctx.Context, job)
+	if err != nil {
+		return components.Result{Requeue: true}, errors.Wrapf(err, "postgres_restore: error creating restore job %s/%s", job.Namespace, job.Name)
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "RestoreStarted", "Restoring %s into database %s", backup.Status.Location, db.Spec.DatabaseName)
+	return components.Result{StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Restore job %s/%s started", job.Namespace, job.Name))}, nil
+}
+
+func (comp *restoreComponent) deleteJob(ctx *components.ComponentContext, instance *dbv1beta1.PostgresRestore) error {
+	meta := metav1.ObjectMeta{Name: instance.Name + "-restore", Namespace: instance.Namespace}
+	err := ctx.Delete(ctx.Context, &batchv1.Job{ObjectMeta: meta}, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "postgres_restore: error deleting restore job")
+	}
+	meta.Name = instance.Name + "-restore-url"
+	err = ctx.Delete(ctx.Context, &corev1.Secret{ObjectMeta: meta})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "postgres_restore: error deleting download url secret")
+	}
+	return nil
+}
+
+func setStatus(status, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresRestore)
+		instance.Status.Status = status
+		instance.Status.Message = message
+		return nil
+	}
+}
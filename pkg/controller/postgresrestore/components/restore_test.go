@@ -0,0 +1,194 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	prcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresrestore/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("PostgresRestore Restore Component", func() {
+	comp := prcomponents.NewRestore()
+	var db *dbv1beta1.PostgresDatabase
+	var backup *dbv1beta1.PostgresBackup
+
+	BeforeEach(func() {
+		comp.InjectS3Factory(func(region string) (s3iface.S3API, error) {
+			// Presigning doesn't talk to AWS, a real client with fake credentials works.
+			sess := session.Must(session.NewSession(&aws.Config{
+				Region:      aws.String(region),
+				Credentials: credentials.NewStaticCredentials("garbage", "garbage", ""),
+			}))
+			return s3.New(sess), nil
+		})
+
+		db = &dbv1beta1.PostgresDatabase{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
+			Spec: dbv1beta1.PostgresDatabaseSpec{
+				DatabaseName: "foo_dev",
+				Owner:        "foo_dev",
+			},
+			Status: dbv1beta1.PostgresDatabaseStatus{
+				Status: dbv1beta1.StatusReady,
+				AdminConnection: dbv1beta1.PostgresConnection{
+					Host:              "mydb",
+					Port:              5432,
+					Username:          "myuser",
+					PasswordSecretRef: helpers.SecretRef{Name: "mysecret", Key: "password"},
+					Database:          "postgres",
+				},
+			},
+		}
+		backup = &dbv1beta1.PostgresBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-backup", Namespace: "summon-dev"},
+			Spec: dbv1beta1.PostgresBackupSpec{
+				Database:   "foo-dev",
+				BucketName: "ridecell-backups",
+				Region:     "us-west-2",
+				Key:        "postgres-backup/summon-dev/foo-dev-backup.dump",
+			},
+			Status: dbv1beta1.PostgresBackupStatus{
+				Status:   dbv1beta1.StatusReady,
+				Location: "s3://ridecell-backups/postgres-backup/summon-dev/foo-dev-backup.dump",
+			},
+		}
+	})
+
+	// Set up the client after the test has changed the instance.
+	setupClient := func(objs ...runtime.Object) {
+		ctx.Client = fake.NewFakeClient(append([]runtime.Object{instance, db, backup}, objs...)...)
+	}
+
+	getJob := func() (*batchv1.Job, error) {
+		job := &batchv1.Job{}
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-restore-restore", Namespace: "summon-dev"}, job)
+		return job, err
+	}
+
+	Describe("IsReconcilable", func() {
+		It("retries after an error", func() {
+			instance.Status.Status = dbv1beta1.StatusError
+			Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		})
+	})
+
+	It("starts a restore job", func() {
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusCreating))
+		job, err := getJob()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(job.OwnerReferences).To(HaveLen(1))
+		Expect(job.Spec.Template.Spec.InitContainers[0].Env[0].Value).To(BeEmpty())
+		Expect(job.Spec.Template.Spec.InitContainers[0].Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("foo-dev-restore-restore-url"))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "OWNER", Value: "foo_dev"}))
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).ToNot(ContainSubstring("--clean"))
+
+		secret := &corev1.Secret{}
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-restore-restore-url", Namespace: "summon-dev"}, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(secret.Data["url"])).To(ContainSubstring("postgres-backup/summon-dev/foo-dev-backup.dump"))
+		Expect(string(secret.Data["url"])).To(ContainSubstring("X-Amz-Signature="))
+	})
+
+	It("drops existing objects with clean", func() {
+		instance.Spec.Clean = true
+		setupClient()
+
+		Expect(comp).To(ReconcileContext(ctx))
+		job, err := getJob()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring("--clean --if-exists"))
+	})
+
+	It("waits for the backup to finish", func() {
+		backup.Status.Status = dbv1beta1.StatusCreating
+		setupClient()
+
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).ToNot(BeZero())
+		_, err = getJob()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("errors if the backup does not exist", func() {
+		instance.Spec.Backup = "other"
+		setupClient()
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+
+	It("reports a failed job", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-restore-restore", Namespace: "summon-dev"},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}},
+			},
+		}
+		setupClient(job)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusError))
+	})
+
+	It("finishes once the job succeeds", func() {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-restore-restore", Namespace: "summon-dev"},
+			Status:     batchv1.JobStatus{Succeeded: 1},
+		}
+		setupClient(job)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+		Expect(instance.Status.CompletedAt).ToNot(BeEmpty())
+		// Kept until Ready has been saved, deleting it first could run the restore twice.
+		_, err := getJob()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("cleans up the job once ready", func() {
+		instance.Status.Status = dbv1beta1.StatusReady
+		meta := metav1.ObjectMeta{Name: "foo-dev-restore-restore", Namespace: "summon-dev"}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-restore-restore-url", Namespace: "summon-dev"}}
+		setupClient(&batchv1.Job{ObjectMeta: meta, Status: batchv1.JobStatus{Succeeded: 1}}, secret)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+		_, err := getJob()
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo-dev-restore-restore-url", Namespace: "summon-dev"}, &corev1.Secret{})
+		Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	})
+})
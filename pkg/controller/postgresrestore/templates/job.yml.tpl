@@ -0,0 +1,87 @@
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-restore
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: restore
+    app.kubernetes.io/instance: {{ .Instance.Name }}-restore
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: restore
+        app.kubernetes.io/instance: {{ .Instance.Name }}-restore
+        app.kubernetes.io/component: database
+        app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+        app.kubernetes.io/managed-by: ridecell-operator
+    spec:
+      restartPolicy: Never
+      volumes:
+      - name: dump
+        emptyDir: {}
+      initContainers:
+      - name: download
+        image: curlimages/curl:7.73.0
+        command:
+        - curl
+        - --fail
+        - --silent
+        - --show-error
+        - --output
+        - /dump/database.dump
+        - $(DOWNLOAD_URL)
+        env:
+        # Presigned URLs are bearer credentials, keep them out of the Job spec.
+        - name: DOWNLOAD_URL
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Instance.Name }}-restore-url
+              key: url
+        terminationMessagePolicy: FallbackToLogsOnError
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
+      containers:
+      - name: restore
+        image: postgres:13-alpine
+        command:
+        - sh
+        - -c
+        # Extensions are installed by the operator, so leave them out of the restore.
+        - |
+          set -e
+          pg_restore --list /dump/database.dump | grep -v ' EXTENSION ' > /dump/restore.list
+          pg_restore --no-owner --no-acl --role="$OWNER" {{ if .Instance.Spec.Clean }}--clean --if-exists {{ end }}--use-list=/dump/restore.list --dbname="$PGDATABASE" /dump/database.dump
+        env:
+        {{- with .Extra.database }}
+        - name: OWNER
+          value: {{ .Spec.Owner | quote }}
+        - name: PGHOST
+          value: {{ .Status.AdminConnection.Host | quote }}
+        - name: PGPORT
+          value: {{ .Status.AdminConnection.Port | default 5432 | quote }}
+        - name: PGUSER
+          value: {{ .Status.AdminConnection.Username | quote }}
+        - name: PGDATABASE
+          value: {{ .Spec.DatabaseName | quote }}
+        - name: PGSSLMODE
+          value: {{ .Status.AdminConnection.SSLMode | default "require" | quote }}
+        - name: PGPASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .Status.AdminConnection.PasswordSecretRef.Name }}
+              key: {{ .Status.AdminConnection.PasswordSecretRef.Key | default "password" }}
+        {{- end }}
+        terminationMessagePolicy: FallbackToLogsOnError
+        resources:
+          requests:
+            memory: 256M
+            cpu: 100m
+        volumeMounts:
+        - name: dump
+          mountPath: /dump
@@ -0,0 +1,12 @@
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Instance.Name }}-restore-url
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: restore
+    app.kubernetes.io/instance: {{ .Instance.Name }}-restore
+    app.kubernetes.io/component: database
+    app.kubernetes.io/part-of: {{ .Extra.database.Name }}
+    app.kubernetes.io/managed-by: ridecell-operator
+data: {}
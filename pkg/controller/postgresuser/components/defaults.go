@@ -45,5 +45,14 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 	if instance.Spec.Username == "" {
 		instance.Spec.Username = instance.Name
 	}
+	for i := range instance.Spec.Grants {
+		grant := &instance.Spec.Grants[i]
+		if grant.Database == "" {
+			grant.Database = instance.Spec.Connection.Database
+		}
+		if grant.Schema == "" {
+			grant.Schema = "public"
+		}
+	}
 	return components.Result{}, nil
 }
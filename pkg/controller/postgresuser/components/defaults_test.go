@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	pucomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresuser/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
@@ -38,4 +39,19 @@ var _ = Describe("PostgresUser Defaults Component", func() {
 		Expect(comp).To(ReconcileContext(ctx))
 		Expect(instance.Spec.Username).To(Equal("foo"))
 	})
+
+	It("sets the default database and schema on grants", func() {
+		instance.Spec.Connection.Database = "summon"
+		instance.Spec.Grants = []dbv1beta1.PostgresGrant{
+			{Profile: "readonly"},
+			{Database: "other", Schema: "reporting", Profile: "readwrite"},
+		}
+
+		comp := pucomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.Grants[0].Database).To(Equal("summon"))
+		Expect(instance.Spec.Grants[0].Schema).To(Equal("public"))
+		Expect(instance.Spec.Grants[1].Database).To(Equal("other"))
+		Expect(instance.Spec.Grants[1].Schema).To(Equal("reporting"))
+	})
 })
@@ -0,0 +1,310 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/components/postgres"
+)
+
+// Object types privileges can be granted on, in the order they are granted.
+var privilegeObjectTypes = []string{"DATABASE", "SCHEMA", "TABLES", "SEQUENCES"}
+
+// Privileges for each named profile, by object type.
+var profilePrivileges = map[string]map[string][]string{
+	dbv1beta1.PostgresProfileReadOnly: {
+		"DATABASE":  {"CONNECT"},
+		"SCHEMA":    {"USAGE"},
+		"TABLES":    {"SELECT"},
+		"SEQUENCES": {"SELECT"},
+	},
+	dbv1beta1.PostgresProfileReadWrite: {
+		"DATABASE":  {"CONNECT", "TEMPORARY"},
+		"SCHEMA":    {"USAGE"},
+		"TABLES":    {"SELECT", "INSERT", "UPDATE", "DELETE"},
+		"SEQUENCES": {"SELECT", "USAGE", "UPDATE"},
+	},
+	dbv1beta1.PostgresProfileOwner: {
+		"DATABASE":  {"CONNECT", "TEMPORARY", "CREATE"},
+		"SCHEMA":    {"USAGE", "CREATE"},
+		"TABLES":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+		"SEQUENCES": {"SELECT", "USAGE", "UPDATE"},
+	},
+}
+
+// Privileges which can be listed individually. These can't be quoted so they have to be checked against a list.
+var validTablePrivileges = map[string]bool{
+	"SELECT":     true,
+	"INSERT":     true,
+	"UPDATE":     true,
+	"DELETE":     true,
+	"TRUNCATE":   true,
+	"REFERENCES": true,
+	"TRIGGER":    true,
+}
+
+// The target of a GRANT or REVOKE statement, privileges with the same target are applied together.
+type privilegeTarget struct {
+	database   string
+	schema     string
+	objectType string
+	forRole    string
+}
+
+type grantsComponent struct{}
+
+func NewGrants() *grantsComponent {
+	return &grantsComponent{}
+}
+
+func (_ *grantsComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *grantsComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.PostgresUser)
+	// Wait for the user to exist.
+	return instance.Status.Status == dbv1beta1.StatusReady
+}
+
+func (comp *grantsComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresUser)
+
+	desired, err := desiredPrivileges(instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+	desiredRoles := uniqueStrings(instance.Spec.Roles)
+	if len(desired) == 0 && len(desiredRoles) == 0 && len(instance.Status.Privileges) == 0 && len(instance.Status.Roles) == 0 {
+		// Nothing granted and nothing to grant.
+		return components.Result{}, nil
+	}
+
+	quotedUsername := pq.QuoteIdentifier(instance.Spec.Username)
+
+	// Revoke anything removed from the spec first, so dropping e.g. readwrite down to readonly works.
+	wanted := map[dbv1beta1.PostgresPrivilege]bool{}
+	for _, privilege := range desired {
+		wanted[privilege] = true
+	}
+	removed := []dbv1beta1.PostgresPrivilege{}
+	for _, privilege := range instance.Status.Privileges {
+		if !wanted[privilege] {
+			removed = append(removed, privilege)
+		}
+	}
+	targets, privileges := groupPrivileges(removed)
+	for _, target := range targets {
+		err := comp.exec(ctx, instance, target.database, privilegeSQL(target, privileges[target], quotedUsername, false))
+		if err != nil {
+			if pqerr, ok := errors.Cause(err).(*pq.Error); ok && pqerr.Code == "3D000" {
+				// The database is gone, so are the privileges.
+				continue
+			}
+			return components.Result{}, errors.Wrapf(err, "postgres_user: failed to revoke privileges on %s", describeTarget(target))
+		}
+	}
+
+	// Always re-run the grants, GRANT ON ALL TABLES only covers tables which exist at the time.
+	targets, privileges = groupPrivileges(desired)
+	for _, target := range targets {
+		err := comp.exec(ctx, instance, target.database, privilegeSQL(target, privileges[target], quotedUsername, true))
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "postgres_user: failed to grant privileges on %s", describeTarget(target))
+		}
+	}
+
+	// Role memberships are cluster-wide, so use the connection database.
+	wantedRoles := map[string]bool{}
+	for _, role := range desiredRoles {
+		wantedRoles[role] = true
+	}
+	for _, role := range instance.Status.Roles {
+		if wantedRoles[role] {
+			continue
+		}
+		err := comp.exec(ctx, instance, instance.Spec.Connection.Database, fmt.Sprintf("REVOKE %s FROM %s", pq.QuoteIdentifier(role), quotedUsername))
+		if err != nil {
+			if pqerr, ok := errors.Cause(err).(*pq.Error); ok && pqerr.Code == "42704" {
+				// The role is gone, so is the membership.
+				continue
+			}
+			return components.Result{}, errors.Wrapf(err, "postgres_user: failed to revoke role %s", role)
+		}
+	}
+	for _, role := range desiredRoles {
+		err := comp.exec(ctx, instance, instance.Spec.Connection.Database, fmt.Sprintf("GRANT %s TO %s", pq.QuoteIdentifier(role), quotedUsername))
+		if err != nil {
+			return components.Result{}, errors.Wrapf(err, "postgres_user: failed to grant role %s", role)
+		}
+	}
+
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.PostgresUser)
+		instance.Status.Privileges = desired
+		instance.Status.Roles = desiredRoles
+		return nil
+	}}, nil
+}
+
+// Run a statement as the admin user in the given database.
+func (_ *grantsComponent) exec(ctx *components.ComponentContext, instance *dbv1beta1.PostgresUser, database string, query string) error {
+	conn := instance.Spec.Connection.DeepCopy()
+	conn.Database = database
+	db, err := postgres.Open(ctx, conn)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open db connection to %s", database)
+	}
+	_, err = db.Exec(query)
+	return err
+}
+
+// Expand the grants in the spec into individual privileges.
+func desiredPrivileges(instance *dbv1beta1.PostgresUser) ([]dbv1beta1.PostgresPrivilege, error) {
+	desired := []dbv1beta1.PostgresPrivilege{}
+	seen := map[dbv1beta1.PostgresPrivilege]bool{}
+	add := func(privilege dbv1beta1.PostgresPrivilege) {
+		if !seen[privilege] {
+			seen[privilege] = true
+			desired = append(desired, privilege)
+		}
+	}
+
+	for i, grant := range instance.Spec.Grants {
+		byType := map[string][]string{}
+		if grant.Profile != "" {
+			profile, ok := profilePrivileges[grant.Profile]
+			if !ok {
+				return nil, errors.Errorf("postgres_user: grant %d has unknown profile %s", i, grant.Profile)
+			}
+			for objectType, privileges := range profile {
+				byType[objectType] = append(byType[objectType], privileges...)
+			}
+		} else if len(grant.Privileges) == 0 {
+			return nil, errors.Errorf("postgres_user: grant %d needs a profile or privileges", i)
+		}
+		for _, privilege := range grant.Privileges {
+			privilege = strings.ToUpper(privilege)
+			if !validTablePrivileges[privilege] {
+				return nil, errors.Errorf("postgres_user: grant %d has unknown privilege %s", i, privilege)
+			}
+			byType["TABLES"] = append(byType["TABLES"], privilege)
+		}
+		if len(byType["DATABASE"]) == 0 {
+			// Table privileges are no use without being able to reach them.
+			byType["DATABASE"] = []string{"CONNECT"}
+			byType["SCHEMA"] = []string{"USAGE"}
+		}
+
+		for _, objectType := range privilegeObjectTypes {
+			schema := grant.Schema
+			if objectType == "DATABASE" {
+				schema = ""
+			}
+			for _, privilege := range byType[objectType] {
+				add(dbv1beta1.PostgresPrivilege{Database: grant.Database, Schema: schema, ObjectType: objectType, Privilege: privilege})
+			}
+		}
+		for _, role := range grant.DefaultPrivilegesFor {
+			for _, objectType := range []string{"TABLES", "SEQUENCES"} {
+				for _, privilege := range byType[objectType] {
+					add(dbv1beta1.PostgresPrivilege{Database: grant.Database, Schema: grant.Schema, ObjectType: objectType, ForRole: role, Privilege: privilege})
+				}
+			}
+		}
+	}
+	return desired, nil
+}
+
+// Group privileges by target, keeping the order they were first seen in.
+func groupPrivileges(privileges []dbv1beta1.PostgresPrivilege) ([]privilegeTarget, map[privilegeTarget][]string) {
+	targets := []privilegeTarget{}
+	grouped := map[privilegeTarget][]string{}
+	for _, privilege := range privileges {
+		target := privilegeTarget{database: privilege.Database, schema: privilege.Schema, objectType: privilege.ObjectType, forRole: privilege.ForRole}
+		if _, ok := grouped[target]; !ok {
+			targets = append(targets, target)
+		}
+		grouped[target] = append(grouped[target], privilege.Privilege)
+	}
+	return targets, grouped
+}
+
+// Build the GRANT or REVOKE statement for some privileges on a target.
+func privilegeSQL(target privilegeTarget, privileges []string, quotedUsername string, grant bool) string {
+	var on string
+	switch target.objectType {
+	case "DATABASE":
+		on = "DATABASE " + pq.QuoteIdentifier(target.database)
+	case "SCHEMA":
+		on = "SCHEMA " + pq.QuoteIdentifier(target.schema)
+	default:
+		if target.forRole != "" {
+			// Default privileges apply to object types, the schema is part of the ALTER DEFAULT PRIVILEGES.
+			on = target.objectType
+		} else {
+			on = fmt.Sprintf("ALL %s IN SCHEMA %s", target.objectType, pq.QuoteIdentifier(target.schema))
+		}
+	}
+
+	var query string
+	if grant {
+		query = fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(privileges, ", "), on, quotedUsername)
+	} else {
+		query = fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(privileges, ", "), on, quotedUsername)
+	}
+	if target.forRole != "" {
+		query = fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s %s", pq.QuoteIdentifier(target.forRole), pq.QuoteIdentifier(target.schema), query)
+	}
+	return query
+}
+
+// Human-readable name of a target, for errors.
+func describeTarget(target privilegeTarget) string {
+	switch target.objectType {
+	case "DATABASE":
+		return fmt.Sprintf("database %s", target.database)
+	case "SCHEMA":
+		return fmt.Sprintf("schema %s.%s", target.database, target.schema)
+	}
+	description := fmt.Sprintf("%s in %s.%s", strings.ToLower(target.objectType), target.database, target.schema)
+	if target.forRole != "" {
+		description = fmt.Sprintf("future %s created by %s", description, target.forRole)
+	}
+	return description
+}
+
+// Remove duplicates, keeping the first of each.
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ridecell/ridecell-operator/pkg/dbpool"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	postgresusercomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresuser/components"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("PostgresUser Grants Component", func() {
+	comp := postgresusercomponents.NewGrants()
+
+	var dbMock sqlmock.Sqlmock
+	var db *sql.DB
+
+	BeforeEach(func() {
+		instance.Spec.Connection = dbv1beta1.PostgresConnection{
+			Host:     "test-database",
+			Port:     5432,
+			Username: "test",
+			PasswordSecretRef: helpers.SecretRef{
+				Name: "foo-password-secret",
+				Key:  "password",
+			},
+			Database: "test",
+		}
+		instance.Spec.Username = "newuser"
+		instance.Status.Status = dbv1beta1.StatusReady
+
+		passwordSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-password-secret",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"password": []byte("1234totallysecurepassword"),
+			},
+		}
+		err := ctx.Client.Create(context.TODO(), passwordSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		db, dbMock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		dbpool.Dbs.Store("postgres host=test-database port=5432 dbname=test user=test password='1234totallysecurepassword' sslmode=require", db)
+	})
+
+	AfterEach(func() {
+		db.Close()
+		dbpool.Dbs.Delete("postgres host=test-database port=5432 dbname=test user=test password='1234totallysecurepassword' sslmode=require")
+
+		// Check for any unmet expectations.
+		err := dbMock.ExpectationsWereMet()
+		if err != nil {
+			Fail(fmt.Sprintf("there were unfulfilled database expectations: %s", err))
+		}
+	})
+
+	Describe("isReconcilable", func() {
+		It("waits for the user to be ready", func() {
+			instance.Status.Status = ""
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+			instance.Status.Status = dbv1beta1.StatusReady
+			Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		})
+	})
+
+	It("does nothing without grants", func() {
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Privileges).To(BeEmpty())
+	})
+
+	It("grants a readonly profile with default privileges and roles", func() {
+		instance.Spec.Grants = []dbv1beta1.PostgresGrant{
+			{Database: "test", Schema: "public", Profile: "readonly", DefaultPrivilegesFor: []string{"summon"}},
+		}
+		instance.Spec.Roles = []string{"analytics"}
+
+		dbMock.ExpectExec(`GRANT CONNECT ON DATABASE "test" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT USAGE ON SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT SELECT ON ALL SEQUENCES IN SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`ALTER DEFAULT PRIVILEGES FOR ROLE "summon" IN SCHEMA "public" GRANT SELECT ON TABLES TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`ALTER DEFAULT PRIVILEGES FOR ROLE "summon" IN SCHEMA "public" GRANT SELECT ON SEQUENCES TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT "analytics" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Privileges).To(HaveLen(6))
+		Expect(instance.Status.Privileges).To(ContainElement(dbv1beta1.PostgresPrivilege{Database: "test", Schema: "public", ObjectType: "TABLES", ForRole: "summon", Privilege: "SELECT"}))
+		Expect(instance.Status.Roles).To(Equal([]string{"analytics"}))
+	})
+
+	It("grants extra table privileges", func() {
+		instance.Spec.Grants = []dbv1beta1.PostgresGrant{
+			{Database: "test", Schema: "public", Privileges: []string{"select", "insert"}},
+		}
+
+		dbMock.ExpectExec(`GRANT CONNECT ON DATABASE "test" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT USAGE ON SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT SELECT, INSERT ON ALL TABLES IN SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Privileges).To(HaveLen(4))
+	})
+
+	It("revokes privileges and roles removed from the spec", func() {
+		instance.Spec.Grants = []dbv1beta1.PostgresGrant{
+			{Database: "test", Schema: "public", Profile: "readonly"},
+		}
+		instance.Status.Privileges = []dbv1beta1.PostgresPrivilege{
+			{Database: "test", ObjectType: "DATABASE", Privilege: "CONNECT"},
+			{Database: "test", ObjectType: "DATABASE", Privilege: "TEMPORARY"},
+			{Database: "test", Schema: "public", ObjectType: "SCHEMA", Privilege: "USAGE"},
+			{Database: "test", Schema: "public", ObjectType: "TABLES", Privilege: "SELECT"},
+			{Database: "test", Schema: "public", ObjectType: "TABLES", Privilege: "INSERT"},
+			{Database: "test", Schema: "public", ObjectType: "TABLES", Privilege: "UPDATE"},
+			{Database: "test", Schema: "public", ObjectType: "SEQUENCES", Privilege: "SELECT"},
+		}
+		instance.Status.Roles = []string{"analytics"}
+
+		dbMock.ExpectExec(`REVOKE TEMPORARY ON DATABASE "test" FROM "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`REVOKE INSERT, UPDATE ON ALL TABLES IN SCHEMA "public" FROM "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT CONNECT ON DATABASE "test" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT USAGE ON SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`GRANT SELECT ON ALL SEQUENCES IN SCHEMA "public" TO "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(`REVOKE "analytics" FROM "newuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Privileges).To(HaveLen(4))
+		Expect(instance.Status.Roles).To(BeEmpty())
+	})
+
+	It("rejects unknown privileges", func() {
+		instance.Spec.Grants = []dbv1beta1.PostgresGrant{
+			{Database: "test", Schema: "public", Privileges: []string{"SELECT; DROP TABLE users"}},
+		}
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+})
@@ -19,6 +19,7 @@ package components
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -43,14 +44,22 @@ func (_ *secretComponent) IsReconcilable(_ *components.ComponentContext) bool {
 }
 
 func (comp *secretComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.PostgresUser)
+	now := time.Now()
+	rotation := instance.Status.Rotation
+	request := instance.Annotations[dbv1beta1.PasswordRotationAnnotation]
+	rotate := rotation.Due(instance.Spec.Rotation, request, now)
+
 	var secretName string
+	var generated bool
 	res, _, err := ctx.CreateOrUpdate("secret.yml.tpl", nil, func(_goalObj, existingObj runtime.Object) error {
 		existing := existingObj.(*corev1.Secret)
 		// Store the name for the status output.
 		secretName = existing.Name
-		// Create a password if needed.
+		// Create a password if needed, or replace it if it is due for rotation.
 		val, ok := existing.Data["password"]
-		if !ok || len(val) == 0 {
+		if !ok || len(val) == 0 || rotate {
+			generated = true
 			rawPassword := make([]byte, 32)
 			_, err := rand.Read(rawPassword)
 			if err != nil {
@@ -62,11 +71,29 @@ func (comp *secretComponent) Reconcile(ctx *components.ComponentContext) (compon
 		}
 		return nil
 	})
+	if err != nil {
+		return res, err
+	}
+
+	if generated || rotation.LastRotated == "" {
+		// Existing passwords from before rotation was enabled count from now.
+		rotation.LastRotated = now.Format(time.UnixDate)
+	}
+	rotation.LastRequest = request
+	if generated && rotate {
+		ctx.Eventf(corev1.EventTypeNormal, "PasswordRotated", "Rotated password for Postgres user %s", instance.Spec.Username)
+	}
+	if next, ok := rotation.NextRotation(instance.Spec.Rotation); ok {
+		// Check back when the next rotation is due.
+		res.RequeueAfter = next.Sub(now)
+	}
+
 	res.StatusModifier = func(obj runtime.Object) error {
 		instance := obj.(*dbv1beta1.PostgresUser)
 		instance.Status.Connection.PasswordSecretRef.Name = secretName
 		instance.Status.Connection.PasswordSecretRef.Key = "password"
+		instance.Status.Rotation = rotation
 		return nil
 	}
-	return res, err
+	return res, nil
 }
@@ -14,6 +14,9 @@ limitations under the License.
 package components_test
 
 import (
+	"time"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	postgresusercomponents "github.com/Ridecell/ridecell-operator/pkg/controller/postgresuser/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -73,4 +76,56 @@ var _ = Describe("PostgresUser Secret Component", func() {
 		Expect(instance.Status.Connection.PasswordSecretRef.Name).To(Equal("foo.postgres-user-password"))
 		Expect(instance.Status.Connection.PasswordSecretRef.Key).To(Equal("password"))
 	})
+
+	Context("with password rotation", func() {
+		var secret *corev1.Secret
+
+		BeforeEach(func() {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo.postgres-user-password", Namespace: "default"},
+				Data: map[string][]byte{
+					"password": []byte("asdfqwer"),
+				},
+			}
+			ctx.Client = fake.NewFakeClient(instance, secret)
+			instance.Spec.Rotation.Interval = metav1.Duration{Duration: 24 * time.Hour}
+		})
+
+		It("starts the clock for an existing password", func() {
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically("~", 24*time.Hour, time.Minute))
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(instance.Status.Rotation.LastRotated).ToNot(Equal(""))
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo.postgres-user-password", Namespace: "default"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data).To(HaveKeyWithValue("password", []byte("asdfqwer")))
+		})
+
+		It("rotates the password once the interval passes", func() {
+			lastRotated := time.Now().Add(-48 * time.Hour).Format(time.UnixDate)
+			instance.Status.Rotation.LastRotated = lastRotated
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rotation.LastRotated).ToNot(Equal(lastRotated))
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo.postgres-user-password", Namespace: "default"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data["password"]).To(HaveLen(43))
+		})
+
+		It("rotates the password on request, once", func() {
+			instance.Status.Rotation.LastRotated = time.Now().Format(time.UnixDate)
+			instance.Annotations = map[string]string{dbv1beta1.PasswordRotationAnnotation: "2019-06-01"}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Rotation.LastRequest).To(Equal("2019-06-01"))
+			err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo.postgres-user-password", Namespace: "default"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data["password"]).To(HaveLen(43))
+
+			rotated := secret.Data["password"]
+			Expect(comp).To(ReconcileContext(ctx))
+			err = ctx.Get(ctx.Context, types.NamespacedName{Name: "foo.postgres-user-password", Namespace: "default"}, secret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Data).To(HaveKeyWithValue("password", rotated))
+		})
+	})
 })
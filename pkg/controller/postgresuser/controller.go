@@ -31,6 +31,7 @@ func Add(mgr manager.Manager) error {
 		postgresusercomponents.NewDefaults(),
 		postgresusercomponents.NewSecret(),
 		postgresusercomponents.NewPostgresUser(),
+		postgresusercomponents.NewGrants(),
 	})
 	return err
 }
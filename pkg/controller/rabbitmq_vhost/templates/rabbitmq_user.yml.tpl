@@ -10,3 +10,4 @@ spec:
     configure: .*
     write: .*
     read: .*
+  rotation: {{ .Instance.Spec.PasswordRotation | toJson }}
@@ -17,6 +17,7 @@ limitations under the License.
 package components_test
 
 import (
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	rmqucomponents "github.com/Ridecell/ridecell-operator/pkg/controller/rabbitmquser/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
@@ -76,4 +77,21 @@ var _ = Describe("RabbitmqUser Secret Component", func() {
 		Expect(instance.Status.Connection.PasswordSecretRef.Name).To(Equal("foo.rabbitmq-user-password"))
 		Expect(instance.Status.Connection.PasswordSecretRef.Key).To(Equal("password"))
 	})
+
+	It("rotates the password on request", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo.rabbitmq-user-password", Namespace: "default"},
+			Data: map[string][]byte{
+				"password": []byte("asdfqwer"),
+			},
+		}
+		ctx.Client = fake.NewFakeClient(instance, secret)
+		instance.Annotations = map[string]string{dbv1beta1.PasswordRotationAnnotation: "2019-06-01"}
+		Expect(comp).To(ReconcileContext(ctx))
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "foo.rabbitmq-user-password", Namespace: "default"}, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(secret.Data).To(HaveKeyWithValue("password", HaveLen(22)))
+		Expect(instance.Status.Rotation.LastRequest).To(Equal("2019-06-01"))
+		Expect(instance.Status.Rotation.LastRotated).ToNot(Equal(""))
+	})
 })
@@ -68,6 +68,20 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance.Spec.InstanceClass = "db.t3.micro"
 	}
 
+	if instance.Spec.StorageType == "" {
+		instance.Spec.StorageType = "gp2"
+	}
+
+	if instance.Spec.BackupRetentionPeriod == nil {
+		backupRetentionPeriod := int64(7)
+		instance.Spec.BackupRetentionPeriod = &backupRetentionPeriod
+	}
+
+	if instance.Spec.PubliclyAccessible == nil {
+		publiclyAccessible := true
+		instance.Spec.PubliclyAccessible = &publiclyAccessible
+	}
+
 	if instance.Spec.SubnetGroupName == "" {
 		instance.Spec.SubnetGroupName = os.Getenv("AWS_SUBNET_GROUP_NAME")
 	}
@@ -50,6 +50,9 @@ var _ = Describe("rds Defaults Component", func() {
 		Expect(instance.Spec.Engine).To(Equal("postgres"))
 		Expect(instance.Spec.EngineVersion).To(Equal("11"))
 		Expect(instance.Spec.InstanceClass).To(Equal("db.t3.micro"))
+		Expect(instance.Spec.StorageType).To(Equal("gp2"))
+		Expect(*instance.Spec.BackupRetentionPeriod).To(Equal(int64(7)))
+		Expect(*instance.Spec.PubliclyAccessible).To(BeTrue())
 	})
 
 })
@@ -19,6 +19,8 @@ package components
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
@@ -32,6 +34,7 @@ import (
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 const rdsInstanceParameterGroupFinalizer = "rdsinstance.parametergroup.finalizer"
@@ -41,7 +44,7 @@ type dbParameterGroupComponent struct {
 }
 
 func NewDBParameterGroup() *dbParameterGroupComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	rdsService := rds.New(sess)
 	return &dbParameterGroupComponent{rdsAPI: rdsService}
 }
@@ -92,15 +95,27 @@ func (comp *dbParameterGroupComponent) Reconcile(ctx *components.ComponentContex
 		return components.Result{}, nil
 	}
 
+	// A parameter group can't change family, so an engine upgrade moves the instance to a new group named after the family.
+	family := parameterGroupFamily(instance.Spec.Engine, instance.Spec.EngineVersion)
+	groupName := instance.Name
 	var parameterGroup *rds.DBParameterGroup
 	describeDBParameterGroupsOutput, err := comp.rdsAPI.DescribeDBParameterGroups(&rds.DescribeDBParameterGroupsInput{
-		DBParameterGroupName: aws.String(instance.Name),
+		DBParameterGroupName: aws.String(groupName),
 	})
+	if err == nil {
+		existingFamily := aws.StringValue(describeDBParameterGroupsOutput.DBParameterGroups[0].DBParameterGroupFamily)
+		if existingFamily != "" && existingFamily != family {
+			groupName = fmt.Sprintf("%s-%s", instance.Name, strings.Replace(family, ".", "-", -1))
+			describeDBParameterGroupsOutput, err = comp.rdsAPI.DescribeDBParameterGroups(&rds.DescribeDBParameterGroupsInput{
+				DBParameterGroupName: aws.String(groupName),
+			})
+		}
+	}
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBParameterGroupNotFoundFault {
 			createDBParameterGroupOutput, err := comp.rdsAPI.CreateDBParameterGroup(&rds.CreateDBParameterGroupInput{
-				DBParameterGroupName:   aws.String(instance.Name),
-				DBParameterGroupFamily: aws.String(fmt.Sprintf("%s%s", instance.Spec.Engine, instance.Spec.EngineVersion)),
+				DBParameterGroupName:   aws.String(groupName),
+				DBParameterGroupFamily: aws.String(family),
 				Description:            aws.String("Created by ridecell-operator"),
 				Tags: []*rds.Tag{
 					&rds.Tag{
@@ -118,11 +133,22 @@ func (comp *dbParameterGroupComponent) Reconcile(ctx *components.ComponentContex
 			}
 			parameterGroup = createDBParameterGroupOutput.DBParameterGroup
 		} else {
-			return components.Result{}, errors.Wrapf(aerr, "rds: failed to describe parameter group")
+			return components.Result{}, errors.Wrapf(err, "rds: failed to describe parameter group")
 		}
 	} else {
 		parameterGroup = describeDBParameterGroupsOutput.DBParameterGroups[0]
 	}
+	setGroupName := func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.RDSInstance)
+		// Until the upgrade is applied the instance (and anything copying its group) stays on the old one.
+		if instance.Status.ParameterGroupName == "" || instance.Status.ParameterGroupName == groupName {
+			instance.Status.ParameterGroupName = groupName
+			instance.Status.PendingParameterGroupName = ""
+		} else {
+			instance.Status.PendingParameterGroupName = groupName
+		}
+		return nil
+	}
 
 	// handle tagging
 	listTagsForResourceOutput, err := comp.rdsAPI.ListTagsForResource(&rds.ListTagsForResourceInput{
@@ -163,7 +189,7 @@ func (comp *dbParameterGroupComponent) Reconcile(ctx *components.ComponentContex
 	// Get default parameter group values
 	var defaultDBParams []*rds.Parameter
 	err = comp.rdsAPI.DescribeDBParametersPages(&rds.DescribeDBParametersInput{
-		DBParameterGroupName: aws.String(fmt.Sprintf("default.%s%s", instance.Spec.Engine, instance.Spec.EngineVersion)),
+		DBParameterGroupName: aws.String(fmt.Sprintf("default.%s", family)),
 	}, func(page *rds.DescribeDBParametersOutput, lastPage bool) bool {
 		defaultDBParams = append(defaultDBParams, page.Parameters...)
 		// if items returned < default MaxItems
@@ -176,7 +202,7 @@ func (comp *dbParameterGroupComponent) Reconcile(ctx *components.ComponentContex
 	// Get current parameter group values
 	var dbParams []*rds.Parameter
 	err = comp.rdsAPI.DescribeDBParametersPages(&rds.DescribeDBParametersInput{
-		DBParameterGroupName: aws.String(instance.Name),
+		DBParameterGroupName: aws.String(groupName),
 	}, func(page *rds.DescribeDBParametersOutput, lastPage bool) bool {
 		dbParams = append(dbParams, page.Parameters...)
 		// if items returned < default MaxItems
@@ -223,57 +249,109 @@ func (comp *dbParameterGroupComponent) Reconcile(ctx *components.ComponentContex
 
 	if len(updateParameters) > 0 {
 		_, err = comp.rdsAPI.ModifyDBParameterGroup(&rds.ModifyDBParameterGroupInput{
-			DBParameterGroupName: aws.String(instance.Name),
+			DBParameterGroupName: aws.String(groupName),
 			Parameters:           updateParameters,
 		})
 		if err != nil {
 			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeInvalidDBParameterGroupStateFault {
 				// Not returning error to retain RequeueAfter behavior.
-				return components.Result{RequeueAfter: time.Second * 30}, nil
+				return components.Result{RequeueAfter: time.Second * 30, StatusModifier: setGroupName}, nil
 			}
 			return components.Result{}, errors.Wrap(err, "rds: unable to modify db parameter group")
 		}
-		return components.Result{RequeueAfter: time.Second * 30}, nil
+		return components.Result{RequeueAfter: time.Second * 30, StatusModifier: setGroupName}, nil
 	}
 
 	if len(resetParameters) > 0 {
 		_, err := comp.rdsAPI.ResetDBParameterGroup(&rds.ResetDBParameterGroupInput{
-			DBParameterGroupName: aws.String(instance.Name),
+			DBParameterGroupName: aws.String(groupName),
 			Parameters:           resetParameters,
 			ResetAllParameters:   aws.Bool(false),
 		})
 		if err != nil {
 			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeInvalidDBParameterGroupStateFault {
 				// Not returning error to retain RequeueAfter behavior.
-				return components.Result{RequeueAfter: time.Second * 30}, nil
+				return components.Result{RequeueAfter: time.Second * 30, StatusModifier: setGroupName}, nil
 			}
 			return components.Result{}, errors.Wrap(err, "rds: failed to reset db parameter group")
 		}
-		return components.Result{RequeueAfter: time.Second * 30}, nil
+		return components.Result{RequeueAfter: time.Second * 30, StatusModifier: setGroupName}, nil
 	}
 
-	return components.Result{}, nil
+	return components.Result{StatusModifier: setGroupName}, nil
 }
 
 func (comp *dbParameterGroupComponent) deleteDependencies(ctx *components.ComponentContext) (components.Result, error) {
 	instance := ctx.Top.(*dbv1beta1.RDSInstance)
-	describeDBParameterGroupsOutput, err := comp.rdsAPI.DescribeDBParameterGroups(&rds.DescribeDBParameterGroupsInput{
-		DBParameterGroupName: aws.String(instance.Name),
+
+	// Every engine upgrade to a new family leaves a "<name>-<family>" group behind, so look for all of them.
+	var parameterGroups []*rds.DBParameterGroup
+	err := comp.rdsAPI.DescribeDBParameterGroupsPages(&rds.DescribeDBParameterGroupsInput{}, func(page *rds.DescribeDBParameterGroupsOutput, lastPage bool) bool {
+		for _, parameterGroup := range page.DBParameterGroups {
+			groupName := aws.StringValue(parameterGroup.DBParameterGroupName)
+			if groupName == instance.Name || strings.HasPrefix(groupName, fmt.Sprintf("%s-%s", instance.Name, instance.Spec.Engine)) {
+				parameterGroups = append(parameterGroups, parameterGroup)
+			}
+		}
+		return true
 	})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBParameterGroupNotFoundFault {
-			return components.Result{}, nil
+		return components.Result{}, errors.Wrap(err, "rds: failed to describe parameter groups for finalizer")
+	}
+
+	for _, parameterGroup := range parameterGroups {
+		if aws.StringValue(parameterGroup.DBParameterGroupName) != instance.Name {
+			// The name alone could belong to another instance, only delete upgrade groups we tagged for this one.
+			owned, err := comp.isOwnedParameterGroup(instance, parameterGroup)
+			if err != nil {
+				return components.Result{}, err
+			}
+			if !owned {
+				continue
+			}
+		}
+
+		_, err = comp.rdsAPI.DeleteDBParameterGroup(&rds.DeleteDBParameterGroupInput{
+			DBParameterGroupName: parameterGroup.DBParameterGroupName,
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBParameterGroupNotFoundFault {
+				continue
+			}
+			return components.Result{}, errors.Wrap(err, "rds: failed to delete parameter group for finalizer")
 		}
-		return components.Result{}, errors.Wrap(err, "rds: failed to describe parameter group for finalizer")
 	}
 
-	_, err = comp.rdsAPI.DeleteDBParameterGroup(&rds.DeleteDBParameterGroupInput{
-		DBParameterGroupName: describeDBParameterGroupsOutput.DBParameterGroups[0].DBParameterGroupName,
+	// Our parameter groups are in the process of being deleted
+	return components.Result{}, nil
+}
+
+func (comp *dbParameterGroupComponent) isOwnedParameterGroup(instance *dbv1beta1.RDSInstance, parameterGroup *rds.DBParameterGroup) (bool, error) {
+	listTagsForResourceOutput, err := comp.rdsAPI.ListTagsForResource(&rds.ListTagsForResourceInput{
+		ResourceName: parameterGroup.DBParameterGroupArn,
 	})
 	if err != nil {
-		return components.Result{}, errors.Wrap(err, "rds: failed to delete parameter group for finalizer")
+		return false, errors.Wrap(err, "rds: failed to list parameter group tags for finalizer")
+	}
+	var foundOperatorTag bool
+	var foundTenantTag bool
+	for _, tag := range listTagsForResourceOutput.TagList {
+		if aws.StringValue(tag.Key) == "Ridecell-Operator" && aws.StringValue(tag.Value) == "true" {
+			foundOperatorTag = true
+		}
+		if aws.StringValue(tag.Key) == "tenant" && aws.StringValue(tag.Value) == instance.Name {
+			foundTenantTag = true
+		}
 	}
+	return foundOperatorTag && foundTenantTag, nil
+}
 
-	// Our parameter group is in the process of being deleted
-	return components.Result{}, nil
+// The parameter group family for an engine version, e.g. postgres11 for 11.8 and postgres9.6 for 9.6.18.
+func parameterGroupFamily(engine string, engineVersion string) string {
+	parts := strings.Split(engineVersion, ".")
+	major, err := strconv.Atoi(parts[0])
+	if len(parts) == 1 || (engine == "postgres" && err == nil && major >= 10) {
+		return engine + parts[0]
+	}
+	return engine + parts[0] + "." + parts[1]
 }
@@ -46,6 +46,11 @@ type mockRDSPGClient struct {
 	deletedParameterGroup   bool
 	hasTags                 bool
 	addedTags               bool
+	family                  string
+	createdParameterGroup   string
+	upgradeParameterGroups  []string
+	foreignParameterGroups  []string
+	deletedParameterGroups  []string
 
 	parameters        []*rds.Parameter
 	defaultParameters []*rds.Parameter
@@ -135,6 +140,28 @@ var _ = Describe("rds parameter group Component", func() {
 		Expect(parametersEquals(mockRDS.parameters, mockRDS.defaultParameters)).To(BeTrue())
 	})
 
+	It("records the parameter group name", func() {
+		mockRDS.parameterGroupExists = true
+		mockRDS.parameterGroupHasParams = true
+		mockRDS.family = "postgres11"
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdParameterGroup).To(BeEmpty())
+		Expect(instance.Status.ParameterGroupName).To(Equal("test"))
+	})
+
+	It("creates a new parameter group for a major version upgrade", func() {
+		mockRDS.parameterGroupExists = true
+		mockRDS.parameterGroupHasParams = true
+		mockRDS.family = "postgres11"
+		instance.Spec.EngineVersion = "12.4"
+		instance.Status.ParameterGroupName = "test"
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdParameterGroup).To(Equal("test-postgres12"))
+		// The instance keeps using the old group until the upgrade is applied.
+		Expect(instance.Status.ParameterGroupName).To(Equal("test"))
+		Expect(instance.Status.PendingParameterGroupName).To(Equal("test-postgres12"))
+	})
+
 	It("tests adding the finalizer", func() {
 		instance.ObjectMeta.Finalizers = []string{}
 		Expect(comp).To(ReconcileContext(ctx))
@@ -158,10 +185,48 @@ var _ = Describe("rds parameter group Component", func() {
 		Expect(mockRDS.deletedParameterGroup).To(BeTrue())
 		Expect(fetchDBInstance.ObjectMeta.Finalizers).To(HaveLen(0))
 	})
+
+	It("deletes the groups left over from engine upgrades", func() {
+		os.Setenv("ENABLE_FINALIZERS", "true")
+		mockRDS.parameterGroupExists = true
+		mockRDS.hasTags = true
+		mockRDS.upgradeParameterGroups = []string{"test-postgres12", "test-postgres13"}
+		mockRDS.foreignParameterGroups = []string{"test-postgres-other", "test-other"}
+		instance.Status.ParameterGroupName = "test-postgres13"
+		currentTime := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&currentTime)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deletedParameterGroups).To(ConsistOf("test", "test-postgres12", "test-postgres13"))
+	})
 })
 
 // Mock aws functions below
+func (m *mockRDSPGClient) DescribeDBParameterGroupsPages(input *rds.DescribeDBParameterGroupsInput, fn func(*rds.DescribeDBParameterGroupsOutput, bool) bool) error {
+	var groupNames []string
+	if m.parameterGroupExists {
+		groupNames = append(groupNames, instance.Name)
+	}
+	groupNames = append(groupNames, m.upgradeParameterGroups...)
+	groupNames = append(groupNames, m.foreignParameterGroups...)
+	groupNames = append(groupNames, "default.postgres11")
+
+	var parameterGroups []*rds.DBParameterGroup
+	for _, groupName := range groupNames {
+		parameterGroups = append(parameterGroups, &rds.DBParameterGroup{
+			DBParameterGroupName: aws.String(groupName),
+			DBParameterGroupArn:  aws.String("arn:" + groupName),
+		})
+	}
+	fn(&rds.DescribeDBParameterGroupsOutput{DBParameterGroups: parameterGroups}, true)
+	return nil
+}
+
 func (m *mockRDSPGClient) DescribeDBParameterGroups(input *rds.DescribeDBParameterGroupsInput) (*rds.DescribeDBParameterGroupsOutput, error) {
+	if aws.StringValue(input.DBParameterGroupName) == "test-postgres12" {
+		// The group for the upgraded family never exists up front.
+		return nil, awserr.New(rds.ErrCodeDBParameterGroupNotFoundFault, "", nil)
+	}
 	if aws.StringValue(input.DBParameterGroupName) != instance.Name {
 		return nil, errors.New("mock_rds: input parameter group name did not match expected value")
 	}
@@ -169,8 +234,9 @@ func (m *mockRDSPGClient) DescribeDBParameterGroups(input *rds.DescribeDBParamet
 	if m.parameterGroupExists {
 		parameterGroups = []*rds.DBParameterGroup{
 			&rds.DBParameterGroup{
-				DBParameterGroupName: input.DBParameterGroupName,
-				DBParameterGroupArn:  aws.String("arn"),
+				DBParameterGroupName:   input.DBParameterGroupName,
+				DBParameterGroupArn:    aws.String("arn"),
+				DBParameterGroupFamily: aws.String(m.family),
 			},
 		}
 		return &rds.DescribeDBParameterGroupsOutput{DBParameterGroups: parameterGroups}, nil
@@ -179,6 +245,14 @@ func (m *mockRDSPGClient) DescribeDBParameterGroups(input *rds.DescribeDBParamet
 }
 
 func (m *mockRDSPGClient) CreateDBParameterGroup(input *rds.CreateDBParameterGroupInput) (*rds.CreateDBParameterGroupOutput, error) {
+	if aws.StringValue(input.DBParameterGroupName) == "test-postgres12" && aws.StringValue(input.DBParameterGroupFamily) == "postgres12" {
+		m.createdParameterGroup = "test-postgres12"
+		return &rds.CreateDBParameterGroupOutput{
+			DBParameterGroup: &rds.DBParameterGroup{
+				DBParameterGroupArn: aws.String("arn"),
+			},
+		}, nil
+	}
 	if aws.StringValue(input.DBParameterGroupName) != instance.Name {
 		return nil, errors.New("mock_rds: input parameter group name did not match expected value")
 	}
@@ -193,11 +267,11 @@ func (m *mockRDSPGClient) CreateDBParameterGroup(input *rds.CreateDBParameterGro
 }
 
 func (m *mockRDSPGClient) DescribeDBParametersPages(input *rds.DescribeDBParametersInput, fn func(*rds.DescribeDBParametersOutput, bool) bool) error {
-	if aws.StringValue(input.DBParameterGroupName) == "default.postgres11" {
+	if aws.StringValue(input.DBParameterGroupName) == "default.postgres11" || aws.StringValue(input.DBParameterGroupName) == "default.postgres12" {
 		fn(&rds.DescribeDBParametersOutput{Parameters: m.defaultParameters}, false)
 		return nil
 	}
-	if aws.StringValue(input.DBParameterGroupName) == "test" {
+	if aws.StringValue(input.DBParameterGroupName) == "test" || aws.StringValue(input.DBParameterGroupName) == "test-postgres12" {
 		if m.parameterGroupHasParams {
 			for k, v := range instance.Spec.Parameters {
 				for _, parameter := range m.parameters {
@@ -263,14 +337,32 @@ func (m *mockRDSPGClient) ResetDBParameterGroup(input *rds.ResetDBParameterGroup
 }
 
 func (m *mockRDSPGClient) DeleteDBParameterGroup(input *rds.DeleteDBParameterGroupInput) (*rds.DeleteDBParameterGroupOutput, error) {
-	if aws.StringValue(input.DBParameterGroupName) != instance.Name {
-		return nil, errors.New("mock_rds: input parameter group name did not match expected value")
+	for _, groupName := range m.foreignParameterGroups {
+		if aws.StringValue(input.DBParameterGroupName) == groupName {
+			return nil, errors.New("mock_rds: deleted a parameter group belonging to another instance")
+		}
 	}
 	m.deletedParameterGroup = true
+	m.deletedParameterGroups = append(m.deletedParameterGroups, aws.StringValue(input.DBParameterGroupName))
 	return &rds.DeleteDBParameterGroupOutput{}, nil
 }
 
 func (m *mockRDSPGClient) ListTagsForResource(input *rds.ListTagsForResourceInput) (*rds.ListTagsForResourceOutput, error) {
+	for _, groupName := range m.foreignParameterGroups {
+		if aws.StringValue(input.ResourceName) == "arn:"+groupName {
+			tags := []*rds.Tag{
+				&rds.Tag{
+					Key:   aws.String("Ridecell-Operator"),
+					Value: aws.String("true"),
+				},
+				&rds.Tag{
+					Key:   aws.String("tenant"),
+					Value: aws.String("test-postgres-other"),
+				},
+			}
+			return &rds.ListTagsForResourceOutput{TagList: tags}, nil
+		}
+	}
 	if m.hasTags {
 		tags := []*rds.Tag{
 			&rds.Tag{
@@ -19,6 +19,7 @@ package components
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -46,7 +48,7 @@ type rdsInstanceComponent struct {
 }
 
 func NewRDSInstance() *rdsInstanceComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	rdsService := rds.New(sess)
 	return &rdsInstanceComponent{rdsAPI: rdsService}
 }
@@ -76,6 +78,10 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 		}
 	} else {
 		if helpers.ContainsFinalizer(RDSInstanceDatabaseFinalizer, instance) {
+			// Read replicas have to be deleted before the primary.
+			if helpers.ContainsFinalizer(RDSInstanceReplicasFinalizer, instance) {
+				return components.Result{RequeueAfter: time.Minute * 1}, nil
+			}
 			if flag := instance.Annotations["ridecell.io/skip-finalizer"]; flag != "true" && os.Getenv("ENABLE_FINALIZERS") == "true" {
 				describeDBInstancesOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
 					DBInstanceIdentifier: aws.String(instance.Spec.InstanceID),
@@ -138,20 +144,30 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 	}
 
 	if databaseNotExist {
+		parameterGroupName := instance.Status.ParameterGroupName
+		if parameterGroupName == "" {
+			parameterGroupName = instance.Name
+		}
+		var iops *int64
+		if instance.Spec.IOPS != 0 {
+			iops = aws.Int64(instance.Spec.IOPS)
+		}
 		createDBInstanceOutput, err := comp.rdsAPI.CreateDBInstance(&rds.CreateDBInstanceInput{
 			MasterUsername:             aws.String(databaseUsername),
 			DBInstanceIdentifier:       aws.String(instance.Spec.InstanceID),
 			MasterUserPassword:         aws.String(string(password)),
-			StorageType:                aws.String("gp2"),
+			StorageType:                aws.String(instance.Spec.StorageType),
+			Iops:                       iops,
 			AllocatedStorage:           aws.Int64(instance.Spec.AllocatedStorage),
 			DBInstanceClass:            aws.String(instance.Spec.InstanceClass),
-			BackupRetentionPeriod:      aws.Int64(7),
+			BackupRetentionPeriod:      instance.Spec.BackupRetentionPeriod,
 			PreferredMaintenanceWindow: aws.String(instance.Spec.MaintenanceWindow),
 			Engine:                     aws.String(instance.Spec.Engine),
 			EngineVersion:              aws.String(instance.Spec.EngineVersion),
 			MultiAZ:                    instance.Spec.MultiAZ,
-			PubliclyAccessible:         aws.Bool(true),
-			DBParameterGroupName:       aws.String(instance.Name),
+			PubliclyAccessible:         instance.Spec.PubliclyAccessible,
+			DeletionProtection:         aws.Bool(instance.Spec.DeletionProtection),
+			DBParameterGroupName:       aws.String(parameterGroupName),
 			VpcSecurityGroupIds:        []*string{aws.String(instance.Status.SecurityGroupID)},
 			DBSubnetGroupName:          aws.String(instance.Spec.SubnetGroupName),
 			StorageEncrypted:           aws.Bool(true),
@@ -215,26 +231,142 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 		}
 	}
 
+	// Changes which are safe to apply straight away.
 	var needsUpdate bool
-	// For now we're only making changes that are safe to apply immediately
-	// This does exclude instance size for now
 	databaseModifyInput := &rds.ModifyDBInstanceInput{
 		DBInstanceIdentifier: database.DBInstanceIdentifier,
 		ApplyImmediately:     aws.Bool(true),
 	}
+	// Changes which cause downtime or a failover, these wait for the maintenance window.
+	// They are still sent with ApplyImmediately because the operator tracks the window
+	// itself, otherwise RDS would flush them alongside any later immediate change.
+	var needsDisruptiveUpdate bool
+	disruptiveModifyInput := &rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier: database.DBInstanceIdentifier,
+		ApplyImmediately:     aws.Bool(true),
+	}
+	held := map[string]string{}
+
+	// Compare against anything RDS is already in the middle of applying.
+	pendingValues := database.PendingModifiedValues
+	if pendingValues == nil {
+		pendingValues = &rds.PendingModifiedValues{}
+	}
+
+	backupRetentionPeriod := int64(7)
+	if instance.Spec.BackupRetentionPeriod != nil {
+		backupRetentionPeriod = *instance.Spec.BackupRetentionPeriod
+	}
+	currentBackupRetentionPeriod := aws.Int64Value(database.BackupRetentionPeriod)
+	if pendingValues.BackupRetentionPeriod != nil {
+		currentBackupRetentionPeriod = aws.Int64Value(pendingValues.BackupRetentionPeriod)
+	}
+	if currentBackupRetentionPeriod != backupRetentionPeriod {
+		needsUpdate = true
+		databaseModifyInput.BackupRetentionPeriod = aws.Int64(backupRetentionPeriod)
+	}
 
-	// If DB does not have a backup retention period of 7 days, set it to 7 days now
-	if aws.Int64Value(database.BackupRetentionPeriod) != 7 {
+	dbStatus := aws.StringValue(database.DBInstanceStatus)
+	// RDS rejects any storage change until a running storage optimization finishes.
+	storageOptimizing := dbStatus == "storage-optimization"
+	var storageHeld bool
+
+	// Storage can only ever grow. AWS rounds increases of less than 10% up to 10%, so this
+	// may end up larger than the spec, which is fine as we never try to shrink it.
+	currentAllocatedStorage := aws.Int64Value(database.AllocatedStorage)
+	if pendingValues.AllocatedStorage != nil {
+		currentAllocatedStorage = aws.Int64Value(pendingValues.AllocatedStorage)
+	}
+	if instance.Spec.AllocatedStorage > currentAllocatedStorage {
+		if storageOptimizing {
+			storageHeld = true
+		} else {
+			needsUpdate = true
+			databaseModifyInput.AllocatedStorage = aws.Int64(instance.Spec.AllocatedStorage)
+		}
+	}
+
+	if instance.Spec.PubliclyAccessible != nil && *instance.Spec.PubliclyAccessible != aws.BoolValue(database.PubliclyAccessible) {
 		needsUpdate = true
-		databaseModifyInput.BackupRetentionPeriod = aws.Int64(7)
+		databaseModifyInput.PubliclyAccessible = instance.Spec.PubliclyAccessible
 	}
 
-	// TODO: Things could get weird if allocated storage is increased by less than 10% as aws will automatically round up to the nearest 10% increase
-	// This is pretty unlikely to happen even at larger numbers.
-	// if aws.Int64Value(database.AllocatedStorage) != instance.Spec.AllocatedStorage {
-	// 	needsUpdate = true
-	// 	databaseModifyInput.AllocatedStorage = aws.Int64(instance.Spec.AllocatedStorage)
-	// }
+	if instance.Spec.DeletionProtection != aws.BoolValue(database.DeletionProtection) {
+		needsUpdate = true
+		databaseModifyInput.DeletionProtection = aws.Bool(instance.Spec.DeletionProtection)
+	}
+
+	// RDS lowercases the window.
+	if instance.Spec.MaintenanceWindow != "" && !strings.EqualFold(instance.Spec.MaintenanceWindow, aws.StringValue(database.PreferredMaintenanceWindow)) {
+		needsUpdate = true
+		databaseModifyInput.PreferredMaintenanceWindow = aws.String(instance.Spec.MaintenanceWindow)
+	}
+
+	currentInstanceClass := aws.StringValue(database.DBInstanceClass)
+	if pendingValues.DBInstanceClass != nil {
+		currentInstanceClass = aws.StringValue(pendingValues.DBInstanceClass)
+	}
+	if instance.Spec.InstanceClass != "" && instance.Spec.InstanceClass != currentInstanceClass {
+		needsDisruptiveUpdate = true
+		disruptiveModifyInput.DBInstanceClass = aws.String(instance.Spec.InstanceClass)
+		held["instanceClass"] = instance.Spec.InstanceClass
+	}
+
+	currentMultiAZ := aws.BoolValue(database.MultiAZ)
+	if pendingValues.MultiAZ != nil {
+		currentMultiAZ = aws.BoolValue(pendingValues.MultiAZ)
+	}
+	if instance.Spec.MultiAZ != nil && *instance.Spec.MultiAZ != currentMultiAZ {
+		needsDisruptiveUpdate = true
+		disruptiveModifyInput.MultiAZ = instance.Spec.MultiAZ
+		held["multiAZ"] = strconv.FormatBool(*instance.Spec.MultiAZ)
+	}
+
+	currentStorageType := aws.StringValue(database.StorageType)
+	if pendingValues.StorageType != nil {
+		currentStorageType = aws.StringValue(pendingValues.StorageType)
+	}
+	if instance.Spec.StorageType != "" && instance.Spec.StorageType != currentStorageType {
+		if storageOptimizing {
+			storageHeld = true
+		} else {
+			needsDisruptiveUpdate = true
+			disruptiveModifyInput.StorageType = aws.String(instance.Spec.StorageType)
+			held["storageType"] = instance.Spec.StorageType
+		}
+	}
+
+	currentIOPS := aws.Int64Value(database.Iops)
+	if pendingValues.Iops != nil {
+		currentIOPS = aws.Int64Value(pendingValues.Iops)
+	}
+	if instance.Spec.IOPS != 0 && instance.Spec.IOPS != currentIOPS {
+		if storageOptimizing {
+			storageHeld = true
+		} else {
+			needsDisruptiveUpdate = true
+			disruptiveModifyInput.Iops = aws.Int64(instance.Spec.IOPS)
+			held["iops"] = strconv.FormatInt(instance.Spec.IOPS, 10)
+		}
+	}
+
+	// Engine versions only go up, RDS has no way to downgrade.
+	currentEngineVersion := aws.StringValue(database.EngineVersion)
+	if pendingValues.EngineVersion != nil {
+		currentEngineVersion = aws.StringValue(pendingValues.EngineVersion)
+	}
+	if instance.Spec.EngineVersion != "" && currentEngineVersion != "" && compareEngineVersions(instance.Spec.EngineVersion, currentEngineVersion) > 0 {
+		needsDisruptiveUpdate = true
+		disruptiveModifyInput.EngineVersion = aws.String(instance.Spec.EngineVersion)
+		held["engineVersion"] = instance.Spec.EngineVersion
+		if parameterGroupFamily(instance.Spec.Engine, instance.Spec.EngineVersion) != parameterGroupFamily(instance.Spec.Engine, currentEngineVersion) {
+			// The parameter group component has already made a group for the new family.
+			disruptiveModifyInput.AllowMajorVersionUpgrade = aws.Bool(true)
+			if instance.Status.PendingParameterGroupName != "" {
+				disruptiveModifyInput.DBParameterGroupName = aws.String(instance.Status.PendingParameterGroupName)
+			}
+		}
+	}
 
 	// attempt a database query to test see if our password is correct.
 	// only attempt this when database is in ready state.
@@ -258,9 +390,10 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 		}
 	}
 
-	dbStatus := aws.StringValue(database.DBInstanceStatus)
+	// storage-optimization can run for hours after a storage change but the instance is fully usable.
+	available := dbStatus == "available" || dbStatus == "pending-reboot" || dbStatus == "storage-optimization"
 	// Only try to update the database if the status is available, otherwise a change may already be in progress.
-	if (dbStatus == "available" || dbStatus == "pending-reboot") && needsUpdate {
+	if available && needsUpdate {
 		err = comp.modifyRDSInstance(databaseModifyInput)
 		if err != nil {
 			return components.Result{}, errors.Wrap(err, "rds: failed to modify db instance")
@@ -268,15 +401,48 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 		return components.Result{RequeueAfter: time.Second * 30}, nil
 	}
 
+	var waitForWindow time.Duration
+	if available && needsDisruptiveUpdate {
+		// Without a window in the spec use the one RDS picked, if there is neither don't hold anything.
+		maintenanceWindow := instance.Spec.MaintenanceWindow
+		if maintenanceWindow == "" {
+			maintenanceWindow = aws.StringValue(database.PreferredMaintenanceWindow)
+		}
+		inWindow := instance.Spec.ApplyImmediately || maintenanceWindow == ""
+		if !inWindow {
+			inWindow, waitForWindow, err = checkMaintenanceWindow(maintenanceWindow, time.Now())
+			if err != nil {
+				return components.Result{}, errors.Wrap(err, "rds: invalid maintenance window")
+			}
+		}
+		if inWindow {
+			err = comp.modifyRDSInstance(disruptiveModifyInput)
+			if err != nil {
+				return components.Result{}, errors.Wrap(err, "rds: failed to modify db instance")
+			}
+			return components.Result{StatusModifier: func(obj runtime.Object) error {
+				instance := obj.(*dbv1beta1.RDSInstance)
+				instance.Status.Status = dbv1beta1.StatusModifying
+				instance.Status.Message = "RDS instance is being modified"
+				instance.Status.PendingModifications = pendingModifications(pendingValues, held)
+				return nil
+			}, RequeueAfter: time.Second * 30}, nil
+		}
+	} else {
+		// Nothing is being held back, only report what RDS itself has pending.
+		held = map[string]string{}
+	}
+
 	if dbStatus == "failed" || dbStatus == "incompatible-parameters" {
 		return components.Result{}, errors.New("rds: rds instance is in a failure state")
 	}
 
-	if dbStatus == "modifying" || dbStatus == "resetting-master-credentials" || dbStatus == "backing-up" {
+	if dbStatus == "modifying" || dbStatus == "resetting-master-credentials" || dbStatus == "backing-up" || dbStatus == "upgrading" || dbStatus == "rebooting" {
 		return components.Result{StatusModifier: func(obj runtime.Object) error {
 			instance := obj.(*dbv1beta1.RDSInstance)
 			instance.Status.Status = dbv1beta1.StatusModifying
-			instance.Status.Message = "password is being updated"
+			instance.Status.Message = fmt.Sprintf("RDS instance status: %s", dbStatus)
+			instance.Status.PendingModifications = pendingModifications(pendingValues, held)
 			return nil
 		}, RequeueAfter: time.Second * 30}, nil
 	}
@@ -291,18 +457,36 @@ func (comp *rdsInstanceComponent) Reconcile(ctx *components.ComponentContext) (c
 		}, RequeueAfter: time.Second * 30}, nil
 	}
 
-	if dbStatus == "available" || dbStatus == "pending-reboot" {
+	if available {
+		requeueAfter := waitForWindow
+		if storageHeld && (requeueAfter == 0 || requeueAfter > time.Minute*5) {
+			requeueAfter = time.Minute * 5
+		}
 		return components.Result{StatusModifier: func(obj runtime.Object) error {
 			instance := obj.(*dbv1beta1.RDSInstance)
 			instance.Status.Status = dbv1beta1.StatusReady
 			instance.Status.Message = "RDS instance exists and is available"
+			if len(held) > 0 {
+				instance.Status.Message = fmt.Sprintf("RDS instance is available, %d change(s) waiting for the maintenance window", len(held))
+			}
+			if storageHeld {
+				instance.Status.Message = "RDS instance is available, storage changes waiting for storage optimization to finish"
+			}
+			// Only move over to the new family's group once RDS has actually switched to it.
+			if len(database.DBParameterGroups) > 0 {
+				instance.Status.ParameterGroupName = aws.StringValue(database.DBParameterGroups[0].DBParameterGroupName)
+				if instance.Status.PendingParameterGroupName == instance.Status.ParameterGroupName {
+					instance.Status.PendingParameterGroupName = ""
+				}
+			}
+			instance.Status.PendingModifications = pendingModifications(pendingValues, held)
 			instance.Status.InstanceID = aws.StringValue(database.DBInstanceIdentifier)
 			instance.Status.Connection.Host = aws.StringValue(database.Endpoint.Address)
 			instance.Status.Connection.Port = 5432
 			instance.Status.Connection.Username = aws.StringValue(database.MasterUsername)
 			instance.Status.Connection.Database = "postgres"
 			return nil
-		}}, nil
+		}, RequeueAfter: requeueAfter}, nil
 	}
 
 	// catchall for i have no idea why this happened, retry every minute just in case it's weird
@@ -347,3 +531,113 @@ func (comp *rdsInstanceComponent) deleteDependencies(ctx *components.ComponentCo
 	}
 	return components.Result{}, nil
 }
+
+// compareEngineVersions compares dotted version strings numerically, returning -1, 0 or 1.
+func compareEngineVersions(a string, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aNum, bNum int
+		if i < len(aParts) {
+			aNum, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bNum, _ = strconv.Atoi(bParts[i])
+		}
+		if aNum < bNum {
+			return -1
+		}
+		if aNum > bNum {
+			return 1
+		}
+	}
+	return 0
+}
+
+var weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// parseWindowTime converts "Mon:03:00" to minutes since the start of the week.
+func parseWindowTime(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, errors.Errorf("unable to parse %#v", value)
+	}
+	day, ok := weekdays[strings.ToLower(parts[0])]
+	if !ok {
+		return 0, errors.Errorf("unknown day %#v", parts[0])
+	}
+	hour, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse %#v", value)
+	}
+	minute, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse %#v", value)
+	}
+	return day*24*60 + hour*60 + minute, nil
+}
+
+// checkMaintenanceWindow returns if now is inside a "ddd:hh:mm-ddd:hh:mm" UTC window and,
+// if not, how long until it opens.
+func checkMaintenanceWindow(window string, now time.Time) (bool, time.Duration, error) {
+	bounds := strings.Split(window, "-")
+	if len(bounds) != 2 {
+		return false, 0, errors.Errorf("unable to parse %#v", window)
+	}
+	start, err := parseWindowTime(bounds[0])
+	if err != nil {
+		return false, 0, err
+	}
+	end, err := parseWindowTime(bounds[1])
+	if err != nil {
+		return false, 0, err
+	}
+
+	const week = 7 * 24 * 60
+	now = now.UTC()
+	current := int(now.Weekday())*24*60 + now.Hour()*60 + now.Minute()
+	// Shift everything so the window starts at zero, this handles windows spanning Saturday night.
+	length := (end - start + week) % week
+	offset := (current - start + week) % week
+	if offset < length {
+		return true, 0, nil
+	}
+	wait := time.Duration(week-offset)*time.Minute - time.Duration(now.Second())*time.Second
+	return false, wait, nil
+}
+
+// pendingModifications merges changes RDS is applying with the ones still held for the window.
+func pendingModifications(pendingValues *rds.PendingModifiedValues, held map[string]string) map[string]string {
+	pending := map[string]string{}
+	for key, value := range held {
+		pending[key] = value
+	}
+	if pendingValues.DBInstanceClass != nil {
+		pending["instanceClass"] = aws.StringValue(pendingValues.DBInstanceClass)
+	}
+	if pendingValues.EngineVersion != nil {
+		pending["engineVersion"] = aws.StringValue(pendingValues.EngineVersion)
+	}
+	if pendingValues.AllocatedStorage != nil {
+		pending["allocatedStorage"] = strconv.FormatInt(aws.Int64Value(pendingValues.AllocatedStorage), 10)
+	}
+	if pendingValues.MultiAZ != nil {
+		pending["multiAZ"] = strconv.FormatBool(aws.BoolValue(pendingValues.MultiAZ))
+	}
+	if pendingValues.StorageType != nil {
+		pending["storageType"] = aws.StringValue(pendingValues.StorageType)
+	}
+	if pendingValues.Iops != nil {
+		pending["iops"] = strconv.FormatInt(aws.Int64Value(pendingValues.Iops), 10)
+	}
+	if pendingValues.BackupRetentionPeriod != nil {
+		pending["backupRetentionPeriod"] = strconv.FormatInt(aws.Int64Value(pendingValues.BackupRetentionPeriod), 10)
+	}
+	if pendingValues.MasterUserPassword != nil {
+		pending["masterUserPassword"] = "****"
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	return pending
+}
@@ -21,6 +21,8 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 	. "github.com/onsi/ginkgo"
@@ -54,6 +56,11 @@ type mockRDSDBClient struct {
 	addedTags         bool
 	has7dayBackup     bool
 	dbStatus          string
+	allocatedStorage  int64
+	instanceClass     string
+	engineVersion     string
+	parameterGroup    string
+	modifyInput       *rds.ModifyDBInstanceInput
 }
 
 var passwordSecret *corev1.Secret
@@ -162,6 +169,138 @@ var _ = Describe("rds aws Component", func() {
 		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
 	})
 
+	Context("with an available database", func() {
+		BeforeEach(func() {
+			instance.Status.Status = dbv1beta1.StatusReady
+			mockRDS.dbInstanceExists = true
+			mockRDS.hasTags = true
+			mockRDS.has7dayBackup = true
+			mockRDS.dbStatus = "available"
+			mockRDS.allocatedStorage = 100
+			mockRDS.instanceClass = "db.t3.micro"
+			dbMock.ExpectQuery("SELECT 1;").WillReturnRows(sqlmock.NewRows([]string{"test"}).AddRow(1)).RowsWillBeClosed()
+		})
+
+		It("grows the allocated storage", func() {
+			instance.Spec.AllocatedStorage = 200
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeTrue())
+			Expect(aws.Int64Value(mockRDS.modifyInput.AllocatedStorage)).To(Equal(int64(200)))
+			Expect(aws.BoolValue(mockRDS.modifyInput.ApplyImmediately)).To(BeTrue())
+		})
+
+		It("does not shrink the allocated storage", func() {
+			instance.Spec.AllocatedStorage = 50
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeFalse())
+		})
+
+		It("holds an instance class change until the maintenance window", func() {
+			now := time.Now().UTC()
+			instance.Spec.InstanceClass = "db.m5.large"
+			instance.Spec.MaintenanceWindow = fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("Mon:15:04"), now.Add(3*time.Hour).Format("Mon:15:04"))
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(mockRDS.modifiedDB).To(BeFalse())
+			Expect(res.RequeueAfter).To(BeNumerically("~", 2*time.Hour, time.Minute))
+			Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+			Expect(instance.Status.PendingModifications).To(HaveKeyWithValue("instanceClass", "db.m5.large"))
+		})
+
+		It("changes the instance class inside the maintenance window", func() {
+			now := time.Now().UTC()
+			instance.Spec.InstanceClass = "db.m5.large"
+			instance.Spec.MaintenanceWindow = fmt.Sprintf("%s-%s", now.Add(-time.Hour).Format("Mon:15:04"), now.Add(time.Hour).Format("Mon:15:04"))
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeTrue())
+			Expect(aws.StringValue(mockRDS.modifyInput.DBInstanceClass)).To(Equal("db.m5.large"))
+			Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusModifying))
+		})
+
+		It("changes the instance class outside the window with applyImmediately", func() {
+			now := time.Now().UTC()
+			instance.Spec.InstanceClass = "db.m5.large"
+			instance.Spec.MaintenanceWindow = fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("Mon:15:04"), now.Add(3*time.Hour).Format("Mon:15:04"))
+			instance.Spec.ApplyImmediately = true
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeTrue())
+			Expect(aws.StringValue(mockRDS.modifyInput.DBInstanceClass)).To(Equal("db.m5.large"))
+		})
+
+		It("does not hold changes when there is no maintenance window", func() {
+			instance.Spec.InstanceClass = "db.m5.large"
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeTrue())
+			Expect(aws.StringValue(mockRDS.modifyInput.DBInstanceClass)).To(Equal("db.m5.large"))
+		})
+
+		It("holds storage changes during storage optimization", func() {
+			mockRDS.dbStatus = "storage-optimization"
+			instance.Spec.AllocatedStorage = 200
+			instance.Spec.StorageType = "io1"
+			instance.Spec.IOPS = 3000
+
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(mockRDS.modifiedDB).To(BeFalse())
+			Expect(res.RequeueAfter).To(Equal(5 * time.Minute))
+			Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+			Expect(instance.Status.Message).To(ContainSubstring("storage optimization"))
+		})
+
+		It("upgrades to the pending parameter group", func() {
+			instance.Spec.Engine = "postgres"
+			instance.Spec.EngineVersion = "12.4"
+			instance.Spec.ApplyImmediately = true
+			instance.Status.ParameterGroupName = "test"
+			instance.Status.PendingParameterGroupName = "test-postgres12"
+			mockRDS.engineVersion = "11.8"
+			mockRDS.parameterGroup = "test"
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeTrue())
+			Expect(aws.StringValue(mockRDS.modifyInput.EngineVersion)).To(Equal("12.4"))
+			Expect(aws.StringValue(mockRDS.modifyInput.DBParameterGroupName)).To(Equal("test-postgres12"))
+			Expect(instance.Status.ParameterGroupName).To(Equal("test"))
+		})
+
+		It("keeps the old parameter group while the upgrade is held", func() {
+			now := time.Now().UTC()
+			instance.Spec.Engine = "postgres"
+			instance.Spec.EngineVersion = "12.4"
+			instance.Spec.MaintenanceWindow = fmt.Sprintf("%s-%s", now.Add(2*time.Hour).Format("Mon:15:04"), now.Add(3*time.Hour).Format("Mon:15:04"))
+			instance.Status.ParameterGroupName = "test"
+			instance.Status.PendingParameterGroupName = "test-postgres12"
+			mockRDS.engineVersion = "11.8"
+			mockRDS.parameterGroup = "test"
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeFalse())
+			Expect(instance.Status.ParameterGroupName).To(Equal("test"))
+			Expect(instance.Status.PendingParameterGroupName).To(Equal("test-postgres12"))
+		})
+
+		It("switches to the new parameter group once the upgrade is applied", func() {
+			instance.Spec.Engine = "postgres"
+			instance.Spec.EngineVersion = "12.4"
+			instance.Status.ParameterGroupName = "test"
+			instance.Status.PendingParameterGroupName = "test-postgres12"
+			mockRDS.engineVersion = "12.4"
+			mockRDS.parameterGroup = "test-postgres12"
+
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockRDS.modifiedDB).To(BeFalse())
+			Expect(instance.Status.ParameterGroupName).To(Equal("test-postgres12"))
+			Expect(instance.Status.PendingParameterGroupName).To(BeEmpty())
+		})
+	})
+
 	It("has a database in pending-reboot state", func() {
 		instance.Status.Status = dbv1beta1.StatusReady
 		mockRDS.dbInstanceExists = true
@@ -220,6 +359,18 @@ var _ = Describe("rds aws Component", func() {
 		Expect(mockRDS.deletedDBInstance).To(BeFalse())
 	})
 
+	It("waits for read replicas to be deleted first", func() {
+		os.Setenv("ENABLE_FINALIZERS", "true")
+		instance.ObjectMeta.Finalizers = []string{"rdsinstance.database.finalizer", "rdsinstance.replicas.finalizer"}
+		mockRDS.dbInstanceExists = true
+		currentTime := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&currentTime)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deletedDBInstance).To(BeFalse())
+		Expect(instance.ObjectMeta.Finalizers).To(HaveLen(2))
+	})
+
 	It("test finalizer behavior during deletion", func() {
 		os.Setenv("ENABLE_FINALIZERS", "true")
 		instance.ObjectMeta.Finalizers = []string{"rdsinstance.database.finalizer"}
@@ -249,13 +400,28 @@ func (m *mockRDSDBClient) DescribeDBInstances(input *rds.DescribeDBInstancesInpu
 					Address: aws.String("endpoint.test"),
 					Port:    aws.Int64(5432),
 				},
-				MasterUsername:   aws.String("test-user"),
-				DBInstanceStatus: aws.String(m.dbStatus),
+				MasterUsername:             aws.String("test-user"),
+				DBInstanceStatus:           aws.String(m.dbStatus),
+				PreferredMaintenanceWindow: aws.String(strings.ToLower(instance.Spec.MaintenanceWindow)),
 			},
 		}
 		if m.has7dayBackup {
 			dbInstances[0].BackupRetentionPeriod = aws.Int64(7)
 		}
+		if m.allocatedStorage != 0 {
+			dbInstances[0].AllocatedStorage = aws.Int64(m.allocatedStorage)
+		}
+		if m.instanceClass != "" {
+			dbInstances[0].DBInstanceClass = aws.String(m.instanceClass)
+		}
+		if m.engineVersion != "" {
+			dbInstances[0].EngineVersion = aws.String(m.engineVersion)
+		}
+		if m.parameterGroup != "" {
+			dbInstances[0].DBParameterGroups = []*rds.DBParameterGroupStatus{
+				&rds.DBParameterGroupStatus{DBParameterGroupName: aws.String(m.parameterGroup)},
+			}
+		}
 		return &rds.DescribeDBInstancesOutput{DBInstances: dbInstances}, nil
 	}
 	return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "", nil)
@@ -281,6 +447,7 @@ func (m *mockRDSDBClient) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*r
 		return nil, errors.New("mock_rds: received incorrect password in modify")
 	}
 	m.modifiedDB = true
+	m.modifyInput = input
 	return &rds.ModifyDBInstanceOutput{}, nil
 }
 
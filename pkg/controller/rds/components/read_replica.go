@@ -0,0 +1,284 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+const RDSInstanceReplicasFinalizer = "rdsinstance.replicas.finalizer"
+
+type readReplicaComponent struct {
+	rdsAPI rdsiface.RDSAPI
+}
+
+func NewReadReplicas() *readReplicaComponent {
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
+	rdsService := rds.New(sess)
+	return &readReplicaComponent{rdsAPI: rdsService}
+}
+
+func (comp *readReplicaComponent) InjectRDSAPI(rdsapi rdsiface.RDSAPI) {
+	comp.rdsAPI = rdsapi
+}
+
+func (_ *readReplicaComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *readReplicaComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (comp *readReplicaComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.RDSInstance)
+
+	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !helpers.ContainsFinalizer(RDSInstanceReplicasFinalizer, instance) {
+			instance.ObjectMeta.Finalizers = helpers.AppendFinalizer(RDSInstanceReplicasFinalizer, instance)
+			err := ctx.Update(ctx.Context, instance.DeepCopy())
+			if err != nil {
+				return components.Result{}, errors.Wrap(err, "rds: failed to update instance while adding finalizer")
+			}
+		}
+	} else {
+		if helpers.ContainsFinalizer(RDSInstanceReplicasFinalizer, instance) {
+			if flag := instance.Annotations["ridecell.io/skip-finalizer"]; flag != "true" && os.Getenv("ENABLE_FINALIZERS") == "true" {
+				// Delete every replica, the primary can't be deleted until they are gone.
+				remaining, err := comp.deleteReplicas(instance, map[string]bool{})
+				if err != nil {
+					return components.Result{}, err
+				}
+				if remaining {
+					return components.Result{RequeueAfter: time.Minute * 1}, nil
+				}
+			}
+			// All operations complete, remove finalizer
+			instance.ObjectMeta.Finalizers = helpers.RemoveFinalizer(RDSInstanceReplicasFinalizer, instance)
+			err := ctx.Update(ctx.Context, instance.DeepCopy())
+			if err != nil {
+				return components.Result{}, errors.Wrap(err, "rds: failed to update instance while removing finalizer")
+			}
+		}
+		// If object is being deleted and has no finalizer just exit.
+		return components.Result{}, nil
+	}
+
+	// Replicas are copied from the primary, so it has to be up first.
+	if instance.Status.Status != dbv1beta1.StatusReady {
+		if len(instance.Spec.ReadReplicas) > 0 {
+			return components.Result{RequeueAfter: time.Second * 30}, nil
+		}
+		return components.Result{}, nil
+	}
+
+	wanted := map[string]bool{}
+	var connections []dbv1beta1.PostgresConnection
+	var waiting bool
+	for _, replica := range instance.Spec.ReadReplicas {
+		replicaID := fmt.Sprintf("%s-%s", instance.Spec.InstanceID, replica.Name)
+		wanted[replicaID] = true
+		instanceClass := replica.InstanceClass
+		if instanceClass == "" {
+			instanceClass = instance.Spec.InstanceClass
+		}
+
+		var database *rds.DBInstance
+		describeDBInstancesOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+			DBInstanceIdentifier: aws.String(replicaID),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != rds.ErrCodeDBInstanceNotFoundFault {
+				return components.Result{}, errors.Wrapf(err, "rds: unable to describe read replica %s", replicaID)
+			}
+			database, err = comp.createReplica(instance, replica, replicaID, instanceClass)
+			if err != nil {
+				return components.Result{}, err
+			}
+		} else {
+			database = describeDBInstancesOutput.DBInstances[0]
+		}
+
+		dbStatus := aws.StringValue(database.DBInstanceStatus)
+		if dbStatus == "failed" || dbStatus == "incompatible-parameters" || dbStatus == "incompatible-restore" {
+			return components.Result{}, errors.Errorf("rds: read replica %s is in a failure state: %s", replicaID, dbStatus)
+		}
+		if dbStatus != "available" || database.Endpoint == nil {
+			waiting = true
+			continue
+		}
+
+		// Replicas have no maintenance window of their own to wait for, only a single reader is affected.
+		if instanceClass != "" && instanceClass != aws.StringValue(database.DBInstanceClass) {
+			_, err = comp.rdsAPI.ModifyDBInstance(&rds.ModifyDBInstanceInput{
+				DBInstanceIdentifier: aws.String(replicaID),
+				DBInstanceClass:      aws.String(instanceClass),
+				ApplyImmediately:     aws.Bool(true),
+			})
+			if err != nil {
+				return components.Result{}, errors.Wrapf(err, "rds: failed to modify read replica %s", replicaID)
+			}
+			waiting = true
+			continue
+		}
+
+		// Replicas share the credentials of the primary.
+		connection := instance.Status.Connection
+		connection.Host = aws.StringValue(database.Endpoint.Address)
+		connection.Port = int(aws.Int64Value(database.Endpoint.Port))
+		connections = append(connections, connection)
+	}
+
+	remaining, err := comp.deleteReplicas(instance, wanted)
+	if err != nil {
+		return components.Result{}, err
+	}
+
+	res := components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.RDSInstance)
+		instance.Status.ReplicaConnections = connections
+		return nil
+	}}
+	if waiting || remaining {
+		res.RequeueAfter = time.Second * 30
+	}
+	return res, nil
+}
+
+func (comp *readReplicaComponent) createReplica(instance *dbv1beta1.RDSInstance, replica dbv1beta1.RDSReadReplicaSpec, replicaID string, instanceClass string) (*rds.DBInstance, error) {
+	parameterGroupName := instance.Status.ParameterGroupName
+	if parameterGroupName == "" {
+		parameterGroupName = instance.Name
+	}
+	var availabilityZone *string
+	if replica.AvailabilityZone != "" {
+		availabilityZone = aws.String(replica.AvailabilityZone)
+	}
+	createReplicaOutput, err := comp.rdsAPI.CreateDBInstanceReadReplica(&rds.CreateDBInstanceReadReplicaInput{
+		DBInstanceIdentifier:       aws.String(replicaID),
+		SourceDBInstanceIdentifier: aws.String(instance.Spec.InstanceID),
+		DBInstanceClass:            aws.String(instanceClass),
+		AvailabilityZone:           availabilityZone,
+		DBParameterGroupName:       aws.String(parameterGroupName),
+		VpcSecurityGroupIds:        []*string{aws.String(instance.Status.SecurityGroupID)},
+		PubliclyAccessible:         instance.Spec.PubliclyAccessible,
+		StorageType:                aws.String(instance.Spec.StorageType),
+		Tags: []*rds.Tag{
+			&rds.Tag{
+				Key:   aws.String("Ridecell-Operator"),
+				Value: aws.String("true"),
+			},
+			&rds.Tag{
+				Key:   aws.String("tenant"),
+				Value: aws.String(instance.Name),
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "rds: unable to create read replica %s", replicaID)
+	}
+	return createReplicaOutput.DBInstance, nil
+}
+
+// deleteReplicas deletes any replicas of the primary which were created by the operator but aren't wanted,
+// returning true if any still exist. Replicas made outside the operator, e.g. for DR, are left alone.
+func (comp *readReplicaComponent) deleteReplicas(instance *dbv1beta1.RDSInstance, wanted map[string]bool) (bool, error) {
+	describeDBInstancesOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(instance.Spec.InstanceID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBInstanceNotFoundFault {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "rds: unable to describe db instance")
+	}
+	if len(describeDBInstancesOutput.DBInstances) == 0 {
+		return false, nil
+	}
+
+	var remaining bool
+	for _, replicaID := range describeDBInstancesOutput.DBInstances[0].ReadReplicaDBInstanceIdentifiers {
+		if wanted[aws.StringValue(replicaID)] || !strings.HasPrefix(aws.StringValue(replicaID), instance.Spec.InstanceID+"-") {
+			continue
+		}
+		managed, err := comp.isOperatorReplica(aws.StringValue(replicaID))
+		if err != nil {
+			return false, err
+		}
+		if !managed {
+			continue
+		}
+		remaining = true
+		// Replicas can't have a final snapshot, the data lives on in the primary.
+		_, err = comp.rdsAPI.DeleteDBInstance(&rds.DeleteDBInstanceInput{
+			DBInstanceIdentifier: replicaID,
+			SkipFinalSnapshot:    aws.Bool(true),
+		})
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == rds.ErrCodeDBInstanceNotFoundFault || aerr.Code() == rds.ErrCodeInvalidDBInstanceStateFault) {
+				// Already gone or already being deleted.
+				continue
+			}
+			return false, errors.Wrapf(err, "rds: failed to delete read replica %s", aws.StringValue(replicaID))
+		}
+	}
+	return remaining, nil
+}
+
+// isOperatorReplica checks if a replica carries the tag added by createReplica.
+func (comp *readReplicaComponent) isOperatorReplica(replicaID string) (bool, error) {
+	describeDBInstancesOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(replicaID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBInstanceNotFoundFault {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "rds: unable to describe read replica %s", replicaID)
+	}
+	if len(describeDBInstancesOutput.DBInstances) == 0 {
+		return false, nil
+	}
+	listTagsForResourceOutput, err := comp.rdsAPI.ListTagsForResource(&rds.ListTagsForResourceInput{
+		ResourceName: describeDBInstancesOutput.DBInstances[0].DBInstanceArn,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "rds: failed to list tags for read replica %s", replicaID)
+	}
+	for _, tag := range listTagsForResourceOutput.TagList {
+		if aws.StringValue(tag.Key) == "Ridecell-Operator" && aws.StringValue(tag.Value) == "true" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	rdscomponents "github.com/Ridecell/ridecell-operator/pkg/controller/rds/components"
+)
+
+type mockRDSReplicaClient struct {
+	rdsiface.RDSAPI
+
+	replicas      map[string]*rds.DBInstance
+	createdInputs []*rds.CreateDBInstanceReadReplicaInput
+	modifyInput   *rds.ModifyDBInstanceInput
+	deleted       []string
+	// Replicas without the operator tag.
+	unmanaged map[string]bool
+}
+
+var _ = Describe("rds read replica Component", func() {
+	comp := rdscomponents.NewReadReplicas()
+	var mockRDS *mockRDSReplicaClient
+
+	BeforeEach(func() {
+		comp = rdscomponents.NewReadReplicas()
+		mockRDS = &mockRDSReplicaClient{replicas: map[string]*rds.DBInstance{}, unmanaged: map[string]bool{}}
+		comp.InjectRDSAPI(mockRDS)
+		instance.Spec.InstanceID = "test"
+		instance.Spec.InstanceClass = "db.t3.micro"
+		instance.ObjectMeta.Finalizers = []string{"rdsinstance.replicas.finalizer"}
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.SecurityGroupID = "sg-1234"
+		instance.Status.Connection = dbv1beta1.PostgresConnection{
+			Host:     "test.rds.aws",
+			Port:     5432,
+			Username: "ridecell_admin",
+			Database: "postgres",
+		}
+	})
+
+	It("does nothing without replicas", func() {
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdInputs).To(BeEmpty())
+		Expect(instance.Status.ReplicaConnections).To(BeEmpty())
+	})
+
+	It("waits for the primary to be ready", func() {
+		instance.Status.Status = dbv1beta1.StatusCreating
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting"}}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdInputs).To(BeEmpty())
+	})
+
+	It("creates a replica", func() {
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting", InstanceClass: "db.r5.large", AvailabilityZone: "us-west-2b"}}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdInputs).To(HaveLen(1))
+		input := mockRDS.createdInputs[0]
+		Expect(aws.StringValue(input.DBInstanceIdentifier)).To(Equal("test-reporting"))
+		Expect(aws.StringValue(input.SourceDBInstanceIdentifier)).To(Equal("test"))
+		Expect(aws.StringValue(input.DBInstanceClass)).To(Equal("db.r5.large"))
+		Expect(aws.StringValue(input.AvailabilityZone)).To(Equal("us-west-2b"))
+		Expect(aws.StringValue(input.DBParameterGroupName)).To(Equal("test"))
+		Expect(instance.Status.ReplicaConnections).To(BeEmpty())
+	})
+
+	It("defaults the replica instance class to the primary", func() {
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting"}}
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(aws.StringValue(mockRDS.createdInputs[0].DBInstanceClass)).To(Equal("db.t3.micro"))
+		Expect(mockRDS.createdInputs[0].AvailabilityZone).To(BeNil())
+	})
+
+	It("exposes an available replica", func() {
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting"}}
+		mockRDS.replicas["test-reporting"] = availableReplica("test-reporting", "db.t3.micro")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.createdInputs).To(BeEmpty())
+		Expect(instance.Status.ReplicaConnections).To(Equal([]dbv1beta1.PostgresConnection{{
+			Host:     "test-reporting.rds.aws",
+			Port:     5432,
+			Username: "ridecell_admin",
+			Database: "postgres",
+		}}))
+	})
+
+	It("changes the instance class of a replica", func() {
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting", InstanceClass: "db.r5.large"}}
+		mockRDS.replicas["test-reporting"] = availableReplica("test-reporting", "db.t3.micro")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(aws.StringValue(mockRDS.modifyInput.DBInstanceClass)).To(Equal("db.r5.large"))
+		Expect(instance.Status.ReplicaConnections).To(BeEmpty())
+	})
+
+	It("deletes replicas removed from the spec", func() {
+		mockRDS.replicas["test-reporting"] = availableReplica("test-reporting", "db.t3.micro")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deleted).To(ConsistOf("test-reporting"))
+	})
+
+	It("leaves replicas not created by the operator alone", func() {
+		mockRDS.replicas["test-dr"] = availableReplica("test-dr", "db.t3.micro")
+		mockRDS.unmanaged["test-dr"] = true
+		mockRDS.replicas["other-reporting"] = availableReplica("other-reporting", "db.t3.micro")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deleted).To(BeEmpty())
+	})
+
+	It("deletes all replicas before removing the finalizer", func() {
+		os.Setenv("ENABLE_FINALIZERS", "true")
+		defer os.Unsetenv("ENABLE_FINALIZERS")
+		instance.Spec.ReadReplicas = []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting"}}
+		mockRDS.replicas["test-reporting"] = availableReplica("test-reporting", "db.t3.micro")
+		currentTime := metav1.Now()
+		instance.ObjectMeta.SetDeletionTimestamp(&currentTime)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.deleted).To(ConsistOf("test-reporting"))
+		Expect(instance.ObjectMeta.Finalizers).To(ConsistOf("rdsinstance.replicas.finalizer"))
+
+		delete(mockRDS.replicas, "test-reporting")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.ObjectMeta.Finalizers).To(BeEmpty())
+	})
+})
+
+func availableReplica(id string, instanceClass string) *rds.DBInstance {
+	return &rds.DBInstance{
+		DBInstanceIdentifier: aws.String(id),
+		DBInstanceArn:        aws.String("arn:aws:rds:us-west-2:123456789012:db:" + id),
+		DBInstanceClass:      aws.String(instanceClass),
+		DBInstanceStatus:     aws.String("available"),
+		Endpoint: &rds.Endpoint{
+			Address: aws.String(id + ".rds.aws"),
+			Port:    aws.Int64(5432),
+		},
+	}
+}
+
+// Mock aws functions below
+
+func (m *mockRDSReplicaClient) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	id := aws.StringValue(input.DBInstanceIdentifier)
+	if id == "test" {
+		primary := &rds.DBInstance{DBInstanceIdentifier: aws.String("test")}
+		for replicaID := range m.replicas {
+			primary.ReadReplicaDBInstanceIdentifiers = append(primary.ReadReplicaDBInstanceIdentifiers, aws.String(replicaID))
+		}
+		return &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{primary}}, nil
+	}
+	replica, ok := m.replicas[id]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "", nil)
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{replica}}, nil
+}
+
+func (m *mockRDSReplicaClient) CreateDBInstanceReadReplica(input *rds.CreateDBInstanceReadReplicaInput) (*rds.CreateDBInstanceReadReplicaOutput, error) {
+	m.createdInputs = append(m.createdInputs, input)
+	return &rds.CreateDBInstanceReadReplicaOutput{DBInstance: &rds.DBInstance{
+		DBInstanceIdentifier: input.DBInstanceIdentifier,
+		DBInstanceStatus:     aws.String("creating"),
+	}}, nil
+}
+
+func (m *mockRDSReplicaClient) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.ModifyDBInstanceOutput, error) {
+	m.modifyInput = input
+	return &rds.ModifyDBInstanceOutput{}, nil
+}
+
+func (m *mockRDSReplicaClient) DeleteDBInstance(input *rds.DeleteDBInstanceInput) (*rds.DeleteDBInstanceOutput, error) {
+	if !aws.BoolValue(input.SkipFinalSnapshot) {
+		return nil, awserr.New(rds.ErrCodeInvalidDBInstanceStateFault, "replicas cannot take a final snapshot", nil)
+	}
+	m.deleted = append(m.deleted, aws.StringValue(input.DBInstanceIdentifier))
+	return &rds.DeleteDBInstanceOutput{}, nil
+}
+
+func (m *mockRDSReplicaClient) ListTagsForResource(input *rds.ListTagsForResourceInput) (*rds.ListTagsForResourceOutput, error) {
+	arn := aws.StringValue(input.ResourceName)
+	id := arn[strings.LastIndex(arn, ":")+1:]
+	if _, ok := m.replicas[id]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "", nil)
+	}
+	tags := []*rds.Tag{&rds.Tag{Key: aws.String("tenant"), Value: aws.String("test")}}
+	if !m.unmanaged[id] {
+		tags = append(tags, &rds.Tag{Key: aws.String("Ridecell-Operator"), Value: aws.String("true")})
+	}
+	return &rds.ListTagsForResourceOutput{TagList: tags}, nil
+}
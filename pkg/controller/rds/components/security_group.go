@@ -34,6 +34,7 @@ import (
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 const rdsInstanceSecurityGroupFinalizer = "rdsinstance.securitygroup.finalizer"
@@ -44,7 +45,7 @@ type dbSecurityGroupComponent struct {
 }
 
 func NewDBSecurityGroup() *dbSecurityGroupComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	ec2Service := ec2.New(sess)
 	rdsService := rds.New(sess)
 	return &dbSecurityGroupComponent{
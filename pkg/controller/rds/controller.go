@@ -33,6 +33,7 @@ func Add(mgr manager.Manager) error {
 		rdscomponents.NewDBSecurityGroup(),
 		rdscomponents.NewSecret(),
 		rdscomponents.NewRDSInstance(),
+		rdscomponents.NewReadReplicas(),
 	})
 	return err
 }
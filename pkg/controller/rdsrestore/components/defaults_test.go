@@ -0,0 +1,40 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	rdsrestorecomponents "github.com/Ridecell/ridecell-operator/pkg/controller/rdsrestore/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("rds restore Defaults Component", func() {
+	It("defaults the target instance to the object name", func() {
+		comp := rdsrestorecomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.TargetInstanceID).To(Equal("test"))
+	})
+
+	It("does not override a set target instance", func() {
+		instance.Spec.TargetInstanceID = "other"
+		comp := rdsrestorecomponents.NewDefaults()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Spec.TargetInstanceID).To(Equal("other"))
+	})
+})
@@ -0,0 +1,265 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+type RDSRestoreComponent struct {
+	rdsAPI rdsiface.RDSAPI
+}
+
+func NewRDSRestore() *RDSRestoreComponent {
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
+	rdsService := rds.New(sess)
+	return &RDSRestoreComponent{rdsAPI: rdsService}
+}
+
+func (comp *RDSRestoreComponent) InjectRDSAPI(rdsapi rdsiface.RDSAPI) {
+	comp.rdsAPI = rdsapi
+}
+
+func (_ *RDSRestoreComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *RDSRestoreComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*dbv1beta1.RDSRestore)
+	// Restores are one-shot, nothing left to do once the instance is available.
+	return instance.Status.Status != dbv1beta1.StatusReady
+}
+
+func (comp *RDSRestoreComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*dbv1beta1.RDSRestore)
+
+	if instance.Spec.SnapshotID != "" && instance.Spec.RestoreTime != "" {
+		return components.Result{}, errors.New("rds_restore: restoreTime cannot be used with snapshotID")
+	}
+
+	// Copy the settings of the RDSInstance so the restored instance can be managed by it once repointed.
+	rdsInstance := &dbv1beta1.RDSInstance{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.RDSInstanceRef, Namespace: instance.Namespace}, rdsInstance)
+	if err != nil {
+		return components.Result{}, errors.Wrapf(err, "rds_restore: failed to get rdsinstance %s", instance.Spec.RDSInstanceRef)
+	}
+	if rdsInstance.Status.SecurityGroupID == "" {
+		return components.Result{RequeueAfter: time.Minute, StatusModifier: setStatus(dbv1beta1.StatusCreating, fmt.Sprintf("Waiting for rdsinstance %s", rdsInstance.Name))}, nil
+	}
+
+	fetchSecret := &corev1.Secret{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s.rds-user-password", rdsInstance.Name), Namespace: instance.Namespace}, fetchSecret)
+	if err != nil {
+		return components.Result{}, errors.Wrap(err, "rds_restore: failed to get password secret")
+	}
+	password, ok := fetchSecret.Data["password"]
+	if !ok {
+		return components.Result{}, errors.New("rds_restore: database password secret not found")
+	}
+
+	var database *rds.DBInstance
+	describeDBInstancesOutput, err := comp.rdsAPI.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(instance.Spec.TargetInstanceID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != rds.ErrCodeDBInstanceNotFoundFault {
+			return components.Result{}, errors.Wrapf(err, "rds_restore: unable to describe db instance")
+		}
+		database, err = comp.restore(instance, rdsInstance)
+		if err != nil {
+			return components.Result{}, err
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "RestoreStarted", "Restoring %s to %s", restoreSource(instance, rdsInstance), instance.Spec.TargetInstanceID)
+	} else {
+		database = describeDBInstancesOutput.DBInstances[0]
+	}
+
+	dbStatus := aws.StringValue(database.DBInstanceStatus)
+	if dbStatus == "failed" || dbStatus == "incompatible-restore" || dbStatus == "incompatible-parameters" {
+		return components.Result{StatusModifier: setStatus(dbv1beta1.StatusError, fmt.Sprintf("RDS instance status: %s", dbStatus))}, errors.Errorf("rds_restore: rds instance %s is in a failure state", instance.Spec.TargetInstanceID)
+	}
+	if dbStatus != "available" {
+		// Don't lose track of a password reset in progress.
+		status := dbv1beta1.StatusCreating
+		if instance.Status.Status == dbv1beta1.StatusModifying {
+			status = dbv1beta1.StatusModifying
+		}
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			instance := obj.(*dbv1beta1.RDSRestore)
+			instance.Status.Status = status
+			instance.Status.Message = fmt.Sprintf("RDS instance status: %s", dbStatus)
+			instance.Status.InstanceID = aws.StringValue(database.DBInstanceIdentifier)
+			return nil
+		}, RequeueAfter: time.Second * 30}, nil
+	}
+
+	// The restored instance has the master password from the time of the backup, reset it to the current one.
+	if instance.Status.Status != dbv1beta1.StatusModifying {
+		_, err = comp.rdsAPI.ModifyDBInstance(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: database.DBInstanceIdentifier,
+			MasterUserPassword:   aws.String(string(password)),
+			ApplyImmediately:     aws.Bool(true),
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "rds_restore: failed to reset master password")
+		}
+		return components.Result{StatusModifier: setStatus(dbv1beta1.StatusModifying, "Resetting master password"), RequeueAfter: time.Second * 30}, nil
+	}
+	if database.PendingModifiedValues != nil && database.PendingModifiedValues.MasterUserPassword != nil {
+		return components.Result{RequeueAfter: time.Second * 30}, nil
+	}
+
+	if instance.Spec.DbConfigRef != nil {
+		err = comp.repointDbConfig(ctx, instance, database)
+		if err != nil {
+			return components.Result{}, err
+		}
+	}
+
+	ctx.Eventf(corev1.EventTypeNormal, "RestoreReady", "RDS instance %s is available", instance.Spec.TargetInstanceID)
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.RDSRestore)
+		instance.Status.Status = dbv1beta1.StatusReady
+		instance.Status.Message = "RDS instance restored and available"
+		instance.Status.InstanceID = aws.StringValue(database.DBInstanceIdentifier)
+		instance.Status.Endpoint = aws.StringValue(database.Endpoint.Address)
+		instance.Status.CompletedAt = time.Now().Format(time.UnixDate)
+		return nil
+	}}, nil
+}
+
+// Start the restore, using the parameter group, security group and subnet group of the RDSInstance.
+func (comp *RDSRestoreComponent) restore(instance *dbv1beta1.RDSRestore, rdsInstance *dbv1beta1.RDSInstance) (*rds.DBInstance, error) {
+	tags := []*rds.Tag{
+		&rds.Tag{
+			Key:   aws.String("Ridecell-Operator"),
+			Value: aws.String("true"),
+		},
+		&rds.Tag{
+			Key:   aws.String("tenant"),
+			Value: aws.String(rdsInstance.Name),
+		},
+	}
+	// The group name changes after a major version upgrade.
+	parameterGroupName := rdsInstance.Status.ParameterGroupName
+	if parameterGroupName == "" {
+		parameterGroupName = rdsInstance.Name
+	}
+	publiclyAccessible := rdsInstance.Spec.PubliclyAccessible
+	if publiclyAccessible == nil {
+		publiclyAccessible = aws.Bool(true)
+	}
+
+	if instance.Spec.SnapshotID != "" {
+		output, err := comp.rdsAPI.RestoreDBInstanceFromDBSnapshot(&rds.RestoreDBInstanceFromDBSnapshotInput{
+			DBInstanceIdentifier: aws.String(instance.Spec.TargetInstanceID),
+			DBSnapshotIdentifier: aws.String(instance.Spec.SnapshotID),
+			DBInstanceClass:      aws.String(rdsInstance.Spec.InstanceClass),
+			DBParameterGroupName: aws.String(parameterGroupName),
+			VpcSecurityGroupIds:  []*string{aws.String(rdsInstance.Status.SecurityGroupID)},
+			DBSubnetGroupName:    aws.String(rdsInstance.Spec.SubnetGroupName),
+			MultiAZ:              rdsInstance.Spec.MultiAZ,
+			PubliclyAccessible:   publiclyAccessible,
+			Tags:                 tags,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "rds_restore: unable to restore snapshot %s", instance.Spec.SnapshotID)
+		}
+		return output.DBInstance, nil
+	}
+
+	input := &rds.RestoreDBInstanceToPointInTimeInput{
+		SourceDBInstanceIdentifier: aws.String(restoreSource(instance, rdsInstance)),
+		TargetDBInstanceIdentifier: aws.String(instance.Spec.TargetInstanceID),
+		DBInstanceClass:            aws.String(rdsInstance.Spec.InstanceClass),
+		DBParameterGroupName:       aws.String(parameterGroupName),
+		VpcSecurityGroupIds:        []*string{aws.String(rdsInstance.Status.SecurityGroupID)},
+		DBSubnetGroupName:          aws.String(rdsInstance.Spec.SubnetGroupName),
+		MultiAZ:                    rdsInstance.Spec.MultiAZ,
+		PubliclyAccessible:         publiclyAccessible,
+		Tags:                       tags,
+	}
+	if instance.Spec.RestoreTime != "" {
+		restoreTime, err := time.Parse(time.RFC3339, instance.Spec.RestoreTime)
+		if err != nil {
+			return nil, errors.Wrap(err, "rds_restore: failed to parse restoreTime")
+		}
+		input.RestoreTime = aws.Time(restoreTime)
+	} else {
+		input.UseLatestRestorableTime = aws.Bool(true)
+	}
+	output, err := comp.rdsAPI.RestoreDBInstanceToPointInTime(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "rds_restore: unable to restore %s to a point in time", aws.StringValue(input.SourceDBInstanceIdentifier))
+	}
+	return output.DBInstance, nil
+}
+
+// Point the DbConfig at the restored instance, the RDSInstance will follow it on the next reconcile.
+func (comp *RDSRestoreComponent) repointDbConfig(ctx *components.ComponentContext, instance *dbv1beta1.RDSRestore, database *rds.DBInstance) error {
+	dbconfig := &dbv1beta1.DbConfig{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Spec.DbConfigRef.Name, Namespace: instance.Namespace}, dbconfig)
+	if err != nil {
+		return errors.Wrapf(err, "rds_restore: failed to get dbconfig %s", instance.Spec.DbConfigRef.Name)
+	}
+	if dbconfig.Spec.MigrationOverrides.RDSInstanceID == instance.Spec.TargetInstanceID {
+		return nil
+	}
+	dbconfig.Spec.MigrationOverrides.RDSInstanceID = instance.Spec.TargetInstanceID
+	dbconfig.Spec.MigrationOverrides.RDSMasterUsername = aws.StringValue(database.MasterUsername)
+	err = ctx.Update(ctx.Context, dbconfig)
+	if err != nil {
+		return errors.Wrapf(err, "rds_restore: failed to update dbconfig %s", dbconfig.Name)
+	}
+	ctx.Eventf(corev1.EventTypeNormal, "DbConfigRepointed", "Pointed dbconfig %s at %s", dbconfig.Name, instance.Spec.TargetInstanceID)
+	return nil
+}
+
+// Describes where the restore comes from, for events and as the point in time source.
+func restoreSource(instance *dbv1beta1.RDSRestore, rdsInstance *dbv1beta1.RDSInstance) string {
+	if instance.Spec.SnapshotID != "" {
+		return instance.Spec.SnapshotID
+	}
+	if instance.Spec.SourceInstanceID != "" {
+		return instance.Spec.SourceInstanceID
+	}
+	return rdsInstance.Status.InstanceID
+}
+
+func setStatus(status, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*dbv1beta1.RDSRestore)
+		instance.Status.Status = status
+		instance.Status.Message = message
+		return nil
+	}
+}
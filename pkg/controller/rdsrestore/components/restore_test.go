@@ -0,0 +1,230 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	rdsrestorecomponents "github.com/Ridecell/ridecell-operator/pkg/controller/rdsrestore/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+type mockRestoreRDSClient struct {
+	rdsiface.RDSAPI
+
+	instance         *rds.DBInstance
+	snapshotRestore  *rds.RestoreDBInstanceFromDBSnapshotInput
+	pointInTime      *rds.RestoreDBInstanceToPointInTimeInput
+	modifiedPassword string
+}
+
+var _ = Describe("rds restore Component", func() {
+	comp := rdsrestorecomponents.NewRDSRestore()
+	var mockRDS *mockRestoreRDSClient
+	var rdsInstance *dbv1beta1.RDSInstance
+
+	BeforeEach(func() {
+		mockRDS = &mockRestoreRDSClient{}
+		comp.InjectRDSAPI(mockRDS)
+
+		instance.Spec.RDSInstanceRef = "foo-dev"
+		instance.Spec.TargetInstanceID = "foo-dev-restored"
+		multiAZ := true
+		rdsInstance = &dbv1beta1.RDSInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "default"},
+			Spec: dbv1beta1.RDSInstanceSpec{
+				InstanceID:      "foo-dev",
+				InstanceClass:   "db.t3.small",
+				MultiAZ:         &multiAZ,
+				SubnetGroupName: "sandbox",
+			},
+			Status: dbv1beta1.RDSInstanceStatus{
+				InstanceID:      "foo-dev",
+				SecurityGroupID: "sg-1234",
+			},
+		}
+	})
+
+	// Set up the client after the test has changed the instance.
+	setupClient := func(objs ...runtime.Object) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.rds-user-password", Namespace: "default"},
+			Data: map[string][]byte{
+				"password": []byte("current"),
+			},
+		}
+		ctx.Client = fake.NewFakeClient(append([]runtime.Object{instance, rdsInstance, secret}, objs...)...)
+	}
+
+	Describe("IsReconcilable", func() {
+		It("is not reconcilable once restored", func() {
+			instance.Status.Status = dbv1beta1.StatusReady
+			Expect(comp.IsReconcilable(ctx)).To(BeFalse())
+		})
+
+		It("is reconcilable while restoring", func() {
+			instance.Status.Status = dbv1beta1.StatusCreating
+			Expect(comp.IsReconcilable(ctx)).To(BeTrue())
+		})
+	})
+
+	It("restores the latest point in time of the rdsinstance", func() {
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusCreating))
+		Expect(instance.Status.InstanceID).To(Equal("foo-dev-restored"))
+		Expect(mockRDS.snapshotRestore).To(BeNil())
+		Expect(mockRDS.pointInTime).ToNot(BeNil())
+		Expect(aws.StringValue(mockRDS.pointInTime.SourceDBInstanceIdentifier)).To(Equal("foo-dev"))
+		Expect(aws.BoolValue(mockRDS.pointInTime.UseLatestRestorableTime)).To(BeTrue())
+		Expect(aws.StringValue(mockRDS.pointInTime.DBParameterGroupName)).To(Equal("foo-dev"))
+		Expect(aws.StringValueSlice(mockRDS.pointInTime.VpcSecurityGroupIds)).To(ConsistOf("sg-1234"))
+		Expect(aws.StringValue(mockRDS.pointInTime.DBSubnetGroupName)).To(Equal("sandbox"))
+		Expect(aws.StringValue(mockRDS.pointInTime.DBInstanceClass)).To(Equal("db.t3.small"))
+		Expect(aws.BoolValue(mockRDS.pointInTime.MultiAZ)).To(BeTrue())
+	})
+
+	It("restores another instance to a given time", func() {
+		instance.Spec.SourceInstanceID = "other"
+		instance.Spec.RestoreTime = "2020-01-02T03:04:05Z"
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(aws.StringValue(mockRDS.pointInTime.SourceDBInstanceIdentifier)).To(Equal("other"))
+		Expect(aws.TimeValue(mockRDS.pointInTime.RestoreTime)).To(Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+		Expect(mockRDS.pointInTime.UseLatestRestorableTime).To(BeNil())
+	})
+
+	It("restores a snapshot", func() {
+		instance.Spec.SnapshotID = "foo-dev-snapshot"
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.pointInTime).To(BeNil())
+		Expect(mockRDS.snapshotRestore).ToNot(BeNil())
+		Expect(aws.StringValue(mockRDS.snapshotRestore.DBSnapshotIdentifier)).To(Equal("foo-dev-snapshot"))
+		Expect(aws.StringValue(mockRDS.snapshotRestore.DBInstanceIdentifier)).To(Equal("foo-dev-restored"))
+		Expect(aws.StringValue(mockRDS.snapshotRestore.DBParameterGroupName)).To(Equal("foo-dev"))
+	})
+
+	It("rejects a restore time with a snapshot", func() {
+		instance.Spec.SnapshotID = "foo-dev-snapshot"
+		instance.Spec.RestoreTime = "2020-01-02T03:04:05Z"
+		setupClient()
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+
+	It("waits for the rdsinstance security group", func() {
+		rdsInstance.Status.SecurityGroupID = ""
+		setupClient()
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).ToNot(BeZero())
+		Expect(mockRDS.pointInTime).To(BeNil())
+	})
+
+	It("resets the master password once available", func() {
+		mockRDS.instance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("foo-dev-restored"),
+			DBInstanceStatus:     aws.String("available"),
+			MasterUsername:       aws.String("foo_dev"),
+			Endpoint:             &rds.Endpoint{Address: aws.String("foo-dev-restored.rds.amazonaws.com")},
+		}
+		instance.Status.Status = dbv1beta1.StatusCreating
+		setupClient()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.modifiedPassword).To(Equal("current"))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusModifying))
+
+		// Still modifying while the reset is in progress.
+		mockRDS.instance.DBInstanceStatus = aws.String("resetting-master-credentials")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusModifying))
+
+		mockRDS.modifiedPassword = ""
+		mockRDS.instance.DBInstanceStatus = aws.String("available")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(mockRDS.modifiedPassword).To(BeEmpty())
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+		Expect(instance.Status.Endpoint).To(Equal("foo-dev-restored.rds.amazonaws.com"))
+		Expect(instance.Status.CompletedAt).ToNot(BeEmpty())
+	})
+
+	It("repoints the dbconfig", func() {
+		mockRDS.instance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("foo-dev-restored"),
+			DBInstanceStatus:     aws.String("available"),
+			MasterUsername:       aws.String("foo_dev"),
+			Endpoint:             &rds.Endpoint{Address: aws.String("foo-dev-restored.rds.amazonaws.com")},
+		}
+		instance.Status.Status = dbv1beta1.StatusModifying
+		instance.Spec.DbConfigRef = &corev1.LocalObjectReference{Name: "default"}
+		dbconfig := &dbv1beta1.DbConfig{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+		setupClient(dbconfig)
+
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(dbv1beta1.StatusReady))
+		err := ctx.Get(ctx.Context, types.NamespacedName{Name: "default", Namespace: "default"}, dbconfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dbconfig.Spec.MigrationOverrides.RDSInstanceID).To(Equal("foo-dev-restored"))
+		Expect(dbconfig.Spec.MigrationOverrides.RDSMasterUsername).To(Equal("foo_dev"))
+	})
+
+	It("fails if the restore fails", func() {
+		mockRDS.instance = &rds.DBInstance{
+			DBInstanceIdentifier: aws.String("foo-dev-restored"),
+			DBInstanceStatus:     aws.String("incompatible-restore"),
+		}
+		setupClient()
+		Expect(comp).NotTo(ReconcileContext(ctx))
+	})
+})
+
+func (m *mockRestoreRDSClient) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	if m.instance == nil || aws.StringValue(input.DBInstanceIdentifier) != aws.StringValue(m.instance.DBInstanceIdentifier) {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, "", nil)
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{m.instance}}, nil
+}
+
+func (m *mockRestoreRDSClient) RestoreDBInstanceFromDBSnapshot(input *rds.RestoreDBInstanceFromDBSnapshotInput) (*rds.RestoreDBInstanceFromDBSnapshotOutput, error) {
+	m.snapshotRestore = input
+	m.instance = &rds.DBInstance{DBInstanceIdentifier: input.DBInstanceIdentifier, DBInstanceStatus: aws.String("creating")}
+	return &rds.RestoreDBInstanceFromDBSnapshotOutput{DBInstance: m.instance}, nil
+}
+
+func (m *mockRestoreRDSClient) RestoreDBInstanceToPointInTime(input *rds.RestoreDBInstanceToPointInTimeInput) (*rds.RestoreDBInstanceToPointInTimeOutput, error) {
+	m.pointInTime = input
+	m.instance = &rds.DBInstance{DBInstanceIdentifier: input.TargetDBInstanceIdentifier, DBInstanceStatus: aws.String("creating")}
+	return &rds.RestoreDBInstanceToPointInTimeOutput{DBInstance: m.instance}, nil
+}
+
+func (m *mockRestoreRDSClient) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.ModifyDBInstanceOutput, error) {
+	m.modifiedPassword = aws.StringValue(input.MasterUserPassword)
+	return &rds.ModifyDBInstanceOutput{DBInstance: m.instance}, nil
+}
@@ -48,6 +48,9 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 	if instance.Spec.Region == "" {
 		instance.Spec.Region = "us-west-2"
 	}
+	if instance.Spec.Encryption != nil && instance.Spec.Encryption.Algorithm == "" {
+		instance.Spec.Encryption.Algorithm = "aws:kms"
+	}
 
 	return components.Result{}, nil
 }
@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	s3bucketcomponents "github.com/Ridecell/ridecell-operator/pkg/controller/s3bucket/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
@@ -41,6 +42,15 @@ var _ = Describe("s3bucket Defaults Component", func() {
 
 		Expect(instance.Spec.BucketName).To(Equal("test-bucket"))
 		Expect(instance.Spec.Region).To(Equal("us-west-2"))
+		Expect(instance.Spec.Encryption).To(BeNil())
+	})
+
+	It("defaults the encryption algorithm to KMS", func() {
+		comp := s3bucketcomponents.NewDefaults()
+		instance.Spec.Encryption = &awsv1beta1.S3BucketEncryptionSpec{}
+		Expect(comp).To(ReconcileContext(ctx))
+
+		Expect(instance.Spec.Encryption.Algorithm).To(Equal("aws:kms"))
 	})
 
 })
@@ -32,6 +32,7 @@ import (
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
 	helpers "github.com/Ridecell/ridecell-operator/pkg/apis/helpers"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 const s3BucketFinalizer = "s3bucket.finalizer"
@@ -51,7 +52,7 @@ func realS3Factory(region string) (s3iface.S3API, error) {
 	if err != nil {
 		return nil, err
 	}
-	return s3.New(sess), nil
+	return s3.New(metrics.InstrumentAWSSession(sess)), nil
 }
 
 func NewS3Bucket() *s3BucketComponent {
@@ -177,6 +178,12 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 	}
 
+	// The public access block has to be relaxed before a public bucket policy can be put.
+	err = reconcilePublicAccessBlock(s3Service, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
 	// Try to grab the existing bucket policy.
 	bucketHasPolicy := true
 	getBucketPolicyObj, err := s3Service.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(instance.Spec.BucketName)})
@@ -230,10 +237,27 @@ func (comp *s3BucketComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 	}
 
+	err = reconcileVersioning(s3Service, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+	err = reconcileEncryption(s3Service, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+	err = reconcileLifecycleRules(s3Service, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+	err = reconcileCorsRules(s3Service, instance)
+	if err != nil {
+		return components.Result{}, err
+	}
+
 	return components.Result{StatusModifier: func(obj runtime.Object) error {
 		instance := obj.(*awsv1beta1.S3Bucket)
 		instance.Status.Status = awsv1beta1.StatusReady
-		instance.Status.Message = "Bucket exists and has correct policy"
+		instance.Status.Message = "Bucket exists and has correct configuration"
 		return nil
 	}}, nil
 }
@@ -293,3 +317,269 @@ func (comp *s3BucketComponent) deleteDependencies(ctx *components.ComponentConte
 	}
 	return components.Result{}, nil
 }
+
+func reconcilePublicAccessBlock(s3Service s3iface.S3API, instance *awsv1beta1.S3Bucket) error {
+	if instance.Spec.PublicAccessBlock == nil {
+		return nil
+	}
+
+	var existing *s3.PublicAccessBlockConfiguration
+	getPublicAccessBlockOutput, err := s3Service.GetPublicAccessBlock(&s3.GetPublicAccessBlockInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchPublicAccessBlockConfiguration" {
+			return errors.Wrapf(err, "s3_bucket: failed to get public access block for bucket %s", instance.Spec.BucketName)
+		}
+	} else {
+		existing = getPublicAccessBlockOutput.PublicAccessBlockConfiguration
+	}
+
+	goal := instance.Spec.PublicAccessBlock
+	if existing != nil &&
+		aws.BoolValue(existing.BlockPublicAcls) == goal.BlockPublicAcls &&
+		aws.BoolValue(existing.IgnorePublicAcls) == goal.IgnorePublicAcls &&
+		aws.BoolValue(existing.BlockPublicPolicy) == goal.BlockPublicPolicy &&
+		aws.BoolValue(existing.RestrictPublicBuckets) == goal.RestrictPublicBuckets {
+		return nil
+	}
+	_, err = s3Service.PutPublicAccessBlock(&s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(instance.Spec.BucketName),
+		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(goal.BlockPublicAcls),
+			IgnorePublicAcls:      aws.Bool(goal.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.Bool(goal.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.Bool(goal.RestrictPublicBuckets),
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to put public access block for bucket %s", instance.Spec.BucketName)
+	}
+	return nil
+}
+
+func reconcileVersioning(s3Service s3iface.S3API, instance *awsv1beta1.S3Bucket) error {
+	if instance.Spec.Versioning == nil {
+		return nil
+	}
+
+	getBucketVersioningOutput, err := s3Service.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to get versioning for bucket %s", instance.Spec.BucketName)
+	}
+
+	// Versioning can never be turned off again once enabled, only suspended.
+	currentStatus := aws.StringValue(getBucketVersioningOutput.Status)
+	goalStatus := currentStatus
+	if *instance.Spec.Versioning {
+		goalStatus = s3.BucketVersioningStatusEnabled
+	} else if currentStatus == s3.BucketVersioningStatusEnabled {
+		goalStatus = s3.BucketVersioningStatusSuspended
+	}
+	if goalStatus == currentStatus {
+		return nil
+	}
+
+	_, err = s3Service.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket:                  aws.String(instance.Spec.BucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(goalStatus)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to put versioning for bucket %s", instance.Spec.BucketName)
+	}
+	return nil
+}
+
+func reconcileEncryption(s3Service s3iface.S3API, instance *awsv1beta1.S3Bucket) error {
+	if instance.Spec.Encryption == nil {
+		return nil
+	}
+
+	var existing *s3.ServerSideEncryptionByDefault
+	getBucketEncryptionOutput, err := s3Service.GetBucketEncryption(&s3.GetBucketEncryptionInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "ServerSideEncryptionConfigurationNotFoundError" {
+			return errors.Wrapf(err, "s3_bucket: failed to get encryption for bucket %s", instance.Spec.BucketName)
+		}
+	} else if config := getBucketEncryptionOutput.ServerSideEncryptionConfiguration; config != nil && len(config.Rules) == 1 {
+		existing = config.Rules[0].ApplyServerSideEncryptionByDefault
+	}
+
+	goal := &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(instance.Spec.Encryption.Algorithm)}
+	if instance.Spec.Encryption.Algorithm == s3.ServerSideEncryptionAwsKms && instance.Spec.Encryption.KMSKeyID != "" {
+		goal.KMSMasterKeyID = aws.String(instance.Spec.Encryption.KMSKeyID)
+	}
+	if existing != nil &&
+		aws.StringValue(existing.SSEAlgorithm) == aws.StringValue(goal.SSEAlgorithm) &&
+		aws.StringValue(existing.KMSMasterKeyID) == aws.StringValue(goal.KMSMasterKeyID) {
+		return nil
+	}
+
+	_, err = s3Service.PutBucketEncryption(&s3.PutBucketEncryptionInput{
+		Bucket: aws.String(instance.Spec.BucketName),
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				&s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: goal},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to put encryption for bucket %s", instance.Spec.BucketName)
+	}
+	return nil
+}
+
+func reconcileLifecycleRules(s3Service s3iface.S3API, instance *awsv1beta1.S3Bucket) error {
+	if len(instance.Spec.LifecycleRules) == 0 {
+		return nil
+	}
+
+	var existing []*s3.LifecycleRule
+	getLifecycleOutput, err := s3Service.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchLifecycleConfiguration" {
+			return errors.Wrapf(err, "s3_bucket: failed to get lifecycle rules for bucket %s", instance.Spec.BucketName)
+		}
+	} else {
+		existing = getLifecycleOutput.Rules
+	}
+
+	// Round-trip the goal rules so both sides are in the same normalized form.
+	goal := lifecycleRulesToAWS(instance.Spec.LifecycleRules)
+	existingRules, ok := lifecycleRulesFromAWS(existing)
+	if ok && reflect.DeepEqual(existingRules, mustLifecycleRulesFromAWS(goal)) {
+		return nil
+	}
+
+	_, err = s3Service.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(instance.Spec.BucketName),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: goal},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to put lifecycle rules for bucket %s", instance.Spec.BucketName)
+	}
+	return nil
+}
+
+func lifecycleRulesToAWS(rules []awsv1beta1.S3BucketLifecycleRule) []*s3.LifecycleRule {
+	awsRules := []*s3.LifecycleRule{}
+	for _, rule := range rules {
+		awsRule := &s3.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Status: aws.String(s3.ExpirationStatusEnabled),
+			Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+		}
+		if rule.ExpirationDays > 0 {
+			awsRule.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(rule.ExpirationDays)}
+		}
+		if rule.NoncurrentVersionExpirationDays > 0 {
+			awsRule.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(rule.NoncurrentVersionExpirationDays)}
+		}
+		for _, transition := range rule.Transitions {
+			awsRule.Transitions = append(awsRule.Transitions, &s3.Transition{
+				Days:         aws.Int64(transition.Days),
+				StorageClass: aws.String(transition.StorageClass),
+			})
+		}
+		awsRules = append(awsRules, awsRule)
+	}
+	return awsRules
+}
+
+// lifecycleRulesFromAWS converts existing rules back to our spec format. Returns false if any
+// rule uses features we don't manage, which means the rules need to be replaced.
+func lifecycleRulesFromAWS(awsRules []*s3.LifecycleRule) ([]awsv1beta1.S3BucketLifecycleRule, bool) {
+	rules := []awsv1beta1.S3BucketLifecycleRule{}
+	for _, awsRule := range awsRules {
+		if aws.StringValue(awsRule.Status) != s3.ExpirationStatusEnabled {
+			return nil, false
+		}
+		if awsRule.Filter != nil && (awsRule.Filter.And != nil || awsRule.Filter.Tag != nil) {
+			return nil, false
+		}
+		rule := awsv1beta1.S3BucketLifecycleRule{ID: aws.StringValue(awsRule.ID)}
+		if awsRule.Filter != nil {
+			rule.Prefix = aws.StringValue(awsRule.Filter.Prefix)
+		} else {
+			rule.Prefix = aws.StringValue(awsRule.Prefix)
+		}
+		if awsRule.Expiration != nil {
+			rule.ExpirationDays = aws.Int64Value(awsRule.Expiration.Days)
+		}
+		if awsRule.NoncurrentVersionExpiration != nil {
+			rule.NoncurrentVersionExpirationDays = aws.Int64Value(awsRule.NoncurrentVersionExpiration.NoncurrentDays)
+		}
+		for _, transition := range awsRule.Transitions {
+			rule.Transitions = append(rule.Transitions, awsv1beta1.S3BucketLifecycleTransition{
+				Days:         aws.Int64Value(transition.Days),
+				StorageClass: aws.StringValue(transition.StorageClass),
+			})
+		}
+		rules = append(rules, rule)
+	}
+	return rules, true
+}
+
+func mustLifecycleRulesFromAWS(awsRules []*s3.LifecycleRule) []awsv1beta1.S3BucketLifecycleRule {
+	rules, _ := lifecycleRulesFromAWS(awsRules)
+	return rules
+}
+
+func reconcileCorsRules(s3Service s3iface.S3API, instance *awsv1beta1.S3Bucket) error {
+	if len(instance.Spec.CorsRules) == 0 {
+		return nil
+	}
+
+	var existing []*s3.CORSRule
+	getBucketCorsOutput, err := s3Service.GetBucketCors(&s3.GetBucketCorsInput{Bucket: aws.String(instance.Spec.BucketName)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchCORSConfiguration" {
+			return errors.Wrapf(err, "s3_bucket: failed to get CORS rules for bucket %s", instance.Spec.BucketName)
+		}
+	} else {
+		existing = getBucketCorsOutput.CORSRules
+	}
+
+	goal := corsRulesToAWS(instance.Spec.CorsRules)
+	if reflect.DeepEqual(corsRulesFromAWS(existing), corsRulesFromAWS(goal)) {
+		return nil
+	}
+
+	_, err = s3Service.PutBucketCors(&s3.PutBucketCorsInput{
+		Bucket:            aws.String(instance.Spec.BucketName),
+		CORSConfiguration: &s3.CORSConfiguration{CORSRules: goal},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3_bucket: failed to put CORS rules for bucket %s", instance.Spec.BucketName)
+	}
+	return nil
+}
+
+func corsRulesToAWS(rules []awsv1beta1.S3BucketCorsRule) []*s3.CORSRule {
+	awsRules := []*s3.CORSRule{}
+	for _, rule := range rules {
+		awsRule := &s3.CORSRule{
+			AllowedOrigins: aws.StringSlice(rule.AllowedOrigins),
+			AllowedMethods: aws.StringSlice(rule.AllowedMethods),
+			AllowedHeaders: aws.StringSlice(rule.AllowedHeaders),
+			ExposeHeaders:  aws.StringSlice(rule.ExposeHeaders),
+		}
+		if rule.MaxAgeSeconds > 0 {
+			awsRule.MaxAgeSeconds = aws.Int64(rule.MaxAgeSeconds)
+		}
+		awsRules = append(awsRules, awsRule)
+	}
+	return awsRules
+}
+
+func corsRulesFromAWS(awsRules []*s3.CORSRule) []awsv1beta1.S3BucketCorsRule {
+	rules := []awsv1beta1.S3BucketCorsRule{}
+	for _, awsRule := range awsRules {
+		rules = append(rules, awsv1beta1.S3BucketCorsRule{
+			AllowedOrigins: aws.StringValueSlice(awsRule.AllowedOrigins),
+			AllowedMethods: aws.StringValueSlice(awsRule.AllowedMethods),
+			AllowedHeaders: aws.StringValueSlice(awsRule.AllowedHeaders),
+			ExposeHeaders:  aws.StringValueSlice(awsRule.ExposeHeaders),
+			MaxAgeSeconds:  aws.Int64Value(awsRule.MaxAgeSeconds),
+		})
+	}
+	return rules
+}
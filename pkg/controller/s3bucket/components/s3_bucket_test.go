@@ -42,12 +42,22 @@ type mockS3Client struct {
 	mockBucketPolicy    *string
 	mockBucketNameTaken bool
 	mockBucketTagged    bool
-
-	putPolicy        bool
-	putPolicyContent string
-	putBucketTagging bool
-	deletePolicy     bool
-	deleteBucket     bool
+	mockPublicAccess    *s3.PublicAccessBlockConfiguration
+	mockVersioning      string
+	mockEncryption      *s3.ServerSideEncryptionByDefault
+	mockLifecycleRules  []*s3.LifecycleRule
+	mockCorsRules       []*s3.CORSRule
+
+	putPolicy         bool
+	putPolicyContent  string
+	putBucketTagging  bool
+	deletePolicy      bool
+	deleteBucket      bool
+	putPublicAccess   *s3.PublicAccessBlockConfiguration
+	putVersioning     string
+	putEncryption     *s3.ServerSideEncryptionByDefault
+	putLifecycleRules []*s3.LifecycleRule
+	putCorsRules      []*s3.CORSRule
 }
 
 var _ = Describe("s3bucket aws Component", func() {
@@ -152,6 +162,145 @@ var _ = Describe("s3bucket aws Component", func() {
 		Expect(mockS3.deletePolicy).To(BeTrue())
 	})
 
+	Describe("bucket settings", func() {
+		BeforeEach(func() {
+			mockS3.mockBucketExists = true
+			mockS3.mockBucketTagged = true
+			instance.Spec.BucketName = "foo-default-miv"
+			instance.Spec.Versioning = aws.Bool(true)
+			instance.Spec.Encryption = &awsv1beta1.S3BucketEncryptionSpec{Algorithm: "aws:kms", KMSKeyID: "arn:aws:kms:us-west-2:1234:key/abcd"}
+			instance.Spec.LifecycleRules = []awsv1beta1.S3BucketLifecycleRule{
+				{
+					ID:                              "expire",
+					ExpirationDays:                  30,
+					NoncurrentVersionExpirationDays: 1,
+					Transitions:                     []awsv1beta1.S3BucketLifecycleTransition{{Days: 7, StorageClass: "STANDARD_IA"}},
+				},
+			}
+			instance.Spec.CorsRules = []awsv1beta1.S3BucketCorsRule{
+				{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "HEAD"}, MaxAgeSeconds: 3000},
+			}
+			instance.Spec.PublicAccessBlock = &awsv1beta1.S3BucketPublicAccessBlock{
+				BlockPublicAcls:       true,
+				IgnorePublicAcls:      true,
+				BlockPublicPolicy:     true,
+				RestrictPublicBuckets: true,
+			}
+		})
+
+		It("applies settings to a bucket without them", func() {
+			Expect(comp).To(ReconcileContext(ctx))
+
+			Expect(mockS3.putVersioning).To(Equal("Enabled"))
+			Expect(mockS3.putEncryption).ToNot(BeNil())
+			Expect(aws.StringValue(mockS3.putEncryption.SSEAlgorithm)).To(Equal("aws:kms"))
+			Expect(aws.StringValue(mockS3.putEncryption.KMSMasterKeyID)).To(Equal("arn:aws:kms:us-west-2:1234:key/abcd"))
+			Expect(mockS3.putLifecycleRules).To(HaveLen(1))
+			Expect(aws.StringValue(mockS3.putLifecycleRules[0].ID)).To(Equal("expire"))
+			Expect(aws.Int64Value(mockS3.putLifecycleRules[0].Expiration.Days)).To(Equal(int64(30)))
+			Expect(aws.Int64Value(mockS3.putLifecycleRules[0].NoncurrentVersionExpiration.NoncurrentDays)).To(Equal(int64(1)))
+			Expect(aws.StringValue(mockS3.putLifecycleRules[0].Transitions[0].StorageClass)).To(Equal("STANDARD_IA"))
+			Expect(mockS3.putCorsRules).To(HaveLen(1))
+			Expect(aws.StringValueSlice(mockS3.putCorsRules[0].AllowedMethods)).To(Equal([]string{"GET", "HEAD"}))
+			Expect(mockS3.putPublicAccess).ToNot(BeNil())
+			Expect(aws.BoolValue(mockS3.putPublicAccess.BlockPublicPolicy)).To(BeTrue())
+			Expect(instance.Status.Status).To(Equal(awsv1beta1.StatusReady))
+		})
+
+		It("does not touch settings that already match", func() {
+			mockS3.mockVersioning = "Enabled"
+			mockS3.mockEncryption = &s3.ServerSideEncryptionByDefault{
+				SSEAlgorithm:   aws.String("aws:kms"),
+				KMSMasterKeyID: aws.String("arn:aws:kms:us-west-2:1234:key/abcd"),
+			}
+			mockS3.mockLifecycleRules = []*s3.LifecycleRule{
+				&s3.LifecycleRule{
+					ID:                          aws.String("expire"),
+					Status:                      aws.String("Enabled"),
+					Filter:                      &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					Expiration:                  &s3.LifecycleExpiration{Days: aws.Int64(30)},
+					NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(1)},
+					Transitions:                 []*s3.Transition{&s3.Transition{Days: aws.Int64(7), StorageClass: aws.String("STANDARD_IA")}},
+				},
+			}
+			mockS3.mockCorsRules = []*s3.CORSRule{
+				&s3.CORSRule{
+					AllowedOrigins: aws.StringSlice([]string{"*"}),
+					AllowedMethods: aws.StringSlice([]string{"GET", "HEAD"}),
+					MaxAgeSeconds:  aws.Int64(3000),
+				},
+			}
+			mockS3.mockPublicAccess = &s3.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				IgnorePublicAcls:      aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(true),
+				RestrictPublicBuckets: aws.Bool(true),
+			}
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			Expect(mockS3.putVersioning).To(Equal(""))
+			Expect(mockS3.putEncryption).To(BeNil())
+			Expect(mockS3.putLifecycleRules).To(BeNil())
+			Expect(mockS3.putCorsRules).To(BeNil())
+			Expect(mockS3.putPublicAccess).To(BeNil())
+		})
+
+		It("corrects drifted settings", func() {
+			mockS3.mockEncryption = &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("AES256")}
+			mockS3.mockLifecycleRules = []*s3.LifecycleRule{
+				&s3.LifecycleRule{
+					ID:         aws.String("expire"),
+					Status:     aws.String("Disabled"),
+					Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					Expiration: &s3.LifecycleExpiration{Days: aws.Int64(30)},
+				},
+			}
+			mockS3.mockPublicAccess = &s3.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				IgnorePublicAcls:      aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(false),
+				RestrictPublicBuckets: aws.Bool(false),
+			}
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			Expect(aws.StringValue(mockS3.putEncryption.SSEAlgorithm)).To(Equal("aws:kms"))
+			Expect(aws.StringValue(mockS3.putLifecycleRules[0].Status)).To(Equal("Enabled"))
+			Expect(aws.BoolValue(mockS3.putPublicAccess.RestrictPublicBuckets)).To(BeTrue())
+		})
+
+		It("leaves settings which aren't in the spec alone", func() {
+			instance.Spec.Versioning = nil
+			instance.Spec.Encryption = nil
+			instance.Spec.LifecycleRules = nil
+			instance.Spec.CorsRules = nil
+			instance.Spec.PublicAccessBlock = nil
+			mockS3.mockVersioning = "Enabled"
+			mockS3.mockEncryption = &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("aws:kms")}
+			mockS3.mockLifecycleRules = []*s3.LifecycleRule{&s3.LifecycleRule{ID: aws.String("expire"), Status: aws.String("Enabled")}}
+			mockS3.mockCorsRules = []*s3.CORSRule{&s3.CORSRule{AllowedOrigins: aws.StringSlice([]string{"*"})}}
+			mockS3.mockPublicAccess = &s3.PublicAccessBlockConfiguration{BlockPublicAcls: aws.Bool(true)}
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			Expect(mockS3.putVersioning).To(BeEmpty())
+			Expect(mockS3.putEncryption).To(BeNil())
+			Expect(mockS3.putLifecycleRules).To(BeNil())
+			Expect(mockS3.putCorsRules).To(BeNil())
+			Expect(mockS3.putPublicAccess).To(BeNil())
+		})
+
+		It("suspends versioning when it is turned off", func() {
+			instance.Spec.Versioning = aws.Bool(false)
+			mockS3.mockVersioning = "Enabled"
+
+			Expect(comp).To(ReconcileContext(ctx))
+
+			Expect(mockS3.putVersioning).To(Equal("Suspended"))
+		})
+	})
+
 	Describe("finalizer tests", func() {
 		It("adds finalizer when there isn't one", func() {
 			instance.ObjectMeta.Finalizers = []string{}
@@ -292,3 +441,100 @@ func (m *mockS3Client) DeleteBucket(input *s3.DeleteBucketInput) (*s3.DeleteBuck
 	m.deleteBucket = true
 	return nil, nil
 }
+
+func (m *mockS3Client) GetPublicAccessBlock(input *s3.GetPublicAccessBlockInput) (*s3.GetPublicAccessBlockOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	if m.mockPublicAccess == nil {
+		return nil, awserr.New("NoSuchPublicAccessBlockConfiguration", "", nil)
+	}
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: m.mockPublicAccess}, nil
+}
+
+func (m *mockS3Client) PutPublicAccessBlock(input *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.putPublicAccess = input.PublicAccessBlockConfiguration
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
+
+func (m *mockS3Client) GetBucketVersioning(input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	output := &s3.GetBucketVersioningOutput{}
+	if m.mockVersioning != "" {
+		output.Status = aws.String(m.mockVersioning)
+	}
+	return output, nil
+}
+
+func (m *mockS3Client) PutBucketVersioning(input *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.putVersioning = aws.StringValue(input.VersioningConfiguration.Status)
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+func (m *mockS3Client) GetBucketEncryption(input *s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	if m.mockEncryption == nil {
+		return nil, awserr.New("ServerSideEncryptionConfigurationNotFoundError", "", nil)
+	}
+	return &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{
+				&s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: m.mockEncryption},
+			},
+		},
+	}, nil
+}
+
+func (m *mockS3Client) PutBucketEncryption(input *s3.PutBucketEncryptionInput) (*s3.PutBucketEncryptionOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.putEncryption = input.ServerSideEncryptionConfiguration.Rules[0].ApplyServerSideEncryptionByDefault
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+func (m *mockS3Client) GetBucketLifecycleConfiguration(input *s3.GetBucketLifecycleConfigurationInput) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	if m.mockLifecycleRules == nil {
+		return nil, awserr.New("NoSuchLifecycleConfiguration", "", nil)
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: m.mockLifecycleRules}, nil
+}
+
+func (m *mockS3Client) PutBucketLifecycleConfiguration(input *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.putLifecycleRules = input.LifecycleConfiguration.Rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (m *mockS3Client) GetBucketCors(input *s3.GetBucketCorsInput) (*s3.GetBucketCorsOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	if m.mockCorsRules == nil {
+		return nil, awserr.New("NoSuchCORSConfiguration", "", nil)
+	}
+	return &s3.GetBucketCorsOutput{CORSRules: m.mockCorsRules}, nil
+}
+
+func (m *mockS3Client) PutBucketCors(input *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	if aws.StringValue(input.Bucket) != instance.Spec.BucketName {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "", nil)
+	}
+	m.putCorsRules = input.CORSConfiguration.CORSRules
+	return &s3.PutBucketCorsOutput{}, nil
+}
@@ -25,11 +25,14 @@ import (
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
@@ -47,7 +50,11 @@ type realKeyManager struct {
 }
 
 func newRealKeyManager() (*realKeyManager, error) {
-	svc, err := iam.NewService(context.Background())
+	client, err := google.DefaultClient(context.Background(), iam.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := iam.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
 	if err != nil {
 		return nil, err
 	}
@@ -22,13 +22,16 @@ import (
 	"os"
 
 	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iam/v1"
+	"google.golang.org/api/option"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	gcpv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/gcp/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 // Interface for an IAM client to allow for a mock implementation.
@@ -43,7 +46,11 @@ type realServiceAccountManager struct {
 }
 
 func newRealServiceAccountManager() (*realServiceAccountManager, error) {
-	svc, err := iam.NewService(context.Background())
+	client, err := google.DefaultClient(context.Background(), iam.CloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := iam.NewService(context.Background(), option.WithHTTPClient(metrics.InstrumentHTTPClient("gcp", client)))
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +119,7 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 				pqdb := obj.(*dbv1beta1.PostgresDatabase)
 				pqdb.Status.DatabaseClusterStatus = dbconfig.Status.Postgres.Status
 				pqdb.Status.AdminConnection = dbconfig.Status.Postgres.Connection
+				pqdb.Status.AdminReplicaConnections = dbconfig.Status.Postgres.ReplicaConnections
 				pqdb.Status.SharedUsers = dbconfig.Status.Postgres.SharedUsers
 				pqdb.Status.RDSInstanceID = dbconfig.Status.RDSInstanceID
 				return nil
@@ -136,6 +137,7 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 	var res components.Result
 	var status string
 	var conn *dbv1beta1.PostgresConnection
+	var replicaConns []dbv1beta1.PostgresConnection
 	var err error
 	var rdsInstanceID string
 	if dbconfig.Spec.Postgres.RDS != nil {
@@ -146,6 +148,7 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 		status = rdsStatus.Status
 		rdsInstanceID = rdsStatus.InstanceID
+		replicaConns = rdsStatus.ReplicaConnections
 	} else if dbconfig.Spec.Postgres.Local != nil {
 		res, status, conn, err = comp.reconcileLocal(ctx, dbconfig)
 		if err != nil {
@@ -178,6 +181,7 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 			instance := obj.(*dbv1beta1.PostgresDatabase)
 			instance.Status.DatabaseClusterStatus = status
 			instance.Status.AdminConnection = *conn
+			instance.Status.AdminReplicaConnections = replicaConns
 			// If we updated periscope shared user status with permissions granted, don't overwrite it with Periscope
 			// PostgresUser Object status. Otherwise, PostgresDatabase status 'Ready' depends on Periscope user being
 			// granted, if periscope user is to be created.
@@ -193,6 +197,7 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 			instance := obj.(*dbv1beta1.DbConfig)
 			instance.Status.Postgres.Status = status
 			instance.Status.Postgres.Connection = *conn
+			instance.Status.Postgres.ReplicaConnections = replicaConns
 			// I don't think dbconfig will ever get granted status (since periscopestatus is the status of periscope postgresuser obj,
 			// not the postgres database shareduser status that gets updated by periscopeuser component).
 			if instance.Status.Postgres.SharedUsers.Periscope != dbv1beta1.StatusGranted {
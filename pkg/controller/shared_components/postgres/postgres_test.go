@@ -274,6 +274,30 @@ var _ = Describe("Postgres Shared Component", func() {
 			Expect(rds.Spec.InstanceID).To(Equal(""))
 		})
 
+		It("passes read replicas through to the RDS database", func() {
+			dbconfig.Spec.Postgres.Mode = "Exclusive"
+			dbconfig.Spec.Postgres.RDS = &dbv1beta1.RDSInstanceSpec{
+				MaintenanceWindow: "Mon:00:00-Mon:01:00",
+				ReadReplicas:      []dbv1beta1.RDSReadReplicaSpec{{Name: "reporting"}},
+			}
+			existing := &dbv1beta1.RDSInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
+				Status: dbv1beta1.RDSInstanceStatus{
+					Status:             dbv1beta1.StatusReady,
+					Connection:         dbv1beta1.PostgresConnection{Host: "foo-dev.rds.aws"},
+					ReplicaConnections: []dbv1beta1.PostgresConnection{{Host: "foo-dev-reporting.rds.aws"}},
+				},
+			}
+			ctx.Client = fake.NewFakeClient(dbconfig, pqdb, existing)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			rds := &dbv1beta1.RDSInstance{}
+			err := ctx.Get(context.Background(), types.NamespacedName{Name: "foo-dev", Namespace: "summon-dev"}, rds)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rds.Spec.ReadReplicas).To(HaveLen(1))
+			Expect(pqdb.Status.AdminReplicaConnections).To(Equal([]dbv1beta1.PostgresConnection{{Host: "foo-dev-reporting.rds.aws"}}))
+		})
+
 		It("creates a periscope user for a RDS database", func() {
 			dbconfig.Spec.Postgres.Mode = "Exclusive"
 			dbconfig.Spec.Postgres.RDS = &dbv1beta1.RDSInstanceSpec{
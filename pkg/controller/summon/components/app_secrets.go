@@ -87,6 +87,16 @@ func (comp *appSecretComponent) WatchMap(obj handler.MapObject, c client.Client)
 	return requests, nil
 }
 
+func (_ *appSecretComponent) DependsOn() []components.Component {
+	return append(specDependencies(),
+		&postgresComponent{},
+		&rabbitmqVhostComponent{},
+		&secretKeyComponent{},
+		&iamUserComponent{},
+		&newMockCarServerTenantComponent{},
+	)
+}
+
 func (_ *appSecretComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 
@@ -148,6 +158,11 @@ func (comp *appSecretComponent) Reconcile(ctx *components.ComponentContext) (com
 
 	// Set up dynamic-y values.
 	appSecretsData["DATABASE_URL"] = fmt.Sprintf("postgis://%s:%s@%s/%s", postgresConnection.Username, postgresPassword, postgresConnection.Host, postgresConnection.Database)
+	if len(instance.Status.PostgresReplicaConnections) > 0 {
+		// Replicas use the same user as the primary, so the password is the same too.
+		replicaConnection := instance.Status.PostgresReplicaConnections[0]
+		appSecretsData["DATABASE_REPLICA_URL"] = fmt.Sprintf("postgis://%s:%s@%s/%s", replicaConnection.Username, postgresPassword, replicaConnection.Host, replicaConnection.Database)
+	}
 	appSecretsData["OUTBOUNDSMS_URL"] = fmt.Sprintf("https://%s.prod.ridecell.io/outbound-sms", instance.Name)
 	appSecretsData["SMS_WEBHOOK_URL"] = fmt.Sprintf("https://%s.ridecell.us/sms/receive/", instance.Name)
 	appSecretsData["CELERY_BROKER_URL"] = fmt.Sprintf("pyamqp://%s:%s@%s/%s?ssl=true", rabbitmqConnection.Username, rabbitmqPassword, rabbitmqConnection.Host, rabbitmqConnection.Vhost)
@@ -17,18 +17,22 @@ limitations under the License.
 package components
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 )
 
 const templatePath = "db/rdssnapshot.yml.tpl"
+const postgresBackupTemplatePath = "db/postgresbackup.yml.tpl"
 
 type backupComponent struct{}
 
@@ -39,6 +43,7 @@ func NewBackup() *backupComponent {
 func (comp *backupComponent) WatchTypes() []runtime.Object {
 	return []runtime.Object{
 		&dbv1beta1.RDSSnapshot{},
+		&dbv1beta1.PostgresBackup{},
 	}
 }
 
@@ -61,62 +66,111 @@ func (comp *backupComponent) Reconcile(ctx *components.ComponentContext) (compon
 		return components.Result{}, errors.Wrap(err, "backup: failed to get postgresdatabase object")
 	}
 
-	// Exit early if versions match
-	// Exit early if there is no RDS instance
-	if instance.Status.BackupVersion == instance.Spec.Version || fetchPostgresDB.Status.RDSInstanceID == "" {
-		return components.Result{StatusModifier: func(obj runtime.Object) error {
-			instance := obj.(*summonv1beta1.SummonPlatform)
-			instance.Status.Status = summonv1beta1.StatusMigrating
-			instance.Status.BackupVersion = instance.Spec.Version
-			return nil
-		}}, nil
+	// Hold new versions until the next maintenance window, this is the first step of a version change.
+	if instance.Status.BackupVersion != instance.Spec.Version {
+		now := time.Now()
+		wait, err := maintenanceWindowWait(instance, now)
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "backup")
+		}
+		if wait > 0 {
+			opensAt := now.Add(wait).Truncate(time.Minute)
+			ctx.Eventf(corev1.EventTypeNormal, "WaitingForWindow", "Version %s is waiting for the next maintenance window at %s", instance.Spec.Version, opensAt.Format(time.UnixDate))
+			return components.Result{RequeueAfter: wait, StatusModifier: waitingForWindow(instance.Spec.Version, opensAt)}, nil
+		}
 	}
 
-	// Data to be copied over to template
-	extra := map[string]interface{}{}
-	extra["rdsInstanceName"] = fetchPostgresDB.Status.RDSInstanceID
-
-	var existing *dbv1beta1.RDSSnapshot
-	_, _, err = ctx.CreateOrUpdate(templatePath, extra, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*dbv1beta1.RDSSnapshot)
-		existing = existingObj.(*dbv1beta1.RDSSnapshot)
-		// Copy the Spec over.
-		existing.Spec = goal.Spec
-		return nil
-	})
-	if err != nil {
-		return components.Result{}, errors.Wrap(err, "backup: failed to create or update rds snapshot")
+	res, err := comp.backup(ctx, instance, fetchPostgresDB)
+	if len(instance.Spec.MaintenanceWindows) > 0 || conditions.Find(instance.Status.Conditions, summonv1beta1.ConditionMaintenanceWindowOpen) != nil {
+		res.StatusModifier = windowOpen(res.StatusModifier)
 	}
+	return res, err
+}
 
-	if !*instance.Spec.Backup.WaitUntilReady {
-		return components.Result{StatusModifier: func(obj runtime.Object) error {
-			instance := obj.(*summonv1beta1.SummonPlatform)
-			instance.Status.Status = summonv1beta1.StatusMigrating
-			instance.Status.BackupVersion = instance.Spec.Version
+// Create the backup for a version change and wait for it if needed.
+func (comp *backupComponent) backup(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform, fetchPostgresDB *dbv1beta1.PostgresDatabase) (components.Result, error) {
+	// Exit early if versions match
+	// Exit early if there is nothing to back up to, no RDS instance and no bucket for a logical backup
+	if instance.Status.BackupVersion == instance.Spec.Version || (fetchPostgresDB.Status.RDSInstanceID == "" && instance.Spec.Backup.BucketName == "") {
+		return components.Result{StatusModifier: backupComplete}, nil
+	}
+
+	// Snapshot the RDS instance if the database has its own, otherwise pg_dump just this database.
+	var err error
+	var kind, name, status, message string
+	if fetchPostgresDB.Status.RDSInstanceID != "" {
+		// Data to be copied over to template
+		extra := map[string]interface{}{}
+		extra["rdsInstanceName"] = fetchPostgresDB.Status.RDSInstanceID
+
+		var existing *dbv1beta1.RDSSnapshot
+		_, _, err = ctx.CreateOrUpdate(templatePath, extra, func(goalObj, existingObj runtime.Object) error {
+			goal := goalObj.(*dbv1beta1.RDSSnapshot)
+			existing = existingObj.(*dbv1beta1.RDSSnapshot)
+			// Copy the Spec over.
+			existing.Spec = goal.Spec
 			return nil
-		}}, nil
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "backup: failed to create or update rds snapshot")
+		}
+		kind, name, status, message = "rdssnapshot", existing.Name, existing.Status.Status, existing.Status.Message
+	} else {
+		var existing *dbv1beta1.PostgresBackup
+		_, _, err = ctx.CreateOrUpdate(postgresBackupTemplatePath, nil, func(goalObj, existingObj runtime.Object) error {
+			goal := goalObj.(*dbv1beta1.PostgresBackup)
+			existing = existingObj.(*dbv1beta1.PostgresBackup)
+			// Copy the Spec over.
+			existing.Spec = goal.Spec
+			return nil
+		})
+		if err != nil {
+			return components.Result{}, errors.Wrap(err, "backup: failed to create or update postgres backup")
+		}
+		kind, name, status, message = "postgresbackup", existing.Name, existing.Status.Status, existing.Status.Message
 	}
 
-	if existing.Status.Status == dbv1beta1.StatusError {
-		return components.Result{}, errors.Wrapf(err, "backup: rdssnapshot %s is in an error state", existing.Name)
+	if !*instance.Spec.Backup.WaitUntilReady {
+		return components.Result{StatusModifier: backupComplete}, nil
 	}
 
-	// We can just return at this point.
-	// When the rdssnapshot is finished it will trigger this component to reconcile.
-	if existing.Status.Status == dbv1beta1.StatusCreating {
-		return components.Result{StatusModifier: setStatus(summonv1beta1.StatusCreatingBackup)}, nil
+	if status == dbv1beta1.StatusError {
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			components.SetCondition(obj, summonv1beta1.ConditionBackupReady, conditions.ConditionFalse, dbv1beta1.StatusError, message)
+			return nil
+		}}, errors.Errorf("backup: %s %s is in an error state: %s", kind, name, message)
 	}
 
-	if existing.Status.Status == dbv1beta1.StatusReady {
+	// We can just return at this point.
+	// When the backup is finished it will trigger this component to reconcile.
+	if status == dbv1beta1.StatusCreating {
 		return components.Result{StatusModifier: func(obj runtime.Object) error {
 			instance := obj.(*summonv1beta1.SummonPlatform)
-			instance.Status.Status = summonv1beta1.StatusMigrating
-			instance.Status.BackupVersion = instance.Spec.Version
+			instance.Status.Status = summonv1beta1.StatusCreatingBackup
+			components.SetCondition(obj, summonv1beta1.ConditionBackupReady, conditions.ConditionFalse, dbv1beta1.StatusCreating, fmt.Sprintf("waiting for %s %s", kind, name))
 			return nil
 		}}, nil
 	}
 
+	if status == dbv1beta1.StatusReady {
+		if kind == "rdssnapshot" {
+			ctx.Eventf(corev1.EventTypeNormal, "SnapshotReady", "RDS snapshot %s for version %s is ready", name, instance.Spec.Version)
+		} else {
+			ctx.Eventf(corev1.EventTypeNormal, "BackupReady", "Postgres backup %s for version %s is ready", name, instance.Spec.Version)
+		}
+		return components.Result{StatusModifier: backupComplete}, nil
+	}
+
 	// Unknown status, reqeueue
 	// Likely new object with no status yet set
 	return components.Result{RequeueAfter: time.Second * 10}, nil
 }
+
+// StatusModifier for when the backup is finished (or not needed), moving on to migrations.
+func backupComplete(obj runtime.Object) error {
+	instance := obj.(*summonv1beta1.SummonPlatform)
+	instance.Status.Status = summonv1beta1.StatusMigrating
+	instance.Status.BackupVersion = instance.Spec.Version
+	components.SetCondition(obj, summonv1beta1.ConditionBackupReady, conditions.ConditionTrue, dbv1beta1.StatusReady, "")
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
@@ -120,6 +121,23 @@ var _ = Describe("SummonPlatform backup Component", func() {
 		Expect(fetchRDSSnapshot.Spec.RDSInstanceID).To(Equal(postgresDatabase.Status.RDSInstanceID))
 	})
 
+	It("errors when the snapshot failed", func() {
+		rdsSnapshot := &dbv1beta1.RDSSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo-dev-1.2.3",
+				Namespace: instance.Namespace,
+			},
+			Status: dbv1beta1.RDSSnapshotStatus{
+				Status:  dbv1beta1.StatusError,
+				Message: "snapshot quota exceeded",
+			},
+		}
+		ctx.Client = fake.NewFakeClient(postgresDatabase, rdsSnapshot)
+
+		_, err := comp.Reconcile(ctx)
+		Expect(err).To(MatchError("backup: rdssnapshot foo-dev-1.2.3 is in an error state: snapshot quota exceeded"))
+	})
+
 	It("does not wait until snapshot is ready", func() {
 		falseBool := false
 		instance.Spec.Backup.WaitUntilReady = &falseBool
@@ -165,4 +183,103 @@ var _ = Describe("SummonPlatform backup Component", func() {
 		Expect(fetchRDSSnapshot.Spec.TTL).To(Equal(instance.Spec.Backup.TTL))
 		Expect(fetchRDSSnapshot.Spec.RDSInstanceID).To(Equal(postgresDatabase.Status.RDSInstanceID))
 	})
+
+	Context("without an RDS instance", func() {
+		BeforeEach(func() {
+			postgresDatabase.Status.RDSInstanceID = ""
+		})
+
+		It("skips the backup without a bucket", func() {
+			ctx.Client = fake.NewFakeClient(postgresDatabase)
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			fetchBackup := &dbv1beta1.PostgresBackup{}
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-1-2-3", Namespace: instance.Namespace}, fetchBackup)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("waits for a logical backup", func() {
+			instance.Spec.Backup.BucketName = "ridecell-backups"
+			ctx.Client = fake.NewFakeClient(postgresDatabase)
+			Expect(comp).To(ReconcileContext(ctx))
+
+			fetchBackup := &dbv1beta1.PostgresBackup{}
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-1-2-3", Namespace: instance.Namespace}, fetchBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetchBackup.Spec.Database).To(Equal("foo-dev"))
+			Expect(fetchBackup.Spec.BucketName).To(Equal("ridecell-backups"))
+			Expect(fetchBackup.Spec.TTL).To(Equal(instance.Spec.Backup.TTL))
+			Expect(instance.Status.BackupVersion).ToNot(Equal(instance.Spec.Version))
+
+			fetchBackup.Status.Status = dbv1beta1.StatusCreating
+			err = ctx.Client.Update(ctx.Context, fetchBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusCreatingBackup))
+
+			fetchBackup.Status.Status = dbv1beta1.StatusReady
+			err = ctx.Client.Update(ctx.Context, fetchBackup)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			Expect(instance.Status.BackupVersion).To(Equal(instance.Spec.Version))
+		})
+	})
+
+	Context("with a maintenance window", func() {
+		BeforeEach(func() {
+			// A daily window starting two hours from now, so it is always closed during the test.
+			start := time.Now().UTC().Add(2 * time.Hour)
+			instance.Spec.MaintenanceWindows = []summonv1beta1.MaintenanceWindow{
+				{Window: start.Format("15:04") + "-" + start.Add(time.Hour).Format("15:04")},
+			}
+			falseBool := false
+			instance.Spec.Backup.WaitUntilReady = &falseBool
+			instance.Status.BackupVersion = "1.2.2"
+			instance.Status.MigrateVersion = "1.2.2"
+			ctx.Client = fake.NewFakeClient(postgresDatabase)
+		})
+
+		It("holds a version change until the window opens", func() {
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically("~", 2*time.Hour, time.Minute))
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusWaitingForWindow))
+			Expect(instance.Status.BackupVersion).To(Equal("1.2.2"))
+			cond := conditions.Find(instance.Status.Conditions, summonv1beta1.ConditionMaintenanceWindowOpen)
+			Expect(cond).ToNot(BeNil())
+			Expect(cond.Status).To(Equal(conditions.ConditionFalse))
+			Expect(conditions.Find(instance.Status.Conditions, summonv1beta1.ConditionBackupReady)).To(BeNil())
+			fetchRDSSnapshot := &dbv1beta1.RDSSnapshot{}
+			err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-1-2-3", Namespace: instance.Namespace}, fetchRDSSnapshot)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("starts the version change inside the window", func() {
+			start := time.Now().UTC().Add(-time.Hour)
+			instance.Spec.MaintenanceWindows = append(instance.Spec.MaintenanceWindows, summonv1beta1.MaintenanceWindow{
+				Window: start.Format("15:04") + "-" + start.Add(2*time.Hour).Format("15:04"),
+			})
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			Expect(instance.Status.BackupVersion).To(Equal("1.2.3"))
+			Expect(conditions.IsTrue(instance.Status.Conditions, summonv1beta1.ConditionMaintenanceWindowOpen)).To(BeTrue())
+		})
+
+		It("starts the version change with the skip annotation", func() {
+			instance.Annotations = map[string]string{"ridecell.io/skip-maintenance-window": "1.2.3"}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			Expect(instance.Status.BackupVersion).To(Equal("1.2.3"))
+		})
+
+		It("doesn't hold a new instance", func() {
+			instance.Status.BackupVersion = ""
+			instance.Status.MigrateVersion = ""
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			Expect(instance.Status.BackupVersion).To(Equal("1.2.3"))
+		})
+	})
 })
@@ -0,0 +1,300 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+// Canary settings used if none are set in the spec.
+const defaultCanaryTrafficPercent = 10
+const defaultCanaryDuration = 10 * time.Minute
+
+// How often the canary health is re-checked while waiting on the health gate.
+const canaryCheckInterval = 30 * time.Second
+
+// The stable Deployment which waits for the canary to be promoted.
+const canaryStableTemplate = "web/deployment.yml.tpl"
+
+var prometheusHTTPClient = metrics.InstrumentHTTPClient("prometheus", &http.Client{Timeout: 30 * time.Second})
+
+// Returns true if the current Spec.Version goes through a canary before the web Deployment is updated.
+func canaryEnabled(instance *summonv1beta1.SummonPlatform) bool {
+	lastGood := instance.Status.Deploy.LastGoodVersion
+	return instance.Spec.Rollout.Strategy == summonv1beta1.RolloutStrategyCanary && lastGood != "" && lastGood != instance.Spec.Version
+}
+
+// Returns true if the canary for the current version ended in the given phase.
+func canaryPhase(instance *summonv1beta1.SummonPlatform, phase string) bool {
+	return instance.Status.Canary.Version == instance.Spec.Version && instance.Status.Canary.Phase == phase
+}
+
+// Returns true if the web Deployment is held at the last known good version until the canary is promoted.
+func canaryPending(instance *summonv1beta1.SummonPlatform) bool {
+	return canaryEnabled(instance) && !canaryPhase(instance, summonv1beta1.CanaryPromoted)
+}
+
+// Returns true if canary pods for the current version should be running and receiving traffic.
+func canaryActive(instance *summonv1beta1.SummonPlatform) bool {
+	return canaryPending(instance) && !canaryPhase(instance, summonv1beta1.CanaryAborted)
+}
+
+// Returns the version the web Deployment stays at while the canary runs, or "" if it should be updated as normal.
+func canaryStableVersion(instance *summonv1beta1.SummonPlatform) string {
+	if !canaryPending(instance) {
+		return ""
+	}
+	return instance.Status.Deploy.LastGoodVersion
+}
+
+// The error reported while the canary for the current version is aborted. This needs to stay the same
+// across reconciles so the error notification is only sent once.
+func canaryAbortedError(instance *summonv1beta1.SummonPlatform) error {
+	return errors.Errorf("canary: aborted version %s, web is still running %s: %s", instance.Spec.Version, instance.Status.Deploy.LastGoodVersion, instance.Status.Canary.Message)
+}
+
+type canaryComponent struct {
+	deploymentTemplatePath string
+	serviceTemplatePath    string
+}
+
+func NewCanary(deploymentTemplatePath, serviceTemplatePath string) *canaryComponent {
+	return &canaryComponent{deploymentTemplatePath: deploymentTemplatePath, serviceTemplatePath: serviceTemplatePath}
+}
+
+func (comp *canaryComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{
+		&appsv1.Deployment{},
+		&corev1.Service{},
+	}
+}
+
+func (_ *canaryComponent) IsReconcilable(ctx *components.ComponentContext) bool {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+	// Same requirements as the web Deployment.
+	if instance.Status.PullSecretStatus != secretsv1beta1.StatusReady {
+		return false
+	}
+	if instance.Status.PostgresStatus != dbv1beta1.StatusReady {
+		return false
+	}
+	return instance.Status.Status == summonv1beta1.StatusDeploying || instance.Status.Status == summonv1beta1.StatusReady
+}
+
+func (comp *canaryComponent) Reconcile(ctx *components.ComponentContext) (components.Result, error) {
+	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
+
+	if !canaryActive(instance) {
+		err := comp.cleanup(ctx, instance)
+		if err != nil {
+			return components.Result{}, err
+		}
+		if canaryPending(instance) {
+			// Aborted, keep reporting it until a new version is deployed.
+			return components.Result{}, canaryAbortedError(instance)
+		}
+		return components.Result{}, nil
+	}
+
+	// Only start or check on a canary during a deploy.
+	if instance.Status.Status != summonv1beta1.StatusDeploying {
+		return components.Result{}, nil
+	}
+
+	version := instance.Spec.Version
+	canary := instance.Status.Canary
+	now := time.Now()
+	if canary.Version != version {
+		// New version, start a fresh canary.
+		canary = summonv1beta1.CanaryStatus{
+			Version:   version,
+			Phase:     summonv1beta1.CanaryProgressing,
+			StartedAt: now.Format(time.UnixDate),
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "CanaryStarted", "Started canary of version %s with %d%% of web traffic", version, instance.Spec.Rollout.Canary.TrafficPercent)
+	}
+
+	extra, err := deploymentExtra(ctx, instance)
+	if err != nil {
+		return components.Result{Requeue: true}, err
+	}
+	res, _, err := ctx.CreateOrUpdate(comp.deploymentTemplatePath, extra, func(goalObj, existingObj runtime.Object) error {
+		goal := goalObj.(*appsv1.Deployment)
+		existing := existingObj.(*appsv1.Deployment)
+		existing.Spec = goal.Spec
+		return nil
+	})
+	if err != nil {
+		return res, errors.Wrapf(err, "canary: failed to update template %s", comp.deploymentTemplatePath)
+	}
+	res, _, err = ctx.CreateOrUpdate(comp.serviceTemplatePath, nil, func(goalObj, existingObj runtime.Object) error {
+		goal := goalObj.(*corev1.Service)
+		existing := existingObj.(*corev1.Service)
+		// Special case: Services mutate the ClusterIP value in the Spec and it should be preserved.
+		goal.Spec.ClusterIP = existing.Spec.ClusterIP
+		existing.Spec = goal.Spec
+		return nil
+	})
+	if err != nil {
+		return res, errors.Wrapf(err, "canary: failed to update template %s", comp.serviceTemplatePath)
+	}
+
+	deployment := &appsv1.Deployment{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: instance.Name + "-web-canary", Namespace: instance.Namespace}, deployment)
+	if err != nil {
+		return components.Result{Requeue: true}, errors.Wrapf(err, "canary: unable to get canary Deployment")
+	}
+	ready := deployment.Spec.Replicas != nil && deployment.Status.UpdatedReplicas == *deployment.Spec.Replicas && deployment.Status.ReadyReplicas == *deployment.Spec.Replicas && deployment.Status.UnavailableReplicas == 0
+	if !ready {
+		startedAt, err := time.Parse(time.UnixDate, canary.StartedAt)
+		if err == nil && now.Sub(startedAt) > instance.Spec.Rollback.ProgressDeadline.Duration {
+			return comp.abort(ctx, instance, canary, fmt.Sprintf("canary did not become ready within %s", instance.Spec.Rollback.ProgressDeadline.Duration))
+		}
+		// Pods going unready restarts the health gate.
+		canary.HealthySince = ""
+		return components.Result{RequeueAfter: canaryCheckInterval, StatusModifier: setCanaryStatus(canary)}, nil
+	}
+
+	if query := instance.Spec.Rollout.Canary.HealthQuery; query != "" {
+		matches, err := queryPrometheus(query)
+		if err != nil {
+			return components.Result{RequeueAfter: canaryCheckInterval, StatusModifier: setCanaryStatus(canary)}, errors.Wrapf(err, "canary: unable to check health query")
+		}
+		if matches > 0 {
+			return comp.abort(ctx, instance, canary, fmt.Sprintf("health query %q returned %d results", query, matches))
+		}
+	}
+
+	healthySince, err := time.Parse(time.UnixDate, canary.HealthySince)
+	if err != nil {
+		healthySince = now
+		canary.HealthySince = now.Format(time.UnixDate)
+	}
+	remaining := instance.Spec.Rollout.Canary.Duration.Duration - now.Sub(healthySince)
+	if remaining > 0 {
+		if remaining > canaryCheckInterval && instance.Spec.Rollout.Canary.HealthQuery != "" {
+			remaining = canaryCheckInterval
+		}
+		return components.Result{RequeueAfter: remaining, StatusModifier: setCanaryStatus(canary)}, nil
+	}
+
+	// Passed the health gate, let the web Deployment update to the new version.
+	ctx.Eventf(corev1.EventTypeNormal, "CanaryPromoted", "Promoted canary of version %s", version)
+	canary.Phase = summonv1beta1.CanaryPromoted
+	return components.Result{Requeue: true, StatusModifier: setCanaryStatus(canary)}, nil
+}
+
+// Stops the canary, leaving the web Deployment at the last known good version.
+func (comp *canaryComponent) abort(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform, canary summonv1beta1.CanaryStatus, reason string) (components.Result, error) {
+	canary.Phase = summonv1beta1.CanaryAborted
+	canary.Message = reason
+	aborted := instance.DeepCopy()
+	aborted.Status.Canary = canary
+	abortedErr := canaryAbortedError(aborted)
+	ctx.Eventf(corev1.EventTypeWarning, "CanaryAborted", "%s", abortedErr)
+
+	err := comp.cleanup(ctx, instance)
+	if err != nil {
+		return components.Result{StatusModifier: setCanaryStatus(canary)}, err
+	}
+	return components.Result{StatusModifier: setCanaryStatus(canary)}, abortedErr
+}
+
+// Deletes the canary Deployment and Service if they exist.
+func (_ *canaryComponent) cleanup(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) error {
+	name := types.NamespacedName{Name: instance.Name + "-web-canary", Namespace: instance.Namespace}
+	for _, obj := range []runtime.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+		err := ctx.Get(ctx.Context, name, obj)
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return errors.Wrapf(err, "canary: failed to get %s", name.Name)
+		}
+		err = ctx.Delete(ctx.Context, obj)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "canary: failed to delete %s", name.Name)
+		}
+	}
+	return nil
+}
+
+func setCanaryStatus(canary summonv1beta1.CanaryStatus) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Canary = canary
+		return nil
+	}
+}
+
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// Runs an instant query against the Prometheus at PROMETHEUS_URL and returns how many series it matched.
+func queryPrometheus(query string) (int, error) {
+	promURL := os.Getenv("PROMETHEUS_URL")
+	if promURL == "" {
+		return 0, errors.New("PROMETHEUS_URL is not set")
+	}
+	resp, err := prometheusHTTPClient.PostForm(strings.TrimRight(promURL, "/")+"/api/v1/query", url.Values{"query": {query}})
+	if err != nil {
+		return 0, errors.Wrap(err, "error querying prometheus")
+	}
+	defer resp.Body.Close()
+
+	body := &prometheusQueryResponse{}
+	err = json.NewDecoder(resp.Body).Decode(body)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error decoding prometheus response (HTTP %d)", resp.StatusCode)
+	}
+	if body.Status != "success" {
+		return 0, errors.Errorf("prometheus query failed: %s", body.Error)
+	}
+	if body.Data.ResultType != "vector" && body.Data.ResultType != "matrix" {
+		return 0, errors.Errorf("prometheus query returned a %s, expected a vector", body.Data.ResultType)
+	}
+	results := []json.RawMessage{}
+	err = json.Unmarshal(body.Data.Result, &results)
+	if err != nil {
+		return 0, errors.Wrap(err, "error decoding prometheus results")
+	}
+	return len(results), nil
+}
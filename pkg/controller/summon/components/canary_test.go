@@ -0,0 +1,250 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("SummonPlatform canary Component", func() {
+	var configMap *corev1.ConfigMap
+	var appSecrets *corev1.Secret
+
+	BeforeEach(func() {
+		instance.Status.Status = summonv1beta1.StatusDeploying
+		instance.Status.PullSecretStatus = secretsv1beta1.StatusReady
+		instance.Status.PostgresStatus = dbv1beta1.StatusReady
+		instance.Status.Deploy.LastGoodVersion = "1.2.2"
+		instance.Spec.Replicas.Web = intp(2)
+		instance.Spec.Rollback.ProgressDeadline = metav1.Duration{Duration: 15 * time.Minute}
+		instance.Spec.Rollout.Strategy = summonv1beta1.RolloutStrategyCanary
+		instance.Spec.Rollout.Canary.TrafficPercent = 10
+		instance.Spec.Rollout.Canary.Replicas = intp(1)
+		instance.Spec.Rollout.Canary.Duration = metav1.Duration{Duration: 10 * time.Minute}
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-config", Namespace: "summon-dev"},
+			Data:       map[string]string{"summon-platform.yml": "{}\n"},
+		}
+		appSecrets = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev.app-secrets", Namespace: "summon-dev"},
+			Data:       map[string][]byte{"filler": []byte("test")},
+		}
+		ctx.Client = fake.NewFakeClient(configMap, appSecrets)
+	})
+
+	// A canary Deployment which has finished rolling out.
+	readyCanary := func() *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo-dev-web-canary", Namespace: "summon-dev"},
+			Spec:       appsv1.DeploymentSpec{Replicas: intp(1)},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: 1, ReadyReplicas: 1},
+		}
+	}
+
+	It("does nothing without the canary strategy", func() {
+		instance.Spec.Rollout.Strategy = ""
+		comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+		Expect(comp).To(ReconcileContext(ctx))
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web-canary", Namespace: "summon-dev"}, deployment)
+		Expect(err).To(HaveOccurred())
+		Expect(instance.Status.Canary.Version).To(BeEmpty())
+	})
+
+	It("starts a canary of the new version", func() {
+		comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Canary.Version).To(Equal("1.2.3"))
+		Expect(instance.Status.Canary.Phase).To(Equal(summonv1beta1.CanaryProgressing))
+		Expect(instance.Status.Canary.StartedAt).ToNot(BeEmpty())
+
+		deployment := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web-canary", Namespace: "summon-dev"}, deployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+		Expect(deployment.Spec.Template.Labels["app.kubernetes.io/name"]).To(Equal("web"))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.3"))
+
+		service := &corev1.Service{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web-canary", Namespace: "summon-dev"}, service)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service.Spec.Selector).To(HaveKeyWithValue("app.kubernetes.io/instance", "foo-dev-web-canary"))
+	})
+
+	It("keeps web on the last good version and splits the ingress traffic", func() {
+		Expect(summoncomponents.NewDeployment("web/deployment.yml.tpl", nil)).To(ReconcileContext(ctx))
+		Expect(summoncomponents.NewIngress("web/ingress.yml.tpl")).To(ReconcileContext(ctx))
+
+		web := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web", Namespace: "summon-dev"}, web)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(web.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+
+		ingress := &extv1beta1.Ingress{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web", Namespace: "summon-dev"}, ingress)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Annotations["traefik.ingress.kubernetes.io/service-weights"]).To(Equal("foo-dev-web-canary: 10%\n"))
+		paths := ingress.Spec.Rules[0].HTTP.Paths
+		Expect(paths).To(HaveLen(2))
+		Expect(paths[1].Backend.ServiceName).To(Equal("foo-dev-web-canary"))
+
+		// Once promoted the weights go away.
+		instance.Status.Canary = summonv1beta1.CanaryStatus{Version: "1.2.3", Phase: summonv1beta1.CanaryPromoted}
+		Expect(summoncomponents.NewIngress("web/ingress.yml.tpl")).To(ReconcileContext(ctx))
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web", Namespace: "summon-dev"}, ingress)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ingress.Annotations).ToNot(HaveKey("traefik.ingress.kubernetes.io/service-weights"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths).To(HaveLen(1))
+	})
+
+	It("promotes a canary which stays healthy", func() {
+		ctx.Client = fake.NewFakeClient(configMap, appSecrets, readyCanary())
+		instance.Status.Canary = summonv1beta1.CanaryStatus{
+			Version:      "1.2.3",
+			Phase:        summonv1beta1.CanaryProgressing,
+			StartedAt:    time.Now().Add(-15 * time.Minute).Format(time.UnixDate),
+			HealthySince: time.Now().Add(-11 * time.Minute).Format(time.UnixDate),
+		}
+
+		comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Canary.Phase).To(Equal(summonv1beta1.CanaryPromoted))
+
+		// Web can now update, and the canary gets cleaned up.
+		Expect(summoncomponents.NewDeployment("web/deployment.yml.tpl", nil)).To(ReconcileContext(ctx))
+		web := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web", Namespace: "summon-dev"}, web)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(web.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.3"))
+
+		Expect(comp).To(ReconcileContext(ctx))
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web-canary", Namespace: "summon-dev"}, &appsv1.Deployment{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("waits out the health gate", func() {
+		ctx.Client = fake.NewFakeClient(configMap, appSecrets, readyCanary())
+		instance.Status.Canary = summonv1beta1.CanaryStatus{
+			Version:   "1.2.3",
+			Phase:     summonv1beta1.CanaryProgressing,
+			StartedAt: time.Now().Add(-time.Minute).Format(time.UnixDate),
+		}
+
+		comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+		res, err := comp.Reconcile(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically("~", 10*time.Minute, time.Minute))
+		Expect(res.StatusModifier(instance)).To(Succeed())
+		Expect(instance.Status.Canary.Phase).To(Equal(summonv1beta1.CanaryProgressing))
+		Expect(instance.Status.Canary.HealthySince).ToNot(BeEmpty())
+	})
+
+	It("aborts a canary which never becomes ready", func() {
+		instance.Status.Canary = summonv1beta1.CanaryStatus{
+			Version:   "1.2.3",
+			Phase:     summonv1beta1.CanaryProgressing,
+			StartedAt: time.Now().Add(-20 * time.Minute).Format(time.UnixDate),
+		}
+
+		comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+		res, err := comp.Reconcile(ctx)
+		Expect(err).To(MatchError("canary: aborted version 1.2.3, web is still running 1.2.2: canary did not become ready within 15m0s"))
+		Expect(res.StatusModifier(instance)).To(Succeed())
+		Expect(instance.Status.Canary.Phase).To(Equal(summonv1beta1.CanaryAborted))
+
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web-canary", Namespace: "summon-dev"}, &appsv1.Deployment{})
+		Expect(err).To(HaveOccurred())
+
+		// And it keeps reporting the same error.
+		_, err = comp.Reconcile(ctx)
+		Expect(err).To(MatchError("canary: aborted version 1.2.3, web is still running 1.2.2: canary did not become ready within 15m0s"))
+	})
+
+	Context("with a health query", func() {
+		var server *httptest.Server
+		var results string
+
+		BeforeEach(func() {
+			results = "[]"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/api/v1/query"))
+				Expect(r.FormValue("query")).To(Equal(`rate(errors{instance="foo-dev-web-canary"}[5m]) > 0.05`))
+				fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": %s}}`, results)
+			}))
+			os.Setenv("PROMETHEUS_URL", server.URL)
+			instance.Spec.Rollout.Canary.HealthQuery = `rate(errors{instance="foo-dev-web-canary"}[5m]) > 0.05`
+			ctx.Client = fake.NewFakeClient(configMap, appSecrets, readyCanary())
+			instance.Status.Canary = summonv1beta1.CanaryStatus{
+				Version:   "1.2.3",
+				Phase:     summonv1beta1.CanaryProgressing,
+				StartedAt: time.Now().Add(-time.Minute).Format(time.UnixDate),
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.Unsetenv("PROMETHEUS_URL")
+		})
+
+		It("keeps checking while the query is empty", func() {
+			comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+			res, err := comp.Reconcile(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(30 * time.Second))
+		})
+
+		It("aborts the canary when the query returns results", func() {
+			results = `[{"metric": {}, "value": [1, "0.2"]}]`
+			comp := summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl")
+			res, err := comp.Reconcile(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("returned 1 results"))
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(instance.Status.Canary.Phase).To(Equal(summonv1beta1.CanaryAborted))
+		})
+	})
+
+	It("holds the deploy status until the canary is promoted", func() {
+		comp := summoncomponents.NewStatus()
+		Expect(comp).To(ReconcileContext(ctx))
+		Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
+		Expect(instance.Status.Deploy.StartedAt).To(BeEmpty())
+	})
+})
@@ -115,26 +115,12 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance.Spec.Config = map[string]summonv1beta1.ConfigValue{}
 	}
 
-	// Fill in defaults.
-	if instance.Spec.Environment == "" {
-		x := instance.Namespace
-		x = strings.TrimPrefix(x, "summon-")
-		instance.Spec.Environment = x
-	}
-	if instance.Spec.Hostname == "" {
-		baseHostname := ".ridecell.us"
-		if instance.Spec.Environment == "uat" || instance.Spec.Environment == "prod" {
-			baseHostname = ".ridecell.com"
-		}
-		instance.Spec.Hostname = instance.Name + baseHostname
-	}
+	// Fill in defaults. The simple ones are shared with the admission webhook.
+	instance.Default()
 	err := comp.replicaDefaults(instance)
 	if err != nil {
 		return components.Result{}, errors.Wrap(err, "error setting replica defaults")
 	}
-	if instance.Spec.PullSecret == "" {
-		instance.Spec.PullSecret = "pull-secret"
-	}
 	if instance.Spec.AwsRegion == "" {
 		instance.Spec.AwsRegion = os.Getenv("AWS_REGION")
 		// If the env var isn't present, assume us-west-2. Mostly for local testing stuff.
@@ -167,10 +153,6 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 		instance.Spec.EnableNewRelic = &val
 	}
 
-	if instance.Spec.MockTenantHardwareType == "" {
-		instance.Spec.MockTenantHardwareType = "OTAKEYS"
-	}
-
 	if instance.Spec.Backup.TTL.Duration == 0 {
 		instance.Spec.Backup.TTL.Duration = time.Hour * 720
 		if instance.Spec.Environment == "dev" || instance.Spec.Environment == "qa" {
@@ -178,6 +160,38 @@ func (comp *defaultsComponent) Reconcile(ctx *components.ComponentContext) (comp
 		}
 	}
 
+	if instance.Spec.Backup.BucketName == "" {
+		instance.Spec.Backup.BucketName = os.Getenv("POSTGRES_BACKUP_BUCKET")
+	}
+
+	if instance.Spec.Rollback.ProgressDeadline.Duration == 0 {
+		instance.Spec.Rollback.ProgressDeadline.Duration = defaultProgressDeadline
+	}
+
+	if instance.Spec.Migrations.RequireApproval == nil {
+		requireApproval := instance.Spec.Environment == "prod"
+		instance.Spec.Migrations.RequireApproval = &requireApproval
+	}
+
+	if instance.Spec.Migrations.MaxRetries == nil {
+		maxRetries := int32(2)
+		instance.Spec.Migrations.MaxRetries = &maxRetries
+	}
+	if instance.Spec.Migrations.RetryBackoff.Duration == 0 {
+		instance.Spec.Migrations.RetryBackoff.Duration = 2 * time.Minute
+	}
+
+	if instance.Spec.Rollout.Canary.TrafficPercent == 0 {
+		instance.Spec.Rollout.Canary.TrafficPercent = defaultCanaryTrafficPercent
+	}
+	if instance.Spec.Rollout.Canary.Replicas == nil {
+		replicas := int32(1)
+		instance.Spec.Rollout.Canary.Replicas = &replicas
+	}
+	if instance.Spec.Rollout.Canary.Duration.Duration == 0 {
+		instance.Spec.Rollout.Canary.Duration.Duration = defaultCanaryDuration
+	}
+
 	if instance.Spec.Backup.WaitUntilReady == nil {
 		prodWaitBool := true
 		instance.Spec.Backup.WaitUntilReady = &prodWaitBool
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+)
+
+// A do-nothing component which depends on whatever it is told to.
+type dependsOnComponent struct {
+	deps []components.Component
+}
+
+func (_ *dependsOnComponent) WatchTypes() []runtime.Object {
+	return []runtime.Object{}
+}
+
+func (_ *dependsOnComponent) IsReconcilable(_ *components.ComponentContext) bool {
+	return true
+}
+
+func (_ *dependsOnComponent) Reconcile(_ *components.ComponentContext) (components.Result, error) {
+	return components.Result{}, nil
+}
+
+func (comp *dependsOnComponent) DependsOn() []components.Component {
+	return comp.deps
+}
+
+var _ = Describe("SummonPlatform component dependencies", func() {
+	It("runs independent top-level components concurrently", func() {
+		defaults := summoncomponents.NewDefaults()
+		autodeploy := summoncomponents.NewAutoDeploy()
+		postgres := summoncomponents.NewPostgres()
+		rabbitmq := summoncomponents.NewRabbitmqVhost("rabbitmq/vhost.yml.tpl")
+		secretKey := summoncomponents.NewSecretKey()
+		appSecret := summoncomponents.NewAppSecret()
+		configMap := summoncomponents.NewConfigMap("configmap.yml.tpl")
+
+		levels, err := components.DependencyLevels([]components.Component{defaults, autodeploy, postgres, rabbitmq, secretKey, appSecret, configMap})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(levels).To(Equal([][]components.Component{
+			{defaults},
+			{autodeploy},
+			{postgres, rabbitmq, secretKey},
+			{appSecret},
+			{configMap},
+		}))
+	})
+
+	It("keeps list order for components without declared dependencies", func() {
+		first := summoncomponents.NewConfigMap("configmap.yml.tpl")
+		second := summoncomponents.NewMigrateWait()
+
+		levels, err := components.DependencyLevels([]components.Component{first, second})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(levels).To(HaveLen(2))
+	})
+
+	It("rejects dependency cycles", func() {
+		first := &dependsOnComponent{}
+		second := summoncomponents.NewConfigMap("configmap.yml.tpl")
+		first.deps = []components.Component{second}
+
+		_, err := components.DependencyLevels([]components.Component{first, second})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("dependency cycle"))
+	})
+})
@@ -29,6 +29,7 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
@@ -65,6 +66,10 @@ func (comp *deploymentComponent) IsReconcilable(ctx *components.ComponentContext
 	if instance.Status.Status == summonv1beta1.StatusReady {
 		return true
 	}
+	// A failed deploy leaves the status in Error, but a rollback might still need to be applied.
+	if instance.Status.Status == summonv1beta1.StatusError && instance.Status.Deploy.FailedVersion != "" {
+		return true
+	}
 	if instance.Status.Status != summonv1beta1.StatusDeploying {
 		return false
 	}
@@ -88,40 +93,29 @@ func (comp *deploymentComponent) Reconcile(ctx *components.ComponentContext) (co
 		return components.Result{}, comp.deleteObject(ctx, instance, "celeryredbeat")
 	}
 
-	// TODO 2020-01-06 After cm+secret merges to just secret, support varying the input names in the component config so comp-dispatch and comp-trip-share can get just the hash of their config.
-	rawAppSecret := &corev1.Secret{}
-	err := ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s.app-secrets", instance.Name), Namespace: instance.Namespace}, rawAppSecret)
+	extra, err := deploymentExtra(ctx, instance)
 	if err != nil {
-		return components.Result{Requeue: true}, errors.Wrapf(err, "deployment: Failed to get appsecrets")
+		return components.Result{Requeue: true}, err
 	}
 
-	config := &corev1.ConfigMap{}
-	err = ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace}, config)
-	if err != nil {
-		return components.Result{Requeue: true}, errors.Wrapf(err, "deployment: unable to get configmap")
-	}
-
-	appSecretsBytes, err := json.Marshal(rawAppSecret.Data)
-	if err != nil {
-		return components.Result{}, errors.Wrapf(err, "deployment: unable to serialize appsecrets")
+	// Render from a copy pinned to the last known good version if this deploy failed and is being rolled back,
+	// or if web is waiting on a canary of the new version to be promoted.
+	renderCtx := ctx
+	rollingBack := false
+	pinnedVersion := ""
+	if version := rollbackVersion(instance); version != "" && isRollbackTemplate(comp.templatePath) {
+		pinnedVersion = version
+		rollingBack = true
+	} else if version := canaryStableVersion(instance); version != "" && comp.templatePath == canaryStableTemplate {
+		pinnedVersion = version
 	}
-	configBytes, err := json.Marshal(config.Data)
-	if err != nil {
-		return components.Result{}, errors.Wrapf(err, "deployment: unable to serialize config")
+	if pinnedVersion != "" {
+		pinned := instance.DeepCopy()
+		pinned.Spec.Version = pinnedVersion
+		renderCtx = ctx.WithTop(pinned)
 	}
 
-	appSecretsHash := comp.hashItem(appSecretsBytes)
-	configMapHash := comp.hashItem(configBytes)
-
-	// Data to be copied over to template
-	extra := map[string]interface{}{}
-	extra["configHash"] = string(configMapHash)
-	extra["appSecretsHash"] = string(appSecretsHash)
-	// Pass debug value
-	_, ok := instance.Spec.Config["DEBUG"]
-	extra["debug"] = bool(ok)
-
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, extra, func(goalObj, existingObj runtime.Object) error {
+	res, op, err := renderCtx.CreateOrUpdate(comp.templatePath, extra, func(goalObj, existingObj runtime.Object) error {
 		goalDeployment, ok := goalObj.(*appsv1.Deployment)
 		if ok {
 			existing := existingObj.(*appsv1.Deployment)
@@ -141,10 +135,51 @@ func (comp *deploymentComponent) Reconcile(ctx *components.ComponentContext) (co
 	if err != nil {
 		return res, errors.Wrapf(err, "deployment: failed to update template %s", comp.templatePath)
 	}
+	if rollingBack && op == controllerutil.OperationResultUpdated {
+		subsystem := strings.Split(comp.templatePath, "/")[0]
+		ctx.Eventf(corev1.EventTypeWarning, "RolledBack", "Rolled back %s to version %s", subsystem, instance.Status.Deploy.LastGoodVersion)
+	}
 	return components.Result{}, nil
 }
 
-func (_ *deploymentComponent) hashItem(data []byte) string {
+// Template data shared by the summon Deployments. The hashes of the app secrets and config make pods restart when either changes.
+func deploymentExtra(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (map[string]interface{}, error) {
+	// TODO 2020-01-06 After cm+secret merges to just secret, support varying the input names in the component config so comp-dispatch and comp-trip-share can get just the hash of their config.
+	rawAppSecret := &corev1.Secret{}
+	err := ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s.app-secrets", instance.Name), Namespace: instance.Namespace}, rawAppSecret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deployment: Failed to get appsecrets")
+	}
+
+	config := &corev1.ConfigMap{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace}, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deployment: unable to get configmap")
+	}
+
+	appSecretsBytes, err := json.Marshal(rawAppSecret.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deployment: unable to serialize appsecrets")
+	}
+	configBytes, err := json.Marshal(config.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "deployment: unable to serialize config")
+	}
+
+	appSecretsHash := hashItem(appSecretsBytes)
+	configMapHash := hashItem(configBytes)
+
+	// Data to be copied over to template
+	extra := map[string]interface{}{}
+	extra["configHash"] = string(configMapHash)
+	extra["appSecretsHash"] = string(appSecretsHash)
+	// Pass debug value
+	_, ok := instance.Spec.Config["DEBUG"]
+	extra["debug"] = bool(ok)
+	return extra, nil
+}
+
+func hashItem(data []byte) string {
 	hash := sha1.Sum(data)
 	encodedHash := hex.EncodeToString(hash[:])
 	return encodedHash
@@ -93,6 +93,36 @@ var _ = Describe("deployment Component", func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("rolls web back to the last good version after a failed deploy", func() {
+		instance.Spec.Rollback.Enabled = true
+		instance.Status.Deploy.LastGoodVersion = "1.2.2"
+		instance.Status.Deploy.FailedVersion = "1.2.3"
+
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-config", instance.Name), Namespace: instance.Namespace},
+			Data:       map[string]string{"summon-platform.yml": "{}\n"},
+		}
+		appSecrets := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s.app-secrets", instance.Name), Namespace: instance.Namespace},
+			Data:       map[string][]byte{"filler": []byte("test")},
+		}
+		ctx.Client = fake.NewFakeClient(appSecrets, configMap)
+
+		Expect(summoncomponents.NewDeployment("web/deployment.yml.tpl", nil)).To(ReconcileContext(ctx))
+		Expect(summoncomponents.NewDeployment("static/deployment.yml.tpl", nil)).To(ReconcileContext(ctx))
+
+		web := &appsv1.Deployment{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-web", Namespace: instance.Namespace}, web)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(web.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.2"))
+
+		// Static isn't rolled back.
+		static := &appsv1.Deployment{}
+		err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-static", Namespace: instance.Namespace}, static)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(static.Spec.Template.Spec.Containers[0].Image).To(Equal("us.gcr.io/ridecell-1/summon:1.2.3"))
+	})
+
 	It("makes sure keys are sorted before hash", func() {
 		comp := summoncomponents.NewDeployment("static/deployment.yml.tpl", nil)
 
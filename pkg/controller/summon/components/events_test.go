@@ -0,0 +1,46 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
+	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
+)
+
+var _ = Describe("SummonPlatform component events", func() {
+	It("records an event when a child object is created", func() {
+		comp := summoncomponents.NewPostgres()
+		Expect(comp).To(ReconcileContext(ctx))
+
+		recorder := ctx.Recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(Equal(corev1.EventTypeNormal + " Created Created PostgresDatabase foo-dev")))
+	})
+
+	It("rate limits identical events", func() {
+		ctx.Eventf(corev1.EventTypeWarning, "Testing", "something broke")
+		ctx.Eventf(corev1.EventTypeWarning, "Testing", "something broke")
+		ctx.Eventf(corev1.EventTypeWarning, "Testing", "something else broke")
+
+		recorder := ctx.Recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(HaveLen(2))
+	})
+})
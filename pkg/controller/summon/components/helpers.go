@@ -19,6 +19,7 @@ package components
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	summonv1beta "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
@@ -31,3 +32,18 @@ func setStatus(status string) components.StatusModifier {
 		return nil
 	}
 }
+
+// Helper function for use as a StatusModifier which sets the main status and one component condition.
+func setStatusWithCondition(status string, conditionType string, conditionStatus conditions.ConditionStatus, reason, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta.SummonPlatform)
+		instance.Status.Status = status
+		components.SetCondition(obj, conditionType, conditionStatus, reason, message)
+		return nil
+	}
+}
+
+// Dependencies for components which only need the spec defaulted and the version resolved before running.
+func specDependencies() []components.Component {
+	return []components.Component{&defaultsComponent{}, &AutoDeployComponent{}}
+}
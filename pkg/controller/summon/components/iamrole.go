@@ -43,6 +43,10 @@ func (comp *iamRoleComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *iamRoleComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *iamRoleComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	//instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	// Check on the UseIAM Role flag
@@ -69,6 +73,8 @@ func (comp *iamRoleComponent) Reconcile(ctx *components.ComponentContext) (compo
 	extra["optimusBucketName"] = instance.Spec.OptimusBucketName
 	extra["mivBucket"] = fmt.Sprintf("ridecell-%s-miv", instance.Name)
 	extra["assumeRolePolicyDocument"] = os.Getenv("AWS_ASSUME_ROLE_POLICY_DOCUMENT")
+	// When the cluster has an OIDC provider, let the IAMRole controller manage the ServiceAccount trust.
+	extra["oidcProviderArn"] = os.Getenv("OIDC_PROVIDER_ARN")
 	if instance.Spec.MIV.ExistingBucket != "" {
 		extra["mivBucket"] = instance.Spec.MIV.ExistingBucket
 	}
@@ -56,6 +56,33 @@ var _ = Describe("SummonPlatform iamrole Component", func() {
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("summon-platform-dev-%s", instance.Name), Namespace: instance.Namespace}, target)
 		Expect(err).ToNot(HaveOccurred())
 	})
+	Context("IRSA", func() {
+		AfterEach(func() {
+			os.Unsetenv("OIDC_PROVIDER_ARN")
+		})
+
+		It("adds the service account when an OIDC provider is configured", func() {
+			os.Setenv("OIDC_PROVIDER_ARN", "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE")
+			comp := summoncomponents.NewIAMRole("aws/iamrole.yml.tpl")
+			Expect(comp).To(ReconcileContext(ctx))
+			target := &awsv1beta1.IAMRole{}
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("summon-platform-dev-%s", instance.Name), Namespace: instance.Namespace}, target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Spec.OIDCProviderArn).To(Equal("arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE"))
+			Expect(target.Spec.ServiceAccounts).To(Equal([]awsv1beta1.IAMRoleServiceAccountRef{{Name: instance.Name}}))
+		})
+
+		It("adds no service accounts without an OIDC provider", func() {
+			comp := summoncomponents.NewIAMRole("aws/iamrole.yml.tpl")
+			Expect(comp).To(ReconcileContext(ctx))
+			target := &awsv1beta1.IAMRole{}
+			err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("summon-platform-dev-%s", instance.Name), Namespace: instance.Namespace}, target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.Spec.OIDCProviderArn).To(BeEmpty())
+			Expect(target.Spec.ServiceAccounts).To(BeEmpty())
+		})
+	})
+
 	Context("Optimus policy", func() {
 		It("grants access to an external bucket", func() {
 			instance.Spec.OptimusBucketName = "asdf"
@@ -43,6 +43,10 @@ func (comp *iamUserComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *iamUserComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *iamUserComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	// Check on the UseIAM Role flag
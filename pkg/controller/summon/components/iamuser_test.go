@@ -19,11 +19,13 @@ package components_test
 import (
 	"context"
 	"os"
+	"time"
 
 	. "github.com/Benjamintf1/unmarshalledmatchers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	awsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/aws/v1beta1"
@@ -44,6 +46,17 @@ var _ = Describe("SummonPlatform iamuser Component", func() {
 		target := &awsv1beta1.IAMUser{}
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, target)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Spec.MaxKeyAge.Duration).To(BeZero())
+	})
+
+	It("sets the max key age when rotation is enabled", func() {
+		instance.Spec.AWSAccessKeyMaxAge = metav1.Duration{Duration: 90 * 24 * time.Hour}
+		comp := summoncomponents.NewIAMUser("aws/iamuser.yml.tpl")
+		Expect(comp).To(ReconcileContext(ctx))
+		target := &awsv1beta1.IAMUser{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Spec.MaxKeyAge.Duration).To(Equal(90 * 24 * time.Hour))
 	})
 
 	Context("Optimus policy", func() {
@@ -29,6 +29,9 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// Traefik annotation used to split traffic between the stable and canary Services.
+const canaryWeightsAnnotation = "traefik.ingress.kubernetes.io/service-weights"
+
 type ingressComponent struct {
 	templatePath string
 }
@@ -73,9 +76,20 @@ func (comp *ingressComponent) Reconcile(ctx *components.ComponentContext) (compo
 		return components.Result{}, comp.deleteObject(ctx, instance, "daphne")
 	}
 
-	res, _, err := ctx.CreateOrUpdate(comp.templatePath, nil, func(goalObj, existingObj runtime.Object) error {
+	// Send a share of the web traffic to the canary while one is running.
+	var extra map[string]interface{}
+	canary := comp.templatePath == "web/ingress.yml.tpl" && canaryActive(instance)
+	if canary {
+		extra = map[string]interface{}{"canaryWeight": instance.Spec.Rollout.Canary.TrafficPercent}
+	}
+
+	res, _, err := ctx.CreateOrUpdate(comp.templatePath, extra, func(goalObj, existingObj runtime.Object) error {
 		goal := goalObj.(*extv1beta1.Ingress)
 		existing := existingObj.(*extv1beta1.Ingress)
+		// Annotations are only ever added by the metadata sync, so drop the weights once the canary is gone.
+		if !canary {
+			delete(existing.Annotations, canaryWeightsAnnotation)
+		}
 		// Copy the Spec over.
 		existing.Spec = goal.Spec
 		return nil
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+	"github.com/Ridecell/ridecell-operator/pkg/components"
+)
+
+// Annotation to push a version change through outside of the maintenance windows. The value has to be the version.
+const skipMaintenanceWindowAnnotation = "ridecell.io/skip-maintenance-window"
+
+// Returns how long until a version change is allowed to start, or 0 if it can start now.
+func maintenanceWindowWait(instance *summonv1beta1.SummonPlatform, now time.Time) (time.Duration, error) {
+	if len(instance.Spec.MaintenanceWindows) == 0 || instance.Status.MigrateVersion == "" {
+		// No windows configured, or a new instance with nothing running yet.
+		return 0, nil
+	}
+	if instance.Annotations[skipMaintenanceWindowAnnotation] == instance.Spec.Version {
+		return 0, nil
+	}
+
+	var wait time.Duration
+	for i, window := range instance.Spec.MaintenanceWindows {
+		until, err := window.Until(now)
+		if err != nil {
+			return 0, errors.Wrapf(err, "maintenance window %d is invalid", i)
+		}
+		if until == 0 {
+			return 0, nil
+		}
+		if i == 0 || until < wait {
+			wait = until
+		}
+	}
+	return wait, nil
+}
+
+// StatusModifier used while a version change is held for the next maintenance window.
+func waitingForWindow(version string, opensAt time.Time) components.StatusModifier {
+	message := fmt.Sprintf("version %s is waiting for the next maintenance window at %s", version, opensAt.Format(time.UnixDate))
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Status = summonv1beta1.StatusWaitingForWindow
+		instance.Status.Message = message
+		components.SetCondition(obj, summonv1beta1.ConditionMaintenanceWindowOpen, conditions.ConditionFalse, summonv1beta1.StatusWaitingForWindow, message)
+		return nil
+	}
+}
+
+// Wraps the StatusModifier of a version change which isn't held (any more) so the hold condition is cleared.
+func windowOpen(modifier components.StatusModifier) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		components.SetCondition(obj, summonv1beta1.ConditionMaintenanceWindowOpen, conditions.ConditionTrue, "WindowOpen", "")
+		if modifier == nil {
+			return nil
+		}
+		return modifier(obj)
+	}
+}
@@ -18,6 +18,7 @@ package components
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -26,6 +27,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -33,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
@@ -41,12 +44,25 @@ import (
 
 const flavorBucket = "ridecell-flavors"
 
+// Annotation which approves running planned migrations for a version, when approval is required.
+const migrationApprovalAnnotation = "ridecell.io/approve-migrations"
+
+// Last line of the plan job output, must match migrations-plan.yml.tpl.
+const migrationPlanEndMarker = "END OF MIGRATION PLAN: "
+
+// How much of the failed migration logs to keep in the status.
+const migrationLogTailSize = 1024
+
+// Operations from migrate --plan which drop or rename existing data.
+var destructiveMigrationOperations = []string{"Remove field", "Delete model", "Rename field", "Rename model", "Remove constraint", "Raw SQL operation"}
+
 type migrationComponent struct {
-	templatePath string
+	templatePath     string
+	planTemplatePath string
 }
 
 func NewMigrations(templatePath string) *migrationComponent {
-	return &migrationComponent{templatePath: templatePath}
+	return &migrationComponent{templatePath: templatePath, planTemplatePath: "migrations-plan.yml.tpl"}
 }
 
 func (comp *migrationComponent) WatchTypes() []runtime.Object {
@@ -79,7 +95,26 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 
 	if instance.Spec.Version == instance.Status.MigrateVersion {
 		// Already migrated, update status and move on.
-		return components.Result{StatusModifier: setStatus(summonv1beta1.StatusDeploying)}, nil
+		return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusDeploying, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionTrue, "Migrated", "")}, nil
+	}
+
+	// Plan upgrades first if enabled. New instances have nothing to protect so they skip straight to migrating.
+	if instance.Spec.Migrations.Plan && instance.Status.MigrateVersion != "" {
+		if instance.Status.MigrationPlan.Version != instance.Spec.Version {
+			return comp.plan(ctx, instance)
+		}
+		plan := instance.Status.MigrationPlan
+		requireApproval := instance.Spec.Migrations.RequireApproval != nil && *instance.Spec.Migrations.RequireApproval
+		if requireApproval && (len(plan.Pending) > 0 || plan.Truncated) && instance.Annotations[migrationApprovalAnnotation] != instance.Spec.Version {
+			message := fmt.Sprintf("%d pending migrations for version %s, set the %s annotation to %s to run them", len(plan.Pending), instance.Spec.Version, migrationApprovalAnnotation, instance.Spec.Version)
+			if plan.Truncated {
+				message = fmt.Sprintf("migration plan for version %s was too large to check, review it by hand and set the %s annotation to %s to run it", instance.Spec.Version, migrationApprovalAnnotation, instance.Spec.Version)
+			} else if plan.Destructive {
+				message = fmt.Sprintf("%d pending migrations with destructive operations for version %s, set the %s annotation to %s to run them", len(plan.Pending), instance.Spec.Version, migrationApprovalAnnotation, instance.Spec.Version)
+			}
+			ctx.Eventf(corev1.EventTypeNormal, "MigrationApprovalRequired", "%s", message)
+			return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusAwaitingApproval, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "AwaitingApproval", message)}, nil
+		}
 	}
 
 	var urlStr string
@@ -122,8 +157,9 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 			// If this fails, someone else might have started a migraton job between the Get and here, so just try again.
 			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error creation migration job %s/%s, might have lost the race condition", job.Namespace, job.Name)
 		}
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationStarted", "Started migration job %s for version %s", job.Name, instance.Spec.Version)
 		// Job is started, so we're done for now.
-		return components.Result{StatusModifier: setStatus(summonv1beta1.StatusMigrating)}, nil
+		return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusMigrating, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "Running", fmt.Sprintf("migration job %s/%s started", job.Namespace, job.Name))}, nil
 	} else if err != nil {
 		// Some other real error, bail.
 		return components.Result{}, err
@@ -149,6 +185,7 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 		}
 
 		glog.Infof("[%s/%s] migrations: Migration job succeeded, updating MigrateVersion from %s to %s\n", instance.Namespace, instance.Name, instance.Status.MigrateVersion, instance.Spec.Version)
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationSucceeded", "Migrations for version %s succeeded", instance.Spec.Version)
 		// Store migrate version in the closure to avoid concurrent edits to Spec.Version resulting in incorrectly advancing MigrateVersion.
 		migrateVersion := instance.Spec.Version
 		// Onward to deploying!
@@ -156,6 +193,8 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 			instance := obj.(*summonv1beta1.SummonPlatform)
 			instance.Status.Status = summonv1beta1.StatusPostMigrateWait
 			instance.Status.MigrateVersion = migrateVersion
+			instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{}
+			components.SetCondition(obj, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionTrue, "Migrated", "")
 			return nil
 		}}, nil
 	}
@@ -163,10 +202,240 @@ func (comp *migrationComponent) Reconcile(ctx *components.ComponentContext) (com
 	// ... Or if the job failed.
 	if existing.Status.Failed > 0 {
 		// If it was an outdated job, we would have already deleted it, so this means it's a failed migration for the current version.
-		glog.Errorf("[%s/%s] Migration job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, existing.Namespace, existing.Name)
-		return components.Result{}, errors.Errorf("migrations: migration job %s/%s failed", existing.Namespace, existing.Name)
+		return comp.handleFailure(ctx, instance, existing)
 	}
 
 	// Job is still running, will get reconciled when it finishes.
-	return components.Result{StatusModifier: setStatus(summonv1beta1.StatusMigrating)}, nil
+	return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusMigrating, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "Running", fmt.Sprintf("migration job %s/%s running", existing.Namespace, existing.Name))}, nil
+}
+
+// Runs the migration plan job for the current version and records the result in the status.
+func (comp *migrationComponent) plan(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (components.Result, error) {
+	obj, err := ctx.GetTemplate(comp.planTemplatePath, nil)
+	if err != nil {
+		return components.Result{}, err
+	}
+	job := obj.(*batchv1.Job)
+
+	existing := &batchv1.Job{}
+	err = ctx.Get(ctx.Context, types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, existing)
+	if err != nil && kerrors.IsNotFound(err) {
+		glog.Infof("Creating migration plan Job %s/%s\n", job.Namespace, job.Name)
+		err = controllerutil.SetControllerReference(instance, job, ctx.Scheme)
+		if err != nil {
+			return components.Result{}, err
+		}
+
+		err = ctx.Create(ctx.Context, job)
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error creating migration plan job %s/%s, might have lost the race condition", job.Namespace, job.Name)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationPlanStarted", "Started migration plan job %s for version %s", job.Name, instance.Spec.Version)
+		return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusMigrating, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "Planning", fmt.Sprintf("migration plan job %s/%s started", job.Namespace, job.Name))}, nil
+	} else if err != nil {
+		return components.Result{}, err
+	}
+
+	existingVersion, ok := existing.Labels["app.kubernetes.io/version"]
+	if !ok || existingVersion != instance.Spec.Version {
+		glog.Infof("[%s/%s] migrations: Found existing migration plan job with bad version %#v\n", instance.Namespace, instance.Name, existingVersion)
+		err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: found existing migration plan job %s/%s with bad version %#v", instance.Namespace, instance.Name, existingVersion)
+	}
+
+	if existing.Status.Succeeded > 0 {
+		terminated, err := jobTermination(ctx, existing, true)
+		if err != nil {
+			return components.Result{Requeue: true}, err
+		}
+		if terminated == nil {
+			return components.Result{Requeue: true}, errors.Errorf("migrations: no successful pod found for job %s/%s", existing.Namespace, existing.Name)
+		}
+		plan := parseMigrationPlan(terminated.Message)
+		plan.Version = instance.Spec.Version
+
+		err = ctx.Delete(ctx.Context, existing, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error deleting successful migration plan job %s/%s", existing.Namespace, existing.Name)
+		}
+
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationPlanned", "Version %s has %d pending migrations (destructive: %t)", plan.Version, len(plan.Pending), plan.Destructive)
+		return components.Result{Requeue: true, StatusModifier: func(obj runtime.Object) error {
+			instance := obj.(*summonv1beta1.SummonPlatform)
+			instance.Status.MigrationPlan = plan
+			return nil
+		}}, nil
+	}
+
+	if existing.Status.Failed > 0 {
+		glog.Errorf("[%s/%s] Migration plan job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, existing.Namespace, existing.Name)
+		err = errors.Errorf("migrations: migration plan job %s/%s failed", existing.Namespace, existing.Name)
+		ctx.Eventf(corev1.EventTypeWarning, "MigrationPlanFailed", "Migration plan job %s for version %s failed", existing.Name, instance.Spec.Version)
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			components.SetCondition(obj, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "PlanFailed", err.Error())
+			return nil
+		}}, err
+	}
+
+	return components.Result{StatusModifier: setStatusWithCondition(summonv1beta1.StatusMigrating, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, "Planning", fmt.Sprintf("migration plan job %s/%s running", existing.Namespace, existing.Name))}, nil
+}
+
+// Handles a failed migration job, recording why it failed and retrying it with a backoff.
+func (comp *migrationComponent) handleFailure(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform, job *batchv1.Job) (components.Result, error) {
+	failure := instance.Status.MigrationFailure
+	if failure.Version != instance.Spec.Version {
+		failure = summonv1beta1.MigrationFailureStatus{Version: instance.Spec.Version}
+	}
+	now := time.Now()
+	firstSeen := failure.FailedAt == ""
+	if firstSeen {
+		// First time seeing this failure, grab the details from the pod before it goes away.
+		terminated, err := jobTermination(ctx, job, false)
+		if err != nil {
+			glog.Errorf("[%s/%s] %s\n", instance.Namespace, instance.Name, err)
+		}
+		failure.Reason = ""
+		failure.ExitCode = 0
+		failure.LogTail = ""
+		if terminated != nil {
+			failure.Reason = terminated.Reason
+			failure.ExitCode = terminated.ExitCode
+			failure.LogTail = logTail(terminated.Message, migrationLogTailSize)
+		}
+		failure.FailedAt = now.Format(time.UnixDate)
+	}
+	failedAt, err := time.Parse(time.UnixDate, failure.FailedAt)
+	if err != nil {
+		failedAt = now
+	}
+
+	var maxRetries int32
+	if instance.Spec.Migrations.MaxRetries != nil {
+		maxRetries = *instance.Spec.Migrations.MaxRetries
+	}
+	message := fmt.Sprintf("migration job %s/%s failed (attempt %d of %d)", job.Namespace, job.Name, failure.Retries+1, maxRetries+1)
+	if failure.Reason != "" {
+		message = fmt.Sprintf("%s: %s, exit code %d", message, failure.Reason, failure.ExitCode)
+	}
+	failedErr := errors.Errorf("migrations: %s", message)
+	if firstSeen {
+		ctx.Eventf(corev1.EventTypeWarning, "MigrationFailed", "Migration job %s for version %s failed (attempt %d of %d)", job.Name, instance.Spec.Version, failure.Retries+1, maxRetries+1)
+	}
+
+	if failure.Retries < maxRetries {
+		retryAt := failedAt.Add(instance.Spec.Migrations.RetryBackoff.Duration << uint(failure.Retries))
+		if now.Before(retryAt) {
+			return components.Result{RequeueAfter: retryAt.Sub(now), StatusModifier: setMigrationFailure(failure, "Failed", fmt.Sprintf("%s, retrying at %s", message, retryAt.Format(time.UnixDate)))}, failedErr
+		}
+		// Delete the failed job so a new one is started on the next reconcile.
+		glog.Infof("[%s/%s] Retrying failed migration job %s/%s\n", instance.Namespace, instance.Name, job.Namespace, job.Name)
+		err = ctx.Delete(ctx.Context, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil {
+			return components.Result{Requeue: true}, errors.Wrapf(err, "migrations: error deleting failed migration job %s/%s", job.Namespace, job.Name)
+		}
+		ctx.Eventf(corev1.EventTypeNormal, "MigrationRetrying", "Retrying migrations for version %s", instance.Spec.Version)
+		failure.Retries++
+		failure.FailedAt = ""
+		return components.Result{Requeue: true, StatusModifier: setMigrationFailure(failure, "Retrying", message)}, nil
+	}
+
+	glog.Errorf("[%s/%s] Migration job failed, leaving job %s/%s for debugging purposes\n", instance.Namespace, instance.Name, job.Namespace, job.Name)
+	return components.Result{StatusModifier: setMigrationFailure(failure, "Failed", failedErr.Error())}, failedErr
+}
+
+// StatusModifier to record a migration failure.
+func setMigrationFailure(failure summonv1beta1.MigrationFailureStatus, reason, message string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.MigrationFailure = failure
+		components.SetCondition(obj, summonv1beta1.ConditionMigrationsComplete, conditions.ConditionFalse, reason, message)
+		return nil
+	}
+}
+
+// Finds the most recently terminated container state of the pods for a Job, either the successful or the failed one.
+// Returns nil if the pods are already gone.
+func jobTermination(ctx *components.ComponentContext, job *batchv1.Job, succeeded bool) (*corev1.ContainerStateTerminated, error) {
+	listOptions := &client.ListOptions{Namespace: job.Namespace}
+	err := listOptions.SetLabelSelector("job-name=" + job.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "migrations: invalid label selector for job %s/%s", job.Namespace, job.Name)
+	}
+	pods := &corev1.PodList{}
+	err = ctx.List(ctx.Context, listOptions, pods)
+	if err != nil {
+		return nil, errors.Wrapf(err, "migrations: error listing pods for job %s/%s", job.Namespace, job.Name)
+	}
+	var found *corev1.ContainerStateTerminated
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.State.Terminated
+			if terminated == nil || (terminated.ExitCode == 0) != succeeded {
+				continue
+			}
+			if found == nil || terminated.FinishedAt.After(found.FinishedAt.Time) {
+				found = terminated.DeepCopy()
+			}
+		}
+	}
+	return found, nil
+}
+
+// Returns at most the last size bytes of a string, cut at a line boundary when possible.
+func logTail(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	s = s[len(s)-size:]
+	if i := strings.Index(s, "\n"); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// Parses the output of migrate --plan, which lists each pending migration followed by its indented operations.
+// A plan without a matching end marker was cut off, so it is treated as destructive.
+func parseMigrationPlan(output string) summonv1beta1.MigrationPlanStatus {
+	plan := summonv1beta1.MigrationPlanStatus{}
+	output, complete := trimMigrationPlanMarker(output)
+	if !complete {
+		plan.Truncated = true
+		plan.Destructive = true
+		plan.DestructiveOperations = append(plan.DestructiveOperations, "plan output was truncated, operations could not be checked")
+	}
+	migration := ""
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "Planned operations:" || strings.HasPrefix(trimmed, "No planned migration operations") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			migration = trimmed
+			plan.Pending = append(plan.Pending, migration)
+			continue
+		}
+		for _, op := range destructiveMigrationOperations {
+			if strings.HasPrefix(trimmed, op) {
+				plan.Destructive = true
+				plan.DestructiveOperations = append(plan.DestructiveOperations, fmt.Sprintf("%s: %s", migration, trimmed))
+				break
+			}
+		}
+	}
+	return plan
+}
+
+// Strips the end marker added by the plan job, returning false if it is missing or the line count doesn't match.
+func trimMigrationPlanMarker(output string) (string, bool) {
+	i := strings.LastIndex(output, migrationPlanEndMarker)
+	if i < 0 {
+		return output, false
+	}
+	plan := output[:i]
+	var lines int
+	_, err := fmt.Sscanf(output[i+len(migrationPlanEndMarker):], "%d lines", &lines)
+	if err != nil || strings.Count(plan, "\n") != lines {
+		return plan, false
+	}
+	return plan, true
 }
@@ -20,17 +20,21 @@ import (
 	"context"
 	"os"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	. "github.com/Ridecell/ridecell-operator/pkg/test_helpers/matchers"
 )
@@ -173,6 +177,7 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(jobs.Items).To(BeEmpty())
 				Expect(instance.Status.MigrateVersion).To(Equal("1.2.3"))
+				Expect(conditions.IsTrue(instance.Status.Conditions, summonv1beta1.ConditionMigrationsComplete)).To(BeTrue())
 			})
 		})
 
@@ -198,6 +203,85 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(instance.Status.MigrateVersion).To(Equal(""))
 			})
+
+			It("records the failure details from the pod", func() {
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-dev-migrations-abcde",
+						Namespace: "summon-dev",
+						Labels:    map[string]string{"job-name": "foo-dev-migrations"},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name: "default",
+								State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+									ExitCode: 137,
+									Reason:   "OOMKilled",
+									Message:  "Applying trips.0107_remove_legacy_fare...",
+								}},
+							},
+						},
+					},
+				}
+				ctx.Client = fake.NewFakeClient(job, pod)
+
+				_, err = comp.Reconcile(ctx)
+				Expect(err).To(MatchError(ContainSubstring("OOMKilled, exit code 137")))
+				failure := instance.Status.MigrationFailure
+				Expect(failure.Version).To(Equal("1.2.3"))
+				Expect(failure.Reason).To(Equal("OOMKilled"))
+				Expect(failure.ExitCode).To(Equal(int32(137)))
+				Expect(failure.LogTail).To(Equal("Applying trips.0107_remove_legacy_fare..."))
+				Expect(failure.FailedAt).NotTo(Equal(""))
+			})
+
+			It("waits for the backoff before retrying", func() {
+				maxRetries := int32(1)
+				instance.Spec.Migrations.MaxRetries = &maxRetries
+				instance.Spec.Migrations.RetryBackoff = metav1.Duration{Duration: time.Hour}
+
+				res, err := comp.Reconcile(ctx)
+				Expect(err).To(HaveOccurred())
+				Expect(res.RequeueAfter).To(BeNumerically(">", 0))
+				job := &batchv1.Job{}
+				err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("retries the migration after the backoff", func() {
+				maxRetries := int32(1)
+				instance.Spec.Migrations.MaxRetries = &maxRetries
+				instance.Spec.Migrations.RetryBackoff = metav1.Duration{Duration: time.Minute}
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{
+					Version:  "1.2.3",
+					Reason:   "Error",
+					ExitCode: 1,
+					FailedAt: time.Now().Add(-2 * time.Minute).Format(time.UnixDate),
+				}
+
+				Expect(comp).To(ReconcileContext(ctx))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).To(HaveOccurred())
+				Expect(instance.Status.MigrationFailure.Retries).To(Equal(int32(1)))
+				Expect(instance.Status.MigrationFailure.FailedAt).To(Equal(""))
+			})
+
+			It("gives up once the retries are used", func() {
+				maxRetries := int32(1)
+				instance.Spec.Migrations.MaxRetries = &maxRetries
+				instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{Version: "1.2.3", Retries: 1}
+
+				_, err := comp.Reconcile(ctx)
+				Expect(err).To(MatchError(ContainSubstring("attempt 2 of 2")))
+				job := &batchv1.Job{}
+				err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
 		})
 
 		Context("with a failed migration job from a previous version", func() {
@@ -288,5 +372,148 @@ var _ = Describe("SummonPlatform Migrations Component", func() {
 				Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(Equal("python manage.py migrate -v3"))
 			})
 		})
+
+		Context("with migration planning enabled", func() {
+			BeforeEach(func() {
+				requireApproval := true
+				instance.Spec.Migrations.Plan = true
+				instance.Spec.Migrations.RequireApproval = &requireApproval
+				instance.Status.MigrateVersion = "1.2.2"
+			})
+
+			It("creates a plan job before migrating", func() {
+				Expect(comp).To(ReconcileContext(ctx))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations-plan", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring("python manage.py migrate --plan"))
+				err = ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).To(HaveOccurred())
+				Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusMigrating))
+			})
+
+			It("skips planning for a new instance", func() {
+				instance.Status.MigrateVersion = ""
+				Expect(comp).To(ReconcileContext(ctx))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("records the plan from a successful plan job", func() {
+				job := &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-dev-migrations-plan",
+						Namespace: "summon-dev",
+						Labels:    map[string]string{"app.kubernetes.io/version": "1.2.3"},
+					},
+					Status: batchv1.JobStatus{
+						Succeeded: 1,
+					},
+				}
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-dev-migrations-plan-abcde",
+						Namespace: "summon-dev",
+						Labels:    map[string]string{"job-name": "foo-dev-migrations-plan"},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name: "default",
+								State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+									ExitCode: 0,
+									Message:  "Planned operations:\nvehicles.0042_vehicle_color\n    Add field color to vehicle\ntrips.0107_remove_legacy_fare\n    Remove field legacy_fare from trip\nEND OF MIGRATION PLAN: 5 lines\n",
+								}},
+							},
+						},
+					},
+				}
+				ctx.Client = fake.NewFakeClient(job, pod)
+
+				Expect(comp).To(ReconcileContext(ctx))
+				plan := instance.Status.MigrationPlan
+				Expect(plan.Version).To(Equal("1.2.3"))
+				Expect(plan.Pending).To(Equal([]string{"vehicles.0042_vehicle_color", "trips.0107_remove_legacy_fare"}))
+				Expect(plan.Destructive).To(BeTrue())
+				Expect(plan.DestructiveOperations).To(Equal([]string{"trips.0107_remove_legacy_fare: Remove field legacy_fare from trip"}))
+				Expect(plan.Truncated).To(BeFalse())
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations-plan", Namespace: "summon-dev"}, job)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("marks a plan cut off by the termination message limit as truncated", func() {
+				job := &batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-dev-migrations-plan",
+						Namespace: "summon-dev",
+						Labels:    map[string]string{"app.kubernetes.io/version": "1.2.3"},
+					},
+					Status: batchv1.JobStatus{
+						Succeeded: 1,
+					},
+				}
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-dev-migrations-plan-abcde",
+						Namespace: "summon-dev",
+						Labels:    map[string]string{"job-name": "foo-dev-migrations-plan"},
+					},
+					Status: corev1.PodStatus{
+						ContainerStatuses: []corev1.ContainerStatus{
+							{
+								Name: "default",
+								State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+									ExitCode: 0,
+									// The kubelet keeps the end of the file, so the head of the plan is missing.
+									Message: "    Add field color to vehicle\nEND OF MIGRATION PLAN: 512 lines\n",
+								}},
+							},
+						},
+					},
+				}
+				ctx.Client = fake.NewFakeClient(job, pod)
+
+				Expect(comp).To(ReconcileContext(ctx))
+				plan := instance.Status.MigrationPlan
+				Expect(plan.Truncated).To(BeTrue())
+				Expect(plan.Destructive).To(BeTrue())
+			})
+
+			It("waits for approval of a truncated plan", func() {
+				instance.Status.MigrationPlan = summonv1beta1.MigrationPlanStatus{Version: "1.2.3", Truncated: true, Destructive: true}
+				Expect(comp).To(ReconcileContext(ctx))
+				Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusAwaitingApproval))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("waits for approval of pending migrations", func() {
+				instance.Status.MigrationPlan = summonv1beta1.MigrationPlanStatus{Version: "1.2.3", Pending: []string{"trips.0107_remove_legacy_fare"}, Destructive: true}
+				Expect(comp).To(ReconcileContext(ctx))
+				Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusAwaitingApproval))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("migrates once the version is approved", func() {
+				instance.Status.MigrationPlan = summonv1beta1.MigrationPlanStatus{Version: "1.2.3", Pending: []string{"trips.0107_remove_legacy_fare"}, Destructive: true}
+				instance.Annotations = map[string]string{"ridecell.io/approve-migrations": "1.2.3"}
+				Expect(comp).To(ReconcileContext(ctx))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("doesn't wait for approval without pending migrations", func() {
+				instance.Status.MigrationPlan = summonv1beta1.MigrationPlanStatus{Version: "1.2.3"}
+				Expect(comp).To(ReconcileContext(ctx))
+				job := &batchv1.Job{}
+				err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-migrations", Namespace: "summon-dev"}, job)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
@@ -36,6 +36,10 @@ func (_ *newMockCarServerTenantComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *newMockCarServerTenantComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *newMockCarServerTenantComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	return true
 }
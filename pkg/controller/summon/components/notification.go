@@ -415,11 +415,31 @@ func (comp *notificationComponent) formatSuccessNotification(instance *summonv1b
 
 // Render the nofiication attachement for an error notification.
 func (comp *notificationComponent) formatErrorNotification(instance *summonv1beta1.SummonPlatform, errorMessage string) slack.Attachment {
+	fields := []slack.AttachmentField{}
+	// Include the details of a failed migration so nobody has to go digging for the pod logs.
+	failure := instance.Status.MigrationFailure
+	if strings.HasPrefix(errorMessage, "migrations:") && failure.Version == instance.Spec.Version && failure.FailedAt != "" {
+		if failure.Reason != "" {
+			fields = append(fields, slack.AttachmentField{
+				Title: "Exit Reason",
+				Value: fmt.Sprintf("%s (exit code %d)", failure.Reason, failure.ExitCode),
+				Short: true,
+			})
+		}
+		if failure.LogTail != "" {
+			fields = append(fields, slack.AttachmentField{
+				Title: "Log Tail",
+				Value: fmt.Sprintf("```%s```", failure.LogTail),
+			})
+		}
+	}
+
 	return slack.Attachment{
 		Title:     fmt.Sprintf("%s Deployment", instance.Spec.Hostname),
 		TitleLink: fmt.Sprintf("https://%s/", instance.Spec.Hostname),
 		Color:     "danger",
 		Text:      fmt.Sprintf("<https://%s/|%s> has error: %s", instance.Spec.Hostname, instance.Spec.Hostname, errorMessage),
 		Fallback:  fmt.Sprintf("%s has error: %s", instance.Spec.Hostname, errorMessage),
+		Fields:    fields,
 	}
 }
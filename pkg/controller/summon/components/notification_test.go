@@ -239,6 +239,24 @@ var _ = Describe("SummonPlatform Notification Component", func() {
 			Expect(mockedDeployStatusClient.PostStatusCalls()).To(HaveLen(0))
 		})
 
+		It("includes the migration failure details in the error notification", func() {
+			instance.Status.Message = "migrations: migration job summon-dev/foo-dev-migrations failed (attempt 1 of 1): OOMKilled, exit code 137"
+			instance.Status.Status = summonv1beta1.StatusError
+			instance.Status.MigrationFailure = summonv1beta1.MigrationFailureStatus{
+				Version:  "1.2.3",
+				Reason:   "OOMKilled",
+				ExitCode: 137,
+				LogTail:  "Applying trips.0107_remove_legacy_fare...",
+				FailedAt: "Mon Jan  2 15:04:05 UTC 2006",
+			}
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(mockedSlackClient.PostMessageCalls()).To(HaveLen(1))
+			post := mockedSlackClient.PostMessageCalls()[0]
+			Expect(post.In2.Fields).To(HaveLen(2))
+			Expect(post.In2.Fields[0].Value).To(Equal("OOMKilled (exit code 137)"))
+			Expect(post.In2.Fields[1].Value).To(Equal("```Applying trips.0107_remove_legacy_fare...```"))
+		})
+
 		It("sends two error notifications for two different errors", func() {
 			instance.Status.Message = "Someone set us up the bomb"
 			instance.Status.Status = summonv1beta1.StatusError
@@ -19,6 +19,7 @@ package components
 import (
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
@@ -37,6 +38,10 @@ func (comp *postgresComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *postgresComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *postgresComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	return true
 }
@@ -53,15 +58,26 @@ func (comp *postgresComponent) Reconcile(ctx *components.ComponentContext) (comp
 	if existing != nil {
 		// If the database is in an error state, mark this summon as error'd too.
 		if existing.Status.Status == dbv1beta1.StatusError {
-			return res, errors.Errorf("postgres: %s", existing.Status.Message)
+			message := existing.Status.Message
+			res.StatusModifier = func(obj runtime.Object) error {
+				components.SetCondition(obj, summonv1beta1.ConditionDatabaseReady, conditions.ConditionFalse, dbv1beta1.StatusError, message)
+				return nil
+			}
+			return res, errors.Errorf("postgres: %s", message)
 		}
 		res.StatusModifier = func(obj runtime.Object) error {
 			instance := obj.(*summonv1beta1.SummonPlatform)
 			instance.Status.PostgresStatus = existing.Status.Status
 			instance.Status.PostgresConnection = existing.Status.Connection
+			instance.Status.PostgresReplicaConnections = existing.Status.ReplicaConnections
 			if existing.Status.Status != "" {
 				instance.Status.Status = summonv1beta1.StatusInitializing
 			}
+			if existing.Status.Status == dbv1beta1.StatusReady {
+				components.SetCondition(obj, summonv1beta1.ConditionDatabaseReady, conditions.ConditionTrue, dbv1beta1.StatusReady, "")
+			} else {
+				components.SetCondition(obj, summonv1beta1.ConditionDatabaseReady, conditions.ConditionFalse, "Provisioning", existing.Status.Message)
+			}
 			return nil
 		}
 	}
@@ -24,10 +24,13 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	dbv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/db/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -56,6 +59,32 @@ var _ = Describe("SummonPlatform Postgres Component", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("passes cloneFrom through to the PostgresDatabase", func() {
+			instance.Spec.Database.CloneFrom = &dbv1beta1.PostgresDatabaseCloneSpec{
+				PostgresDatabaseRef: corev1.ObjectReference{Name: "foo-uat", Namespace: "summon-uat"},
+				ScrubScriptRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "scrub"},
+					Key:                  "scrub.sql",
+				},
+			}
+			Expect(comp).To(ReconcileContext(ctx))
+
+			db := &dbv1beta1.PostgresDatabase{}
+			err := ctx.Get(context.TODO(), types.NamespacedName{Name: "foo-dev", Namespace: "summon-dev"}, db)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(db.Spec.CloneFrom).To(Equal(instance.Spec.Database.CloneFrom))
+		})
+
+		It("passes the pooler through to the PostgresDatabase", func() {
+			instance.Spec.Database.Pooler = &dbv1beta1.PostgresDatabasePoolerSpec{PoolMode: "session", PoolSize: 10}
+			Expect(comp).To(ReconcileContext(ctx))
+
+			db := &dbv1beta1.PostgresDatabase{}
+			err := ctx.Get(context.TODO(), types.NamespacedName{Name: "foo-dev", Namespace: "summon-dev"}, db)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(db.Spec.Pooler).To(Equal(instance.Spec.Database.Pooler))
+		})
+
 		It("sets PostgresStatus", func() {
 			db := &dbv1beta1.PostgresDatabase{
 				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
@@ -67,6 +96,22 @@ var _ = Describe("SummonPlatform Postgres Component", func() {
 
 			Expect(comp).To(ReconcileContext(ctx))
 			Expect(instance.Status.PostgresStatus).To(Equal(dbv1beta1.StatusReady))
+			Expect(conditions.IsTrue(instance.Status.Conditions, summonv1beta1.ConditionDatabaseReady)).To(BeTrue())
+		})
+
+		It("sets DatabaseReady to false while provisioning", func() {
+			db := &dbv1beta1.PostgresDatabase{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-dev", Namespace: "summon-dev"},
+				Status: dbv1beta1.PostgresDatabaseStatus{
+					Status: dbv1beta1.StatusCreating,
+				},
+			}
+			ctx.Client = fake.NewFakeClient(db)
+
+			Expect(comp).To(ReconcileContext(ctx))
+			cond := conditions.Find(instance.Status.Conditions, summonv1beta1.ConditionDatabaseReady)
+			Expect(cond).ToNot(BeNil())
+			Expect(cond.Status).To(Equal(conditions.ConditionFalse))
 		})
 
 		Context("with database name migration override", func() {
@@ -40,6 +40,10 @@ func (comp *pullSecretComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *pullSecretComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *pullSecretComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	// Secrets have no dependencies, always reconcile.
 	return true
@@ -39,6 +39,10 @@ func (comp *rabbitmqVhostComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *rabbitmqVhostComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *rabbitmqVhostComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	// Has no dependencies, always reconcilable.
 	return true
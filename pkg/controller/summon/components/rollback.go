@@ -0,0 +1,70 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Progress deadline used if none is set in the spec.
+const defaultProgressDeadline = 15 * time.Minute
+
+// Subsystems which get rolled back to the last known good version after a failed deploy.
+var rollbackSubsystems = []string{"web", "celeryd", "daphne"}
+
+// Returns true if the current Spec.Version already failed to become ready within the progress deadline.
+func deployFailed(instance *summonv1beta1.SummonPlatform) bool {
+	return instance.Status.Deploy.FailedVersion != "" && instance.Status.Deploy.FailedVersion == instance.Spec.Version
+}
+
+// Returns the version to roll back to, or "" if there shouldn't be a rollback.
+func rollbackVersion(instance *summonv1beta1.SummonPlatform) string {
+	lastGood := instance.Status.Deploy.LastGoodVersion
+	if !instance.Spec.Rollback.Enabled || !deployFailed(instance) || lastGood == instance.Spec.Version {
+		return ""
+	}
+	return lastGood
+}
+
+// Returns true if the given template belongs to a subsystem which gets rolled back.
+func isRollbackTemplate(templatePath string) bool {
+	for _, subsystem := range rollbackSubsystems {
+		if strings.HasPrefix(templatePath, subsystem+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// The error reported while the current version is marked as failed. This needs to stay the same
+// across reconciles so the error notification is only sent once.
+func deployFailedError(instance *summonv1beta1.SummonPlatform) error {
+	deadline := instance.Spec.Rollback.ProgressDeadline.Duration
+	if version := rollbackVersion(instance); version != "" {
+		return errors.Errorf("status: version %s did not become ready within %s, rolled back %s to %s", instance.Spec.Version, deadline, strings.Join(rollbackSubsystems, ", "), version)
+	}
+	reason := "automatic rollback is disabled"
+	if instance.Spec.Rollback.Enabled {
+		reason = "no known good version to roll back to"
+	}
+	return errors.Errorf("status: version %s did not become ready within %s, %s", instance.Spec.Version, deadline, reason)
+}
@@ -47,6 +47,10 @@ func (comp *s3BucketComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *s3BucketComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *s3BucketComponent) IsReconcilable(_ *components.ComponentContext) bool {
 	// Has no dependencies, always reconcilable.
 	return true
@@ -40,6 +40,12 @@ var _ = Describe("SummonPlatform s3bucket Component", func() {
 		target := &awsv1beta1.S3Bucket{}
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev", Namespace: "summon-dev"}, target)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Spec.Versioning).ToNot(BeNil())
+		Expect(*target.Spec.Versioning).To(BeTrue())
+		Expect(target.Spec.CorsRules).To(HaveLen(1))
+		Expect(target.Spec.PublicAccessBlock.BlockPublicAcls).To(BeTrue())
+		// The static bucket is served publicly through its bucket policy.
+		Expect(target.Spec.PublicAccessBlock.BlockPublicPolicy).To(BeFalse())
 		// Make sure it doesn't touch the MIV status.
 		Expect(instance.Status.MIV.Bucket).To(Equal(""))
 	})
@@ -50,6 +56,10 @@ var _ = Describe("SummonPlatform s3bucket Component", func() {
 		target := &awsv1beta1.S3Bucket{}
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: "foo-dev-miv", Namespace: "summon-dev"}, target)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Spec.Encryption.Algorithm).To(Equal("aws:kms"))
+		Expect(target.Spec.LifecycleRules).To(HaveLen(1))
+		Expect(target.Spec.LifecycleRules[0].ExpirationDays).To(Equal(int64(90)))
+		Expect(target.Spec.PublicAccessBlock.BlockPublicPolicy).To(BeTrue())
 		Expect(instance.Status.MIV.Bucket).To(Equal("ridecell-foo-dev-miv"))
 	})
 
@@ -45,6 +45,10 @@ func (comp *secretKeyComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *secretKeyComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *secretKeyComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	// No ability to return errors so we're doing checks for this in Reconcile
 	return true
@@ -41,6 +41,10 @@ func (comp *serviceAccountK8sComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *serviceAccountK8sComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *serviceAccountK8sComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	return true
 }
@@ -63,11 +67,11 @@ func (comp *serviceAccountK8sComponent) Reconcile(ctx *components.ComponentConte
 
 	extra := map[string]interface{}{}
 	extra["accountId"] = match[1]
+	// With an OIDC provider the IAMRole controller annotates the ServiceAccount itself.
+	extra["irsa"] = os.Getenv("OIDC_PROVIDER_ARN") != ""
 
 	res, _, err := ctx.CreateOrUpdate("service_account_k8s.yml.tpl", extra, func(goalObj, existingObj runtime.Object) error {
-		goal := goalObj.(*corev1.ServiceAccount)
-		existing := existingObj.(*corev1.ServiceAccount)
-		existing.Annotations = goal.Annotations
+		// Annotations are merged by CreateOrUpdate, don't clobber the one set by the IAMRole controller.
 		return nil
 	})
 	return res, err
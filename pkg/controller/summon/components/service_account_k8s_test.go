@@ -24,7 +24,9 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	summoncomponents "github.com/Ridecell/ridecell-operator/pkg/controller/summon/components"
 	corev1 "k8s.io/api/core/v1"
@@ -44,6 +46,26 @@ var _ = Describe("SummonPlatform iamrole Component", func() {
 		target := &corev1.ServiceAccount{}
 		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, target)
 		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Annotations).To(HaveKeyWithValue("eks.amazonaws.com/role-arn", "arn:aws:iam::123456789:role/summon-platform-dev-foo-dev"))
+	})
+
+	It("leaves the annotation to the IAMRole controller with an OIDC provider", func() {
+		os.Setenv("OIDC_PROVIDER_ARN", "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-west-2.amazonaws.com/id/EXAMPLE")
+		defer os.Unsetenv("OIDC_PROVIDER_ARN")
+		existing := &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        instance.Name,
+				Namespace:   instance.Namespace,
+				Annotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/summon-platform-dev-foo-dev"},
+			},
+		}
+		ctx.Client = fake.NewFakeClient(instance, existing)
+		comp := summoncomponents.NewserviceAccountK8s()
+		Expect(comp).To(ReconcileContext(ctx))
+		target := &corev1.ServiceAccount{}
+		err := ctx.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}, target)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target.Annotations).To(HaveKeyWithValue("eks.amazonaws.com/role-arn", "arn:aws:iam::123456789012:role/summon-platform-dev-foo-dev"))
 	})
 
 	It("Should not create serviceaccount object", func() {
@@ -36,6 +36,10 @@ func (comp *serviceAccountComponent) WatchTypes() []runtime.Object {
 	}
 }
 
+func (_ *serviceAccountComponent) DependsOn() []components.Component {
+	return specDependencies()
+}
+
 func (_ *serviceAccountComponent) IsReconcilable(ctx *components.ComponentContext) bool {
 	instance := ctx.Top.(*summonv1beta1.SummonPlatform)
 	return instance.Spec.GCPProject != ""
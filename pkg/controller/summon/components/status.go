@@ -19,13 +19,16 @@ package components
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/Ridecell/ridecell-operator/pkg/apis/conditions"
 	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
 	"github.com/Ridecell/ridecell-operator/pkg/components"
 )
@@ -55,6 +58,27 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 		return components.Result{}, nil
 	}
 
+	if deployFailed(instance) {
+		// Already past the progress deadline for this version, keep reporting it until a new version is deployed.
+		err := deployFailedError(instance)
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			components.SetCondition(obj, summonv1beta1.ConditionDeploymentsReady, conditions.ConditionFalse, "ProgressDeadlineExceeded", err.Error())
+			return nil
+		}}, err
+	}
+
+	if canaryPending(instance) {
+		if canaryPhase(instance, summonv1beta1.CanaryAborted) {
+			// Already reported by the canary component.
+			return components.Result{}, nil
+		}
+		// Web stays on the old version until the canary is promoted, so the deploy can't be ready yet.
+		return components.Result{StatusModifier: func(obj runtime.Object) error {
+			components.SetCondition(obj, summonv1beta1.ConditionDeploymentsReady, conditions.ConditionFalse, "CanaryProgressing", fmt.Sprintf("waiting for the canary of version %s to be promoted", instance.Spec.Version))
+			return nil
+		}}, nil
+	}
+
 	// Grab all (important) Deployments and make sure they are all ready.
 	web := &appsv1.Deployment{}
 	daphne := &appsv1.Deployment{}
@@ -140,14 +164,9 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 			comp.isReady(static) && schedulerReady &&
 			comp.isReady(dispatch) && comp.isReady(businessPortal) &&
 			comp.isReady(tripShare) && comp.isReady(hwAux) && comp.isReady(kafkaconsumer) {
-			return components.Result{StatusModifier: func(obj runtime.Object) error {
-				instance := obj.(*summonv1beta1.SummonPlatform)
-				instance.Status.Status = summonv1beta1.StatusReady
-				instance.Status.Message = fmt.Sprintf("Cluster %s ready", instance.Name)
-				return nil
-			}}, nil
+			return components.Result{StatusModifier: deploymentsReady(instance.Spec.Version)}, nil
 		}
-		return components.Result{}, nil
+		return comp.notReady(ctx, instance)
 	}
 
 	// celery scheduler needs to be handled separately
@@ -166,16 +185,73 @@ func (comp *statusComponent) Reconcile(ctx *components.ComponentContext) (compon
 		kafkaconsumer.Spec.Replicas != nil && kafkaconsumer.Status.AvailableReplicas == *kafkaconsumer.Spec.Replicas &&
 		schedulerReady {
 		// TODO: Add an actual HTTP self check in here.
-		return components.Result{StatusModifier: func(obj runtime.Object) error {
+		return components.Result{StatusModifier: deploymentsReady(instance.Spec.Version)}, nil
+	}
+
+	// Not ready, alas.
+	return comp.notReady(ctx, instance)
+}
+
+// StatusModifier used once everything is ready. This version is now the last known good one.
+func deploymentsReady(version string) components.StatusModifier {
+	return func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Status = summonv1beta1.StatusReady
+		instance.Status.Message = fmt.Sprintf("Cluster %s ready", instance.Name)
+		instance.Status.Deploy.LastGoodVersion = version
+		instance.Status.Deploy.Version = version
+		instance.Status.Deploy.FailedVersion = ""
+		components.SetCondition(obj, summonv1beta1.ConditionDeploymentsReady, conditions.ConditionTrue, summonv1beta1.StatusReady, "")
+		return nil
+	}
+}
+
+// Handle deployments not being ready yet, enforcing the progress deadline for new versions.
+func (comp *statusComponent) notReady(ctx *components.ComponentContext, instance *summonv1beta1.SummonPlatform) (components.Result, error) {
+	version := instance.Spec.Version
+	deadline := instance.Spec.Rollback.ProgressDeadline.Duration
+	deploy := instance.Status.Deploy
+
+	if deploy.LastGoodVersion == version {
+		// This version was ready before, so there is no deadline. Likely just pods being rescheduled.
+		return components.Result{StatusModifier: deploymentsNotReady}, nil
+	}
+
+	startedAt, err := time.Parse(time.UnixDate, deploy.StartedAt)
+	if deploy.Version != version || err != nil {
+		// New version, start the clock.
+		startedAt := time.Now().Format(time.UnixDate)
+		return components.Result{RequeueAfter: deadline, StatusModifier: func(obj runtime.Object) error {
 			instance := obj.(*summonv1beta1.SummonPlatform)
-			instance.Status.Status = summonv1beta1.StatusReady
-			instance.Status.Message = fmt.Sprintf("Cluster %s ready", instance.Name)
-			return nil
+			instance.Status.Deploy.Version = version
+			instance.Status.Deploy.StartedAt = startedAt
+			return deploymentsNotReady(obj)
 		}}, nil
 	}
 
-	// Not ready, alas.
-	return components.Result{}, nil
+	remaining := deadline - time.Since(startedAt)
+	if remaining > 0 {
+		// Check back once the deadline passes, in case nothing else triggers a reconcile.
+		return components.Result{RequeueAfter: remaining, StatusModifier: deploymentsNotReady}, nil
+	}
+
+	// Out of time, mark the version as failed. The deployment components handle the rollback from here.
+	failed := instance.DeepCopy()
+	failed.Status.Deploy.FailedVersion = version
+	failedErr := deployFailedError(failed)
+	ctx.Eventf(corev1.EventTypeWarning, "ProgressDeadlineExceeded", "%s", failedErr)
+	return components.Result{StatusModifier: func(obj runtime.Object) error {
+		instance := obj.(*summonv1beta1.SummonPlatform)
+		instance.Status.Deploy.FailedVersion = version
+		components.SetCondition(obj, summonv1beta1.ConditionDeploymentsReady, conditions.ConditionFalse, "ProgressDeadlineExceeded", failedErr.Error())
+		return nil
+	}}, failedErr
+}
+
+// StatusModifier used while waiting on Deployments and StatefulSets to roll out.
+func deploymentsNotReady(obj runtime.Object) error {
+	components.SetCondition(obj, summonv1beta1.ConditionDeploymentsReady, conditions.ConditionFalse, summonv1beta1.StatusDeploying, "waiting for deployments to become ready")
+	return nil
 }
 
 // Short helper because we need to do this 6 times.
@@ -18,6 +18,7 @@ package components_test
 
 import (
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -185,6 +186,69 @@ var _ = Describe("SummonPlatform Status Component", func() {
 		Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusDeploying))
 	})
 
+	Context("progress deadline", func() {
+		BeforeEach(func() {
+			instance.Status.Status = summonv1beta1.StatusDeploying
+			instance.Spec.Rollback.ProgressDeadline = metav1.Duration{Duration: 15 * time.Minute}
+		})
+
+		It("starts the clock for a new version", func() {
+			comp := summoncomponents.NewStatus()
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Deploy.Version).To(Equal("1.2.3"))
+			Expect(instance.Status.Deploy.StartedAt).ToNot(BeEmpty())
+		})
+
+		It("records the last good version once ready", func() {
+			webDeployment.Status.AvailableReplicas = 2
+			daphneDeployment.Status.AvailableReplicas = 2
+			celerydDeployment.Status.AvailableReplicas = 2
+			channelworkersDeployment.Status.AvailableReplicas = 2
+			staticDeployment.Status.AvailableReplicas = 2
+			celerybeatStatefulSet.Status.ReadyReplicas = 2
+			kafkaconsumerDeployment.Status.AvailableReplicas = 2
+			instance.Status.Deploy.FailedVersion = "1.2.2"
+			ctx.Client = makeClient()
+
+			comp := summoncomponents.NewStatus()
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Status).To(Equal(summonv1beta1.StatusReady))
+			Expect(instance.Status.Deploy.LastGoodVersion).To(Equal("1.2.3"))
+			Expect(instance.Status.Deploy.FailedVersion).To(BeEmpty())
+		})
+
+		It("fails the deploy once the deadline passes", func() {
+			instance.Spec.Rollback.Enabled = true
+			instance.Status.Deploy.LastGoodVersion = "1.2.2"
+			instance.Status.Deploy.Version = "1.2.3"
+			instance.Status.Deploy.StartedAt = time.Now().Add(-20 * time.Minute).Format(time.UnixDate)
+
+			comp := summoncomponents.NewStatus()
+			res, err := comp.Reconcile(ctx)
+			Expect(err).To(MatchError("status: version 1.2.3 did not become ready within 15m0s, rolled back web, celeryd, daphne to 1.2.2"))
+			Expect(res.StatusModifier(instance)).To(Succeed())
+			Expect(instance.Status.Deploy.FailedVersion).To(Equal("1.2.3"))
+		})
+
+		It("doesn't fail a version which was already ready", func() {
+			instance.Status.Deploy.LastGoodVersion = "1.2.3"
+			instance.Status.Deploy.Version = "1.2.3"
+			instance.Status.Deploy.StartedAt = time.Now().Add(-20 * time.Minute).Format(time.UnixDate)
+
+			comp := summoncomponents.NewStatus()
+			Expect(comp).To(ReconcileContext(ctx))
+			Expect(instance.Status.Deploy.FailedVersion).To(BeEmpty())
+		})
+
+		It("keeps reporting a failed version", func() {
+			instance.Status.Deploy.FailedVersion = "1.2.3"
+
+			comp := summoncomponents.NewStatus()
+			_, err := comp.Reconcile(ctx)
+			Expect(err).To(MatchError("status: version 1.2.3 did not become ready within 15m0s, automatic rollback is disabled"))
+		})
+	})
+
 	Context("new status check behavior", func() {
 		BeforeEach(func() {
 			os.Setenv("ENABLE_NEW_STATUS_CHECK", "true")
@@ -82,6 +82,7 @@ func Add(mgr manager.Manager) error {
 		summoncomponents.NewDeployment("web/deployment.yml.tpl", nil),
 		summoncomponents.NewPodDisruptionBudget("web/podDisruptionBudget.yml.tpl"),
 		summoncomponents.NewService("web/service.yml.tpl"),
+		summoncomponents.NewCanary("web/canary-deployment.yml.tpl", "web/canary-service.yml.tpl"),
 		summoncomponents.NewIngress("web/ingress.yml.tpl"),
 		summoncomponents.NewIngress("web/ingress-protected.yml.tpl"),
 
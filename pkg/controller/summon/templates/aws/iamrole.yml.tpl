@@ -103,3 +103,8 @@ spec:
             ]
          }
  permissionsBoundaryArn: {{ .Extra.permissionsBoundaryArn }}
+{{ if and .Instance.Spec.UseIamRole .Extra.oidcProviderArn }}
+ oidcProviderArn: {{ .Extra.oidcProviderArn }}
+ serviceAccounts:
+ - name: {{ .Instance.Name }}
+{{ end }}
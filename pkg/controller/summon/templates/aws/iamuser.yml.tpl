@@ -86,3 +86,6 @@ spec:
               }
             }
  permissionsBoundaryArn: {{ .Extra.permissionsBoundaryArn }}
+{{ if .Instance.Spec.AWSAccessKeyMaxAge.Duration }}
+ maxKeyAge: {{ .Instance.Spec.AWSAccessKeyMaxAge.Duration }}
+{{ end }}
@@ -6,3 +6,15 @@ metadata:
 spec:
  bucketName: ridecell-{{ .Instance.Name }}-miv
  region: {{ .Instance.Spec.AwsRegion }}
+ versioning: true
+ encryption:
+   algorithm: aws:kms
+ lifecycleRules:
+ - id: expire-miv-images
+   expirationDays: 90
+   noncurrentVersionExpirationDays: 1
+ publicAccessBlock:
+   blockPublicAcls: true
+   ignorePublicAcls: true
+   blockPublicPolicy: true
+   restrictPublicBuckets: true
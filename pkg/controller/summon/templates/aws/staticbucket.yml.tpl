@@ -19,3 +19,20 @@ spec:
                     "Resource": "arn:aws:s3:::ridecell-{{ .Instance.Name }}-static/*"
                   }]
                }
+ versioning: true
+ encryption:
+   algorithm: AES256
+ lifecycleRules:
+ - id: expire-noncurrent-versions
+   noncurrentVersionExpirationDays: 30
+ corsRules:
+ - allowedOrigins: ["*"]
+   allowedMethods: ["GET", "HEAD"]
+   allowedHeaders: ["*"]
+   maxAgeSeconds: 3000
+ # Objects are served through the public bucket policy, so only ACLs are blocked.
+ publicAccessBlock:
+   blockPublicAcls: true
+   ignorePublicAcls: true
+   blockPublicPolicy: false
+   restrictPublicBuckets: false
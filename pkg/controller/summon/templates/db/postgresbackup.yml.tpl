@@ -0,0 +1,9 @@
+kind: PostgresBackup
+apiVersion: db.ridecell.io/v1beta1
+metadata:
+ name: {{ .Instance.Name }}-{{ .Instance.Spec.Version | replace "_" "-" | lower }}
+ namespace: {{ .Instance.Namespace }}
+spec:
+ database: {{ .Instance.Name }}
+ bucketName: {{ .Instance.Spec.Backup.BucketName }}
+ ttl: {{ .Instance.Spec.Backup.TTL.Duration }}
@@ -2,11 +2,11 @@
 apiVersion: apps/v1
 kind: Deployment
 metadata:
-  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
   namespace: {{ .Instance.Namespace }}
   labels:
     app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
-    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
     app.kubernetes.io/version: {{ .Instance.Spec.Version }}
     app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
     app.kubernetes.io/part-of: {{ .Instance.Name }}
@@ -16,12 +16,12 @@ spec:
   replicas: {{ block "replicas" . }}1{{ end }}
   selector:
     matchLabels:
-      app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+      app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
   template:
     metadata:
       labels:
         app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
-        app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+        app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
         app.kubernetes.io/version: {{ .Instance.Spec.Version }}
         app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
         app.kubernetes.io/part-of: {{ .Instance.Name }}
@@ -42,7 +42,7 @@ spec:
           requiredDuringSchedulingIgnoredDuringExecution:
           - labelSelector:
               matchLabels:
-                app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+                app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
             topologyKey: "kubernetes.io/hostname"
           preferredDuringSchedulingIgnoredDuringExecution:
           - weight: 1
@@ -50,7 +50,7 @@ spec:
               topologyKey: "failure-domain.beta.kubernetes.io/zone"
               labelSelector:
                 matchLabels:
-                  app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+                  app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
       imagePullSecrets:
       - name: pull-secret
       containers:
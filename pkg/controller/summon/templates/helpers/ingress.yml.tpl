@@ -16,6 +16,10 @@ metadata:
     kubernetes.io/tls-acme: "true"
     cert-manager.io/cluster-issuer: letsencrypt-prod
 {{ block "extraAnnotations" . }}{{ end }}
+{{- if .Extra.canaryWeight }}
+    traefik.ingress.kubernetes.io/service-weights: |
+      {{ .Instance.Name }}-{{ template "componentName" . }}-canary: {{ .Extra.canaryWeight }}%
+{{- end }}
 spec:
   rules:
   - host: {{ .Instance.Spec.Hostname }}
@@ -25,6 +29,7 @@ spec:
         backend:
           serviceName: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
           servicePort: 8000
+{{- template "canaryPath" . }}
   {{ if or (eq  .Instance.Spec.Environment "dev") (eq  .Instance.Spec.Environment "qa") }}
   - host: {{ .Instance.Name }}.ridecell.io
     http:
@@ -33,6 +38,7 @@ spec:
         backend:
           serviceName: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
           servicePort: 8000
+{{- template "canaryPath" . }}
   {{ end }}
   {{- range .Instance.Spec.Aliases }}
   - host: {{.}}
@@ -42,6 +48,7 @@ spec:
         backend:
           serviceName: {{ $.Instance.Name }}-{{ block "componentName" $ }}{{ end }}
           servicePort: 8000
+{{- template "canaryPath" $ }}
   {{- end }}
   tls:
   - secretName: {{ .Instance.Name }}-tls
@@ -54,3 +61,11 @@ spec:
     - {{.}}
     {{- end }}
 {{ end }}
+{{ define "canaryPath" }}
+{{- if .Extra.canaryWeight }}
+      - path: {{ template "ingressPath" . }}
+        backend:
+          serviceName: {{ .Instance.Name }}-{{ template "componentName" . }}-canary
+          servicePort: 8000
+{{- end }}
+{{- end }}
@@ -2,11 +2,11 @@
 kind: Service
 apiVersion: v1
 metadata:
-  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+  name: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
   namespace: {{ .Instance.Namespace }}
   labels:
     app.kubernetes.io/name: {{ block "componentName" . }}{{ end }}
-    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}
+    app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}
     app.kubernetes.io/version: {{ .Instance.Spec.Version }}
     app.kubernetes.io/component: {{ block "componentType" . }}{{ end }}
     app.kubernetes.io/part-of: {{ .Instance.Name }}
@@ -15,6 +15,6 @@ metadata:
 {{ block "extraAnnotations" . }}{{ end -}}
 spec:
   selector:
-    {{ block "selectors" . }}{app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}}{{ end }}
+    {{ block "selectors" . }}{app.kubernetes.io/instance: {{ .Instance.Name }}-{{ block "componentName" . }}{{ end }}{{ block "instanceSuffix" . }}{{ end }}}{{ end }}
   ports: {{ block "servicePorts" . }}[{protocol: TCP, port: 8000}]{{ end }}
 {{ end }}
@@ -0,0 +1,35 @@
+{{/* Container settings shared by the web deployment and its canary. */}}
+{{ define "webCommand" }}
+{{- if (deref .Instance.Spec.Metrics.Web) -}}
+[python, -m, summon_platform]
+{{- else -}}
+[python, -m, twisted, --log-format, text, web, --listen, tcp:8000, --wsgi, summon_platform.wsgi.application]
+{{- end -}}
+{{ end }}
+{{ define "webDeploymentPorts" }}
+{{- if (deref .Instance.Spec.Metrics.Web) -}}
+[{containerPort: 8000}, {containerPort: 9000}]
+{{- else -}}
+[{containerPort: 8000}]
+{{- end -}}
+{{ end }}
+{{ define "webMetricsEnabled" }}"{{ .Instance.Spec.Metrics.Web | default false }}"{{ end }}
+{{ define "webResources" }}{requests: {memory: "800M", cpu: "50m"}, limits: {memory: "1365M"}}{{ end }}
+{{ define "webContainerExtra" }}
+        readinessProbe:
+          httpGet:
+            path: /healthz
+            port: 8000
+            httpHeaders:
+            - name: X-Forwarded-Proto
+              value: https
+          periodSeconds: 2
+        livenessProbe:
+          httpGet:
+            path: /healthz
+            port: 8000
+            httpHeaders:
+            - name: X-Forwarded-Proto
+              value: https
+          initialDelaySeconds: 60
+{{ end }}
@@ -0,0 +1,57 @@
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance.Name }}-migrations-plan
+  namespace: {{ .Instance.Namespace }}
+  labels:
+    app.kubernetes.io/name: migrations-plan
+    app.kubernetes.io/instance: {{ .Instance.Name }}-migrations-plan
+    app.kubernetes.io/version: {{ .Instance.Spec.Version }}
+    app.kubernetes.io/component: migration
+    app.kubernetes.io/part-of: {{ .Instance.Name }}
+    app.kubernetes.io/managed-by: summon-operator
+spec:
+  backoffLimit: 0
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: migrations-plan
+        app.kubernetes.io/instance: {{ .Instance.Name }}-migrations-plan
+        app.kubernetes.io/version: {{ .Instance.Spec.Version }}
+        app.kubernetes.io/component: migration
+        app.kubernetes.io/part-of: {{ .Instance.Name }}
+        app.kubernetes.io/managed-by: summon-operator
+    spec:
+      restartPolicy: Never
+      imagePullSecrets:
+      - name: pull-secret
+      containers:
+      - name: default
+        image: us.gcr.io/ridecell-1/summon:{{ .Instance.Spec.Version }}
+        imagePullPolicy: Always
+        command:
+        - sh
+        - "-c"
+        # The plan is read back by the operator from the termination message, which is capped at 4KB. The end
+        # marker carries the line count so the operator can tell when the plan was cut off.
+        - python manage.py migrate --plan > /tmp/plan && echo "END OF MIGRATION PLAN: $(wc -l < /tmp/plan) lines" >> /tmp/plan && cp /tmp/plan /dev/termination-log && cat /dev/termination-log
+        terminationMessagePath: /dev/termination-log
+        terminationMessagePolicy: FallbackToLogsOnError
+        resources:
+          requests:
+            memory: 800M
+            cpu: 100m
+          limits:
+            memory: 1.5G
+        volumeMounts:
+        - name: config-volume
+          mountPath: /etc/config
+        - name: app-secrets
+          mountPath: /etc/secrets
+      volumes:
+        - name: config-volume
+          configMap:
+            name: {{ .Instance.Name }}-config
+        - name: app-secrets
+          secret:
+            secretName: {{ .Instance.Name }}.app-secrets
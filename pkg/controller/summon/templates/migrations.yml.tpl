@@ -36,6 +36,8 @@ spec:
         {{- else }}
         - {{ if and (not .Instance.Spec.NoCore1540Fixup) (ne .Instance.Status.MigrateVersion "") }}if [ -f common/management/commands/core_1540_pre_migrate.py ]; then python manage.py core_1540_pre_migrate; fi && {{ end }}python manage.py migrate -v3
         {{- end }}
+        # On failure the end of the logs ends up in the termination message, which is reported in the status.
+        terminationMessagePolicy: FallbackToLogsOnError
         resources:
           requests:
             memory: 1.5G
@@ -16,6 +16,13 @@ spec:
     postgis_topology: ""
     pg_trgm: ""
   dbConfigRef: {{ .Instance.Spec.Database.DbConfigRef | toJson }}
+  {{ if .Instance.Spec.Database.CloneFrom }}
+  cloneFrom: {{ .Instance.Spec.Database.CloneFrom | toJson }}
+  {{ end }}
+  {{ if .Instance.Spec.Database.Pooler }}
+  pooler: {{ .Instance.Spec.Database.Pooler | toJson }}
+  {{ end }}
+  passwordRotation: {{ .Instance.Spec.PasswordRotation | toJson }}
   {{ if .Instance.Spec.MigrationOverrides.PostgresDatabase }}
   databaseName: {{ .Instance.Spec.MigrationOverrides.PostgresDatabase }}
   {{ end }}
@@ -5,6 +5,7 @@ metadata:
   namespace: {{ .Instance.Namespace }}
 spec:
   vhostName: {{ .Instance.Spec.MigrationOverrides.RabbitMQVhost | default .Instance.Name }}
+  passwordRotation: {{ .Instance.Spec.PasswordRotation | toJson }}
   policies:
     HA:
       pattern: ^(?!amq\.).*
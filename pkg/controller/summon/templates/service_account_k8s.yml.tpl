@@ -1,7 +1,9 @@
 apiVersion: v1
 kind: ServiceAccount
 metadata:
+  {{ if not .Extra.irsa }}
   annotations:
     eks.amazonaws.com/role-arn: "arn:aws:iam::{{ .Extra.accountId }}:role/summon-platform-{{ .Instance.Spec.Environment }}-{{ .Instance.Name }}"
+  {{ end }}
   name: {{ .Instance.Name }}
   namespace: {{ .Instance.Namespace }}
\ No newline at end of file
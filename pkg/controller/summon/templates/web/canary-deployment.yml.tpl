@@ -0,0 +1,10 @@
+{{ define "componentName" }}web{{ end }}
+{{ define "componentType" }}web{{ end }}
+{{ define "instanceSuffix" }}-canary{{ end }}
+{{ define "command" }}{{ template "webCommand" . }}{{ end }}
+{{ define "deploymentPorts" }}{{ template "webDeploymentPorts" . }}{{ end }}
+{{ define "metricsEnabled" }}{{ template "webMetricsEnabled" . }}{{ end }}
+{{ define "replicas" }}{{ .Instance.Spec.Rollout.Canary.Replicas | default 1 }}{{ end }}
+{{ define "resources" }}{{ template "webResources" . }}{{ end }}
+{{ define "containerExtra" }}{{ template "webContainerExtra" . }}{{ end }}
+{{ template "deployment" . }}
@@ -0,0 +1,4 @@
+{{ define "componentName" }}web{{ end }}
+{{ define "componentType" }}web{{ end }}
+{{ define "instanceSuffix" }}-canary{{ end }}
+{{ template "service" . }}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/Ridecell/ridecell-operator/pkg/components"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	aquav1alpha1 "github.com/aquasecurity/starboard/pkg/apis/aquasecurity/v1alpha1"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -41,7 +42,7 @@ type reportComponent struct {
 }
 
 func NewVulnerabilityReport() *reportComponent {
-	sess := session.Must(session.NewSession())
+	sess := metrics.InstrumentAWSSession(session.Must(session.NewSession()))
 	shService := sh.New(sess, &aws.Config{
 		Region: aws.String("us-west-2"), // All vulneribilty report should go to us-west-2 region only.
 	})
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// InstrumentAWSSession adds a handler to count every API call made by clients built from this session.
+func InstrumentAWSSession(sess *session.Session) *session.Session {
+	sess.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "ridecell.io/metrics",
+		Fn: func(r *request.Request) {
+			operation := ""
+			if r.Operation != nil {
+				operation = r.Operation.Name
+			}
+			ObserveCloudAPICall("aws", r.ClientInfo.ServiceName, operation, r.Error)
+		},
+	})
+	return sess
+}
+
+// An http.RoundTripper which counts each request by host and method.
+type instrumentedTransport struct {
+	provider string
+	base     http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	result := "success"
+	if err != nil || resp.StatusCode >= 400 {
+		result = "error"
+	}
+	CloudAPICalls.WithLabelValues(t.provider, req.URL.Host, req.Method, result).Inc()
+	return resp, err
+}
+
+// InstrumentHTTPClient returns a copy of the client which counts every request as a call to the given provider.
+func InstrumentHTTPClient(provider string, client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	instrumented := *client
+	instrumented.Transport = &instrumentedTransport{provider: provider, base: base}
+	return &instrumented
+}
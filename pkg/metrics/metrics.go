@@ -0,0 +1,124 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the Prometheus metrics for the operator itself. Everything is registered
+// on the controller-runtime registry, so it is served from the manager's metrics endpoint.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// Duration of a full reconcile of one top-level object.
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ridecell_operator_reconcile_duration_seconds",
+		Help:    "Duration of a full reconcile of one object, by controller.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"controller"})
+
+	// Duration of a single component's Reconcile.
+	ComponentDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ridecell_operator_component_duration_seconds",
+		Help:    "Duration of a single component reconcile, by controller and component.",
+		Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"controller", "component"})
+
+	// Errors returned from component reconciles.
+	ComponentErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_component_errors_total",
+		Help: "Number of errors returned by component reconciles, by controller and component.",
+	}, []string{"controller", "component"})
+
+	// Requeues requested by reconciles.
+	Requeues = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_requeues_total",
+		Help: "Number of reconciles which asked to be requeued, by controller and type (immediate, delayed, error).",
+	}, []string{"controller", "type"})
+
+	// Number of objects currently in each status.
+	ObjectsByStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_objects",
+		Help: "Number of objects by controller and current .status.status value.",
+	}, []string{"controller", "status"})
+
+	// Calls to external cloud APIs.
+	CloudAPICalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ridecell_operator_cloud_api_calls_total",
+		Help: "Number of calls to external cloud APIs, by provider, service, operation, and result.",
+	}, []string{"provider", "service", "operation", "result"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ReconcileDuration,
+		ComponentDuration,
+		ComponentErrors,
+		Requeues,
+		ObjectsByStatus,
+		CloudAPICalls,
+	)
+}
+
+// ObserveCloudAPICall counts a single call to a cloud API.
+func ObserveCloudAPICall(provider, service, operation string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	CloudAPICalls.WithLabelValues(provider, service, operation, result).Inc()
+}
+
+// StatusTracker keeps the ObjectsByStatus gauge up to date for a single controller.
+type StatusTracker struct {
+	controller string
+	mutex      sync.Mutex
+	statuses   map[string]string
+}
+
+func NewStatusTracker(controller string) *StatusTracker {
+	return &StatusTracker{controller: controller, statuses: map[string]string{}}
+}
+
+// Set records the current status of an object, keyed by namespace/name.
+func (t *StatusTracker) Set(key, status string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	old, ok := t.statuses[key]
+	if ok && old == status {
+		return
+	}
+	if ok {
+		ObjectsByStatus.WithLabelValues(t.controller, old).Dec()
+	}
+	t.statuses[key] = status
+	ObjectsByStatus.WithLabelValues(t.controller, status).Inc()
+}
+
+// Delete forgets an object which no longer exists.
+func (t *StatusTracker) Delete(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	old, ok := t.statuses[key]
+	if !ok {
+		return
+	}
+	delete(t.statuses, key)
+	ObjectsByStatus.WithLabelValues(t.controller, old).Dec()
+}
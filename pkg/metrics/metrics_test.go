@@ -0,0 +1,66 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
+)
+
+var _ = Describe("Metrics", func() {
+	It("tracks objects by status", func() {
+		tracker := metrics.NewStatusTracker("test-tracker")
+		tracker.Set("default/foo", "Deploying")
+		tracker.Set("default/bar", "Deploying")
+		Expect(testutil.ToFloat64(metrics.ObjectsByStatus.WithLabelValues("test-tracker", "Deploying"))).To(Equal(2.0))
+
+		tracker.Set("default/foo", "Ready")
+		tracker.Set("default/foo", "Ready")
+		Expect(testutil.ToFloat64(metrics.ObjectsByStatus.WithLabelValues("test-tracker", "Deploying"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(metrics.ObjectsByStatus.WithLabelValues("test-tracker", "Ready"))).To(Equal(1.0))
+
+		tracker.Delete("default/foo")
+		tracker.Delete("default/missing")
+		Expect(testutil.ToFloat64(metrics.ObjectsByStatus.WithLabelValues("test-tracker", "Ready"))).To(Equal(0.0))
+	})
+
+	It("counts calls through an instrumented HTTP client", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(500)
+			}
+		}))
+		defer server.Close()
+		host := strings.TrimPrefix(server.URL, "http://")
+
+		client := metrics.InstrumentHTTPClient("test", nil)
+		_, err := client.Get(server.URL + "/ok")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.Get(server.URL + "/fail")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(testutil.ToFloat64(metrics.CloudAPICalls.WithLabelValues("test", host, "GET", "success"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(metrics.CloudAPICalls.WithLabelValues("test", host, "GET", "error"))).To(Equal(1.0))
+	})
+})
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Per-database usage collected by the PostgresDatabase controller, labeled by the namespace and name of the
+// PostgresDatabase object so tenants on a shared server can be told apart.
+var (
+	PostgresDatabaseSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_size_bytes",
+		Help: "Size of the database on disk.",
+	}, []string{"namespace", "name"})
+
+	PostgresDatabaseQuota = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_quota_bytes",
+		Help: "Size quota of the database, only set if it has one.",
+	}, []string{"namespace", "name"})
+
+	PostgresDatabaseConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_connections",
+		Help: "Open connections to the database.",
+	}, []string{"namespace", "name"})
+
+	PostgresDatabaseMaxConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_max_connections",
+		Help: "max_connections of the server the database is on.",
+	}, []string{"namespace", "name"})
+
+	PostgresDatabaseLongTransactions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_long_transactions",
+		Help: "Transactions open for more than five minutes.",
+	}, []string{"namespace", "name"})
+
+	PostgresDatabaseReplicationLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ridecell_operator_postgres_database_replication_lag_seconds",
+		Help: "Largest replay lag of any replica, only for local clusters.",
+	}, []string{"namespace", "name"})
+)
+
+var postgresDatabaseGauges = []*prometheus.GaugeVec{
+	PostgresDatabaseSize,
+	PostgresDatabaseQuota,
+	PostgresDatabaseConnections,
+	PostgresDatabaseMaxConnections,
+	PostgresDatabaseLongTransactions,
+	PostgresDatabaseReplicationLag,
+}
+
+func init() {
+	for _, gauge := range postgresDatabaseGauges {
+		crmetrics.Registry.MustRegister(gauge)
+	}
+}
+
+// DeletePostgresDatabase drops every series for a database which no longer exists.
+func DeletePostgresDatabase(namespace, name string) {
+	for _, gauge := range postgresDatabaseGauges {
+		gauge.DeleteLabelValues(namespace, name)
+	}
+}
@@ -20,9 +20,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/Ridecell/ridecell-operator/pkg/errors"
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 	"net/http"
 )
 
+// HTTP client for CloudAMQP, instrumented so API calls show up in the operator metrics.
+var cloudamqpHTTPClient = metrics.InstrumentHTTPClient("cloudamqp", http.DefaultClient)
+
 type CloudamqpFirewallRule struct {
 	Services    []string `json:"services"`
 	IP          string   `json:"ip"`
@@ -43,7 +47,7 @@ func PutCloudamqpFirewallRules(apiUrl string, apiKey string, data []CloudamqpFir
 	req.SetBasicAuth("", apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := cloudamqpHTTPClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -62,7 +66,7 @@ func GetCloudamqpFirewallRules(apiUrl string, apiKey string) ([]CloudamqpFirewal
 	}
 	req.SetBasicAuth("", apiKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := cloudamqpHTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
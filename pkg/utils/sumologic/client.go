@@ -27,6 +27,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/Ridecell/ridecell-operator/pkg/metrics"
 )
 
 var newDefaultHTTPClient http.Client = http.Client{
@@ -77,7 +79,7 @@ func NewClient(baseurl, id, key string) (*Client, error) {
 	return &Client{
 		BaseURL:    baseurl,
 		Auth:       base64.StdEncoding.EncodeToString([]byte(id + ":" + key)),
-		httpClient: &newDefaultHTTPClient,
+		httpClient: metrics.InstrumentHTTPClient("sumologic", &newDefaultHTTPClient),
 	}, nil
 }
 
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summonplatform
+
+import (
+	"context"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Fills in the persistent defaults for a SummonPlatform at admission time.
+type defaultingHandler struct {
+	decoder types.Decoder
+}
+
+var _ admission.Handler = &defaultingHandler{}
+
+func (h *defaultingHandler) Handle(ctx context.Context, req types.Request) types.Response {
+	instance := &summonv1beta1.SummonPlatform{}
+	err := h.decoder.Decode(req, instance)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	// The namespace isn't always in the body on create, and the defaults depend on it.
+	if instance.Namespace == "" {
+		instance.Namespace = req.AdmissionRequest.Namespace
+	}
+
+	defaulted := instance.DeepCopy()
+	defaulted.Default()
+	return admission.PatchResponse(instance, defaulted)
+}
+
+// defaultingHandler implements inject.Decoder.
+// A decoder will be automatically injected.
+var _ inject.Decoder = &defaultingHandler{}
+
+// InjectDecoder injects the decoder.
+func (h *defaultingHandler) InjectDecoder(d types.Decoder) error {
+	h.decoder = d
+	return nil
+}
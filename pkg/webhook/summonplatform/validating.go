@@ -0,0 +1,147 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summonplatform
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+
+	secretsv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/secrets/v1beta1"
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Rejects SummonPlatform specs which would only fail later during reconcile.
+type validatingHandler struct {
+	client  client.Client
+	decoder types.Decoder
+}
+
+var _ admission.Handler = &validatingHandler{}
+
+func (h *validatingHandler) Handle(ctx context.Context, req types.Request) types.Response {
+	instance := &summonv1beta1.SummonPlatform{}
+	err := h.decoder.Decode(req, instance)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	if instance.Namespace == "" {
+		instance.Namespace = req.AdmissionRequest.Namespace
+	}
+
+	var allErrs field.ErrorList
+	if req.AdmissionRequest.Operation == admissionv1beta1.Update {
+		old := &summonv1beta1.SummonPlatform{}
+		err := json.Unmarshal(req.AdmissionRequest.OldObject.Raw, old)
+		if err != nil {
+			return admission.ErrorResponse(http.StatusBadRequest, err)
+		}
+		// Don't get in the way of metadata-only updates (like finalizers) or deletes, otherwise objects
+		// created before this webhook existed could get stuck.
+		if reflect.DeepEqual(instance.Spec, old.Spec) || instance.DeletionTimestamp != nil {
+			return admission.ValidationResponse(true, "")
+		}
+		allErrs = instance.ValidateUpdate(old)
+	} else {
+		allErrs = instance.ValidateCreate()
+	}
+
+	fernetErr, err := h.checkFernetKeys(ctx, instance)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	if fernetErr != nil {
+		allErrs = append(allErrs, fernetErr)
+	}
+
+	if len(allErrs) > 0 {
+		return admission.ValidationResponse(false, allErrs.ToAggregate().Error())
+	}
+	return admission.ValidationResponse(true, "")
+}
+
+// Check that one of the input secrets has FERNET_KEYS. An input secret which doesn't exist yet is checked via
+// its EncryptedSecret instead, since they are usually applied together and decrypted later. If neither
+// exists the check is skipped, as the secret might just be applied after this object.
+func (h *validatingHandler) checkFernetKeys(ctx context.Context, instance *summonv1beta1.SummonPlatform) (*field.Error, error) {
+	secretNames := instance.Spec.Secrets
+	if len(secretNames) == 0 {
+		// This must match the defaults in the app secrets component.
+		secretNames = []string{instance.Namespace, instance.Name}
+	}
+
+	for _, secretName := range secretNames {
+		key := k8stypes.NamespacedName{Name: secretName, Namespace: instance.Namespace}
+		secret := &corev1.Secret{}
+		err := h.client.Get(ctx, key, secret)
+		if err == nil {
+			if len(secret.Data["FERNET_KEYS"]) > 0 {
+				return nil, nil
+			}
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		encryptedSecret := &secretsv1beta1.EncryptedSecret{}
+		err = h.client.Get(ctx, key, encryptedSecret)
+		if err == nil {
+			if encryptedSecret.Data["FERNET_KEYS"] != "" {
+				return nil, nil
+			}
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	return field.Required(field.NewPath("spec", "secrets"), "none of the input secrets contain FERNET_KEYS"), nil
+}
+
+// validatingHandler implements inject.Client.
+// A client will be automatically injected.
+var _ inject.Client = &validatingHandler{}
+
+// InjectClient injects the client.
+func (h *validatingHandler) InjectClient(c client.Client) error {
+	h.client = c
+	return nil
+}
+
+// validatingHandler implements inject.Decoder.
+// A decoder will be automatically injected.
+var _ inject.Decoder = &validatingHandler{}
+
+// InjectDecoder injects the decoder.
+func (h *validatingHandler) InjectDecoder(d types.Decoder) error {
+	h.decoder = d
+	return nil
+}
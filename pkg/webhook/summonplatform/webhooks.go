@@ -0,0 +1,60 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summonplatform
+
+import (
+	"github.com/pkg/errors"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/builder"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+
+	summonv1beta1 "github.com/Ridecell/ridecell-operator/pkg/apis/summon/v1beta1"
+)
+
+// Webhooks builds the defaulting and validating admission webhooks for SummonPlatform.
+func Webhooks(mgr manager.Manager) ([]webhooktypes.Webhook, error) {
+	mutating, err := builder.NewWebhookBuilder().
+		Name("mutating.summonplatforms.summon.ridecell.io").
+		Path("/mutate-summonplatforms").
+		Mutating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		FailurePolicy(admissionregistrationv1beta1.Fail).
+		WithManager(mgr).
+		ForType(&summonv1beta1.SummonPlatform{}).
+		Handlers(&defaultingHandler{}).
+		Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build SummonPlatform mutating webhook")
+	}
+
+	validating, err := builder.NewWebhookBuilder().
+		Name("validating.summonplatforms.summon.ridecell.io").
+		Path("/validate-summonplatforms").
+		Validating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		FailurePolicy(admissionregistrationv1beta1.Fail).
+		WithManager(mgr).
+		ForType(&summonv1beta1.SummonPlatform{}).
+		Handlers(&validatingHandler{}).
+		Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build SummonPlatform validating webhook")
+	}
+
+	return []webhooktypes.Webhook{mutating, validating}, nil
+}
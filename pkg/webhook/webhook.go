@@ -0,0 +1,72 @@
+/*
+Copyright 2019 Ridecell, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// Options for the admission webhook server.
+type Options struct {
+	// Port for the webhook server to listen on.
+	Port int32
+	// Directory to store the generated serving certificate in.
+	CertDir string
+	// Namespace the operator is running in, used for the webhook Service and certificate Secret.
+	Namespace string
+}
+
+// WebhookFuncs is a list of functions to build all the admission webhooks served by the operator.
+var WebhookFuncs []func(manager.Manager) ([]webhooktypes.Webhook, error)
+
+// AddToManager creates the admission webhook server and registers all webhooks with it.
+func AddToManager(mgr manager.Manager, opts Options) error {
+	svr, err := crwebhook.NewServer("ridecell-operator-admission-server", mgr, crwebhook.ServerOptions{
+		Port:    opts.Port,
+		CertDir: opts.CertDir,
+		BootstrapOptions: &crwebhook.BootstrapOptions{
+			MutatingWebhookConfigName:   "ridecell-operator-mutating-webhook",
+			ValidatingWebhookConfigName: "ridecell-operator-validating-webhook",
+			Secret: &types.NamespacedName{
+				Namespace: opts.Namespace,
+				Name:      "ridecell-operator-webhook-cert",
+			},
+			Service: &crwebhook.Service{
+				Namespace: opts.Namespace,
+				Name:      "ridecell-operator-webhook",
+				Selectors: map[string]string{"control-plane": "controller-manager"},
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create webhook server")
+	}
+
+	webhooks := []webhooktypes.Webhook{}
+	for _, f := range WebhookFuncs {
+		whs, err := f(mgr)
+		if err != nil {
+			return err
+		}
+		webhooks = append(webhooks, whs...)
+	}
+	return svr.Register(webhooks...)
+}